@@ -7,17 +7,17 @@ func (ui *gameui) SwappingAnimation(mpos, ppos position) {
 		return
 	}
 	ui.DrawDungeonView(NormalMode)
-	time.Sleep(25 * time.Millisecond)
+	UISleep(25 * time.Millisecond)
 	_, fgm, bgColorm := ui.PositionDrawing(mpos)
 	_, _, bgColorp := ui.PositionDrawing(ppos)
 	ui.DrawAtPosition(mpos, true, 'Φ', fgm, bgColorp)
 	ui.DrawAtPosition(ppos, true, 'Φ', ColorFgPlayer, bgColorm)
 	ui.Flush()
-	time.Sleep(75 * time.Millisecond)
+	UISleep(75 * time.Millisecond)
 	ui.DrawAtPosition(mpos, true, 'Φ', ColorFgPlayer, bgColorp)
 	ui.DrawAtPosition(ppos, true, 'Φ', fgm, bgColorm)
 	ui.Flush()
-	time.Sleep(75 * time.Millisecond)
+	UISleep(75 * time.Millisecond)
 }
 
 func (ui *gameui) TeleportAnimation(from, to position, showto bool) {
@@ -28,12 +28,12 @@ func (ui *gameui) TeleportAnimation(from, to position, showto bool) {
 	_, _, bgColort := ui.PositionDrawing(to)
 	ui.DrawAtPosition(from, true, 'Φ', ColorCyan, bgColorf)
 	ui.Flush()
-	time.Sleep(75 * time.Millisecond)
+	UISleep(75 * time.Millisecond)
 	if showto {
 		ui.DrawAtPosition(from, true, 'Φ', ColorBlue, bgColorf)
 		ui.DrawAtPosition(to, true, 'Φ', ColorCyan, bgColort)
 		ui.Flush()
-		time.Sleep(75 * time.Millisecond)
+		UISleep(75 * time.Millisecond)
 	}
 }
 
@@ -54,7 +54,7 @@ func (ui *gameui) ProjectileTrajectoryAnimation(ray []position, fg uicolor) {
 		r, fgColor, bgColor := ui.PositionDrawing(pos)
 		ui.DrawAtPosition(pos, true, '•', fg, bgColor)
 		ui.Flush()
-		time.Sleep(30 * time.Millisecond)
+		UISleep(30 * time.Millisecond)
 		ui.DrawAtPosition(pos, true, r, fgColor, bgColor)
 	}
 }
@@ -64,13 +64,13 @@ func (ui *gameui) MonsterProjectileAnimation(ray []position, r rune, fg uicolor)
 		return
 	}
 	ui.DrawDungeonView(NormalMode)
-	time.Sleep(25 * time.Millisecond)
+	UISleep(25 * time.Millisecond)
 	for i := 0; i < len(ray); i++ {
 		pos := ray[i]
 		or, fgColor, bgColor := ui.PositionDrawing(pos)
 		ui.DrawAtPosition(pos, true, r, fg, bgColor)
 		ui.Flush()
-		time.Sleep(30 * time.Millisecond)
+		UISleep(30 * time.Millisecond)
 		ui.DrawAtPosition(pos, true, or, fgColor, bgColor)
 	}
 }
@@ -80,7 +80,7 @@ func (ui *gameui) ExplosionAnimationAt(pos position, fg uicolor) {
 	_, _, bgColor := ui.PositionDrawing(pos)
 	mons := g.MonsterAt(pos)
 	r := ';'
-	switch RandInt(9) {
+	switch RandIntUI(9) {
 	case 0, 6:
 		r = ','
 	case 1:
@@ -107,7 +107,7 @@ func (ui *gameui) ExplosionAnimation(es explosionStyle, pos position) {
 		return
 	}
 	ui.DrawDungeonView(NormalMode)
-	time.Sleep(20 * time.Millisecond)
+	UISleep(20 * time.Millisecond)
 	colors := [2]uicolor{ColorFgExplosionStart, ColorFgExplosionEnd}
 	if es == WallExplosion || es == AroundWallExplosion {
 		colors[0] = ColorFgExplosionWallStart
@@ -119,16 +119,16 @@ func (ui *gameui) ExplosionAnimation(es explosionStyle, pos position) {
 			nb = append(nb, pos)
 		}
 		for _, npos := range nb {
-			fg := colors[RandInt(2)]
+			fg := colors[RandIntUI(2)]
 			if !g.Player.LOS[npos] {
 				continue
 			}
 			ui.ExplosionAnimationAt(npos, fg)
 		}
 		ui.Flush()
-		time.Sleep(100 * time.Millisecond)
+		UISleep(100 * time.Millisecond)
 	}
-	time.Sleep(20 * time.Millisecond)
+	UISleep(20 * time.Millisecond)
 }
 
 func (ui *gameui) TormentExplosionAnimation() {
@@ -137,20 +137,20 @@ func (ui *gameui) TormentExplosionAnimation() {
 		return
 	}
 	ui.DrawDungeonView(NormalMode)
-	time.Sleep(20 * time.Millisecond)
+	UISleep(20 * time.Millisecond)
 	colors := [3]uicolor{ColorFgExplosionStart, ColorFgExplosionEnd, ColorFgMagicPlace}
 	for i := 0; i < 3; i++ {
 		for npos, b := range g.Player.LOS {
 			if !b {
 				continue
 			}
-			fg := colors[RandInt(3)]
+			fg := colors[RandIntUI(3)]
 			ui.ExplosionAnimationAt(npos, fg)
 		}
 		ui.Flush()
-		time.Sleep(100 * time.Millisecond)
+		UISleep(100 * time.Millisecond)
 	}
-	time.Sleep(20 * time.Millisecond)
+	UISleep(20 * time.Millisecond)
 }
 
 func (ui *gameui) WallExplosionAnimation(pos position) {
@@ -163,7 +163,7 @@ func (ui *gameui) WallExplosionAnimation(pos position) {
 		//ui.DrawAtPosition(pos, true, '☼', fg, bgColor)
 		ui.DrawAtPosition(pos, true, '☼', bgColor, fg)
 		ui.Flush()
-		time.Sleep(25 * time.Millisecond)
+		UISleep(25 * time.Millisecond)
 	}
 }
 
@@ -173,16 +173,16 @@ func (ui *gameui) FireBoltAnimation(ray []position) {
 		return
 	}
 	ui.DrawDungeonView(NormalMode)
-	time.Sleep(25 * time.Millisecond)
+	UISleep(25 * time.Millisecond)
 	colors := [2]uicolor{ColorFgExplosionStart, ColorFgExplosionEnd}
 	for j := 0; j < 3; j++ {
 		for i := len(ray) - 1; i >= 0; i-- {
-			fg := colors[RandInt(2)]
+			fg := colors[RandIntUI(2)]
 			pos := ray[i]
 			_, _, bgColor := ui.PositionDrawing(pos)
 			mons := g.MonsterAt(pos)
 			r := '*'
-			if RandInt(2) == 0 {
+			if RandIntUI(2) == 0 {
 				r = '×'
 			}
 			if mons.Exists() {
@@ -192,9 +192,9 @@ func (ui *gameui) FireBoltAnimation(ray []position) {
 			ui.DrawAtPosition(pos, true, r, bgColor, fg)
 		}
 		ui.Flush()
-		time.Sleep(100 * time.Millisecond)
+		UISleep(100 * time.Millisecond)
 	}
-	time.Sleep(25 * time.Millisecond)
+	UISleep(25 * time.Millisecond)
 }
 
 func (ui *gameui) SlowingMagaraAnimation(ray []position) {
@@ -202,23 +202,23 @@ func (ui *gameui) SlowingMagaraAnimation(ray []position) {
 		return
 	}
 	ui.DrawDungeonView(NormalMode)
-	time.Sleep(25 * time.Millisecond)
+	UISleep(25 * time.Millisecond)
 	colors := [2]uicolor{ColorFgConfusedMonster, ColorFgMagicPlace}
 	for j := 0; j < 3; j++ {
 		for i := len(ray) - 1; i >= 0; i-- {
-			fg := colors[RandInt(2)]
+			fg := colors[RandIntUI(2)]
 			pos := ray[i]
 			_, _, bgColor := ui.PositionDrawing(pos)
 			r := '*'
-			if RandInt(2) == 0 {
+			if RandIntUI(2) == 0 {
 				r = '×'
 			}
 			ui.DrawAtPosition(pos, true, r, bgColor, fg)
 		}
 		ui.Flush()
-		time.Sleep(100 * time.Millisecond)
+		UISleep(100 * time.Millisecond)
 	}
-	time.Sleep(25 * time.Millisecond)
+	UISleep(25 * time.Millisecond)
 }
 
 func (ui *gameui) ProjectileSymbol(dir direction) (r rune) {
@@ -241,20 +241,20 @@ func (ui *gameui) ThrowAnimation(ray []position, hit bool) {
 		return
 	}
 	ui.DrawDungeonView(NormalMode)
-	time.Sleep(25 * time.Millisecond)
+	UISleep(25 * time.Millisecond)
 	for i := len(ray) - 1; i >= 0; i-- {
 		pos := ray[i]
 		r, fgColor, bgColor := ui.PositionDrawing(pos)
 		ui.DrawAtPosition(pos, true, ui.ProjectileSymbol(pos.Dir(g.Player.Pos)), ColorFgProjectile, bgColor)
 		ui.Flush()
-		time.Sleep(30 * time.Millisecond)
+		UISleep(30 * time.Millisecond)
 		ui.DrawAtPosition(pos, true, r, fgColor, bgColor)
 	}
 	if hit {
 		pos := ray[0]
 		ui.HitAnimation(pos, true)
 	}
-	time.Sleep(30 * time.Millisecond)
+	UISleep(30 * time.Millisecond)
 }
 
 func (ui *gameui) MonsterJavelinAnimation(ray []position, hit bool) {
@@ -263,16 +263,16 @@ func (ui *gameui) MonsterJavelinAnimation(ray []position, hit bool) {
 		return
 	}
 	ui.DrawDungeonView(NormalMode)
-	time.Sleep(25 * time.Millisecond)
+	UISleep(25 * time.Millisecond)
 	for i := 0; i < len(ray); i++ {
 		pos := ray[i]
 		r, fgColor, bgColor := ui.PositionDrawing(pos)
 		ui.DrawAtPosition(pos, true, ui.ProjectileSymbol(pos.Dir(g.Player.Pos)), ColorFgMonster, bgColor)
 		ui.Flush()
-		time.Sleep(30 * time.Millisecond)
+		UISleep(30 * time.Millisecond)
 		ui.DrawAtPosition(pos, true, r, fgColor, bgColor)
 	}
-	time.Sleep(30 * time.Millisecond)
+	UISleep(30 * time.Millisecond)
 }
 
 func (ui *gameui) HitAnimation(pos position, targeting bool) {
@@ -292,7 +292,7 @@ func (ui *gameui) HitAnimation(pos position, targeting bool) {
 		ui.DrawAtPosition(pos, targeting, '∞', ColorFgAnimationHit, bgColor)
 	}
 	ui.Flush()
-	time.Sleep(50 * time.Millisecond)
+	UISleep(50 * time.Millisecond)
 }
 
 func (ui *gameui) LightningHitAnimation(targets []position) {
@@ -301,20 +301,20 @@ func (ui *gameui) LightningHitAnimation(targets []position) {
 		return
 	}
 	ui.DrawDungeonView(NormalMode)
-	time.Sleep(25 * time.Millisecond)
+	UISleep(25 * time.Millisecond)
 	colors := [2]uicolor{ColorFgExplosionStart, ColorFgExplosionEnd}
 	for j := 0; j < 2; j++ {
 		for _, pos := range targets {
 			_, _, bgColor := ui.PositionDrawing(pos)
 			mons := g.MonsterAt(pos)
 			if mons.Exists() || pos == g.Player.Pos {
-				ui.DrawAtPosition(pos, false, '√', bgColor, colors[RandInt(2)])
+				ui.DrawAtPosition(pos, false, '√', bgColor, colors[RandIntUI(2)])
 			} else {
-				ui.DrawAtPosition(pos, false, '∞', bgColor, colors[RandInt(2)])
+				ui.DrawAtPosition(pos, false, '∞', bgColor, colors[RandIntUI(2)])
 			}
 		}
 		ui.Flush()
-		time.Sleep(100 * time.Millisecond)
+		UISleep(100 * time.Millisecond)
 	}
 }
 
@@ -327,11 +327,11 @@ func (ui *gameui) WoundedAnimation() {
 	r, _, bg := ui.PositionDrawing(g.Player.Pos)
 	ui.DrawAtPosition(g.Player.Pos, false, r, ColorFgHPwounded, bg)
 	ui.Flush()
-	time.Sleep(50 * time.Millisecond)
+	UISleep(50 * time.Millisecond)
 	if g.Player.HP <= 15 {
 		ui.DrawAtPosition(g.Player.Pos, false, r, ColorFgHPcritical, bg)
 		ui.Flush()
-		time.Sleep(50 * time.Millisecond)
+		UISleep(50 * time.Millisecond)
 	}
 }
 
@@ -341,14 +341,14 @@ func (ui *gameui) DrinkingPotionAnimation() {
 		return
 	}
 	ui.DrawDungeonView(NoFlushMode)
-	time.Sleep(50 * time.Millisecond)
+	UISleep(50 * time.Millisecond)
 	r, fg, bg := ui.PositionDrawing(g.Player.Pos)
 	ui.DrawAtPosition(g.Player.Pos, false, r, ColorGreen, bg)
 	ui.Flush()
-	time.Sleep(75 * time.Millisecond)
+	UISleep(75 * time.Millisecond)
 	ui.DrawAtPosition(g.Player.Pos, false, r, ColorYellow, bg)
 	ui.Flush()
-	time.Sleep(75 * time.Millisecond)
+	UISleep(75 * time.Millisecond)
 	ui.DrawAtPosition(g.Player.Pos, false, r, fg, bg)
 	ui.Flush()
 }
@@ -362,7 +362,7 @@ func (ui *gameui) StatusEndAnimation() {
 	r, _, bg := ui.PositionDrawing(g.Player.Pos)
 	ui.DrawAtPosition(g.Player.Pos, false, r, ColorViolet, bg)
 	ui.Flush()
-	time.Sleep(100 * time.Millisecond)
+	UISleep(100 * time.Millisecond)
 }
 
 func (ui *gameui) MenuSelectedAnimation(m menu, ok bool) {
@@ -385,7 +385,7 @@ func (ui *gameui) MenuSelectedAnimation(m menu, ok bool) {
 			ui.DrawColoredText(message, MenuCols[m][0], DungeonHeight, ColorMagenta)
 		}
 		ui.Flush()
-		time.Sleep(25 * time.Millisecond)
+		UISleep(25 * time.Millisecond)
 		ui.DrawColoredText(m.String(), MenuCols[m][0], DungeonHeight, ColorViolet)
 	}
 }
@@ -400,5 +400,5 @@ func (ui *gameui) MagicMappingAnimation(border []int) {
 		ui.DrawAtPosition(pos, false, r, fg, bg)
 	}
 	ui.Flush()
-	time.Sleep(12 * time.Millisecond)
+	UISleep(12 * time.Millisecond)
 }
@@ -5,52 +5,66 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 )
 
-var ch chan uiInput
-var interrupt chan bool
-
-func init() {
-	ch = make(chan uiInput, 100)
-	interrupt = make(chan bool)
-}
-
 type gameui struct {
-	g       *game
-	bStdin  *bufio.Reader
-	bStdout *bufio.Writer
-	cursor  position
-	stty    string
+	g         *game
+	in        io.Reader // standard input if nil
+	out       io.Writer // standard output if nil
+	bStdin    *bufio.Reader
+	bStdout   *bufio.Writer
+	cursor    position
+	stty      string
+	ch        chan uiInput
+	interrupt chan bool
+	// session, if not nil, is released while waiting for input, so that
+	// other games of the process can run (see telnet.go)
+	session interface {
+		Release()
+		Acquire()
+	}
 	// below unused for this backend
 	menuHover menu
 	itemHover int
 }
 
 func (ui *gameui) Init() error {
-	ui.bStdin = bufio.NewReader(os.Stdin)
-	ui.bStdout = bufio.NewWriter(os.Stdout)
+	ui.ch = make(chan uiInput, 100)
+	ui.interrupt = make(chan bool)
+	if ui.in == nil {
+		ui.in = os.Stdin
+		cmd := exec.Command("stty", "-g")
+		cmd.Stdin = os.Stdin
+		save, err := cmd.Output()
+		if err != nil {
+			save = []byte("sane")
+		}
+		ui.stty = string(save)
+		cmd = exec.Command("stty", "raw", "-echo")
+		cmd.Stdin = os.Stdin
+		cmd.Run()
+	}
+	if ui.out == nil {
+		ui.out = os.Stdout
+	}
+	ui.bStdin = bufio.NewReader(ui.in)
+	ui.bStdout = bufio.NewWriter(ui.out)
 	fmt.Fprint(ui.bStdout, "\x1b[2J")
 	ui.HideCursor()
 	fmt.Fprintf(ui.bStdout, "\x1b[?25l")
-	cmd := exec.Command("stty", "-g")
-	cmd.Stdin = os.Stdin
-	save, err := cmd.Output()
-	if err != nil {
-		save = []byte("sane")
-	}
-	ui.stty = string(save)
-	cmd = exec.Command("stty", "raw", "-echo")
-	cmd.Stdin = os.Stdin
-	cmd.Run()
 	ui.menuHover = -1
 	go func() {
 		for {
 			r, _, err := ui.bStdin.ReadRune()
-			if err == nil {
-				ch <- uiInput{key: string(r)}
+			if err != nil {
+				// input closed, as for a lost telnet connection
+				close(ui.ch)
+				return
 			}
+			ui.ch <- uiInput{key: string(r)}
 		}
 	}()
 
@@ -61,6 +75,9 @@ func (ui *gameui) Close() {
 	fmt.Fprint(ui.bStdout, "\x1b[2J")
 	fmt.Fprintf(ui.bStdout, "\x1b[?25h")
 	ui.bStdout.Flush()
+	if ui.stty == "" {
+		return
+	}
 	cmd := exec.Command("stty", ui.stty)
 	cmd.Stdin = os.Stdin
 	err := cmd.Run()
@@ -77,49 +94,7 @@ func (ui *gameui) MoveTo(x, y int) {
 
 func (ui *gameui) Flush() {
 	ui.DrawLogFrame()
-	var prevfg, prevbg uicolor
-	first := true
-	var prevx, prevy int
-	for _, cdraw := range ui.g.DrawLog[len(ui.g.DrawLog)-1].Draws {
-		cell := cdraw.Cell
-		x, y := cdraw.X, cdraw.Y
-		pfg := true
-		pbg := true
-		pxy := true
-		if first {
-			prevfg = cell.Fg
-			prevbg = cell.Bg
-			prevx = x
-			prevy = y
-			first = false
-		} else {
-			if prevfg == cell.Fg {
-				pfg = false
-			} else {
-				prevfg = cell.Fg
-			}
-			if prevbg == cell.Bg {
-				pbg = false
-			} else {
-				prevbg = cell.Bg
-			}
-			if x == prevx+1 && y == prevy {
-				pxy = false
-			}
-		}
-		if pxy {
-			ui.MoveTo(x, y)
-		}
-		if pfg {
-			fmt.Fprintf(ui.bStdout, "\x1b[38;5;%dm", cell.Fg)
-		}
-		if pbg {
-			fmt.Fprintf(ui.bStdout, "\x1b[48;5;%dm", cell.Bg)
-		}
-		ui.bStdout.WriteRune(cell.R)
-	}
-	ui.MoveTo(ui.cursor.X, ui.cursor.Y)
-	fmt.Fprintf(ui.bStdout, "\x1b[0m")
+	WriteANSIDraws(ui.bStdout, ui.g.DrawLog[len(ui.g.DrawLog)-1].Draws, ui.cursor)
 	ui.bStdout.Flush()
 }
 
@@ -143,13 +118,23 @@ func (ui *gameui) Small() bool {
 }
 
 func (ui *gameui) Interrupt() {
-	interrupt <- true
+	ui.interrupt <- true
 }
 
 func (ui *gameui) PollEvent() (in uiInput) {
+	if ui.session != nil {
+		ui.session.Release()
+		defer ui.session.Acquire()
+	}
+	var ok bool
 	select {
-	case in = <-ch:
-	case in.interrupt = <-interrupt:
+	case in, ok = <-ui.ch:
+		if !ok {
+			// escape exits menus until the next player turn
+			ui.g.hangup = true
+			in = uiInput{key: "\x1b"}
+		}
+	case in.interrupt = <-ui.interrupt:
 	}
 	return in
 }
@@ -64,7 +64,7 @@ func (g *game) RandomApt() (aptitude, bool) {
 		if count > 1000 {
 			break
 		}
-		r := RandInt(NumApts)
+		r := g.RandInt(NumApts)
 		apt = aptitude(r)
 		if g.Player.Aptitudes[apt] {
 			continue
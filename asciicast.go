@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+type runeWriter interface {
+	io.Writer
+	WriteRune(r rune) (int, error)
+}
+
+// WriteANSIDraws writes ANSI escape sequences drawing cells on a terminal
+// with 256 colors support.
+func WriteANSIDraws(w runeWriter, draws []cellDraw, cursor position) {
+	var prevfg, prevbg uicolor
+	first := true
+	var prevx, prevy int
+	for _, cdraw := range draws {
+		cell := cdraw.Cell
+		x, y := cdraw.X, cdraw.Y
+		pfg := true
+		pbg := true
+		pxy := true
+		if first {
+			prevfg = cell.Fg
+			prevbg = cell.Bg
+			first = false
+		} else {
+			if prevfg == cell.Fg {
+				pfg = false
+			} else {
+				prevfg = cell.Fg
+			}
+			if prevbg == cell.Bg {
+				pbg = false
+			} else {
+				prevbg = cell.Bg
+			}
+			if x == prevx+1 && y == prevy {
+				pxy = false
+			}
+		}
+		prevx, prevy = x, y
+		if pxy {
+			fmt.Fprintf(w, "\x1b[%d;%dH", y+1, x+1)
+		}
+		if pfg {
+			fmt.Fprintf(w, "\x1b[38;5;%dm", cell.Fg)
+		}
+		if pbg {
+			fmt.Fprintf(w, "\x1b[48;5;%dm", cell.Bg)
+		}
+		w.WriteRune(cell.R)
+	}
+	fmt.Fprintf(w, "\x1b[%d;%dH", cursor.Y+1, cursor.X+1)
+	fmt.Fprintf(w, "\x1b[0m")
+}
+
+type asciicastHeader struct {
+	Version       int               `json:"version"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Timestamp     int64             `json:"timestamp,omitempty"`
+	IdleTimeLimit float64           `json:"idle_time_limit"`
+	Env           map[string]string `json:"env"`
+}
+
+// asciicastIdleTimeLimit is the maximum pause between frames, in seconds,
+// when playing an exported replay.
+const asciicastIdleTimeLimit = 2
+
+// WriteAsciicast converts replay frames into an asciicast v2 recording,
+// with colors of the 256-color palette if color256 is true, or of the
+// 16-color palette otherwise.
+func WriteAsciicast(w io.Writer, frames []drawFrame, color256 bool) error {
+	width, height := 80, 24
+	var start time.Time
+	for _, df := range frames {
+		if start.IsZero() {
+			start = df.Time
+		}
+		for _, dr := range df.Draws {
+			width = Max(width, dr.X+1)
+			height = Max(height, dr.Y+1)
+		}
+	}
+	header := asciicastHeader{
+		Version:       2,
+		Width:         width,
+		Height:        height,
+		IdleTimeLimit: asciicastIdleTimeLimit,
+		Env:           map[string]string{"TERM": "xterm-256color"},
+	}
+	if !start.IsZero() {
+		header.Timestamp = start.Unix()
+	}
+	enc := json.NewEncoder(w)
+	err := enc.Encode(header)
+	if err != nil {
+		return err
+	}
+	err = enc.Encode([]interface{}{0.0, "o", "\x1b[2J\x1b[?25l"})
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	for i, df := range frames {
+		if len(df.Draws) == 0 {
+			continue
+		}
+		draws := make([]cellDraw, len(df.Draws))
+		for j, dr := range df.Draws {
+			if color256 {
+				dr.Cell.Fg = Map16ColorTo256(dr.Cell.Fg)
+				dr.Cell.Bg = Map16ColorTo256(dr.Cell.Bg)
+			} else {
+				dr.Cell.Fg = Map256ColorTo16(dr.Cell.Fg)
+				dr.Cell.Bg = Map256ColorTo16(dr.Cell.Bg)
+			}
+			draws[j] = dr
+		}
+		buf.Reset()
+		WriteANSIDraws(buf, draws, position{0, height - 1})
+		t := df.Time.Sub(start).Seconds()
+		if start.IsZero() || df.Time.IsZero() {
+			// no timing information
+			t = float64(i) / 10
+		}
+		err = enc.Encode([]interface{}{t, "o", buf.String()})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
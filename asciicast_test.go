@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAsciicast(t *testing.T) {
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	frames := []drawFrame{
+		{Time: start, Draws: []cellDraw{
+			{Cell: UICell{R: '@', Fg: Color16Blue, Bg: Color16Base03}, X: 3, Y: 2},
+			{Cell: UICell{R: '.', Fg: Color16Base0, Bg: Color16Base03}, X: 4, Y: 2},
+		}},
+		{Time: start.Add(time.Second)},
+		{Time: start.Add(1500 * time.Millisecond), Draws: []cellDraw{
+			{Cell: UICell{R: 'g', Fg: Color16Red, Bg: Color16Base03}, X: 99, Y: 25},
+		}},
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteAsciicast(buf, frames, true); err != nil {
+		t.Fatal(err)
+	}
+	sc := bufio.NewScanner(buf)
+	sc.Scan()
+	var header asciicastHeader
+	if err := json.Unmarshal(sc.Bytes(), &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != 2 || header.Width != 100 || header.Height != 26 || header.Timestamp != start.Unix() {
+		t.Errorf("bad header: %+v", header)
+	}
+	events := [][]interface{}{}
+	for sc.Scan() {
+		var ev []interface{}
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) != 3 {
+		t.Fatalf("bad number of events: %d", len(events))
+	}
+	if events[2][0].(float64) != 1.5 {
+		t.Errorf("bad event time: %v", events[2][0])
+	}
+	out := events[1][2].(string)
+	if !strings.Contains(out, "\x1b[3;4H\x1b[38;5;33m\x1b[48;5;234m@\x1b[38;5;244m.") {
+		t.Errorf("bad frame output: %q", out)
+	}
+}
@@ -40,17 +40,20 @@ type node struct {
 
 type nodeMap map[position]*node
 
-var nodeCache []node
-
-func init() {
-	nodeCache = make([]node, 0, DungeonNCells)
+// SortedPositions returns the positions of the node map in a fixed order.
+func (nm nodeMap) SortedPositions() []position {
+	ps := make([]position, 0, len(nm))
+	for pos := range nm {
+		ps = append(ps, pos)
+	}
+	sortPositions(ps)
+	return ps
 }
 
 func (nm nodeMap) get(p position) *node {
 	n, ok := nm[p]
 	if !ok {
-		nodeCache = append(nodeCache, node{Pos: p})
-		n = &nodeCache[len(nodeCache)-1]
+		n = &node{Pos: p}
 		nm[p] = n
 	}
 	return n
@@ -63,7 +66,6 @@ type Astar interface {
 }
 
 func AstarPath(ast Astar, from, to position) (path []position, length int, found bool) {
-	nodeCache = nodeCache[:0]
 	nm := nodeMap{}
 	nq := &priorityQueue{}
 	heap.Init(nq)
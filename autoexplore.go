@@ -2,8 +2,6 @@ package main
 
 import "errors"
 
-var DijkstraMapCache [DungeonNCells]int
-
 func (g *game) Autoexplore(ev event) error {
 	if mons := g.MonsterInLOS(); mons.Exists() {
 		return errors.New("You cannot auto-explore while there are monsters in view.")
@@ -79,7 +77,7 @@ func (g *game) BuildAutoexploreMap(sources []int) {
 
 func (g *game) NextAuto() (next *position, finished bool) {
 	ap := &autoexplorePath{game: g}
-	if DijkstraMapCache[g.Player.Pos.idx()] == unreachable {
+	if g.dijkstraMap[g.Player.Pos.idx()] == unreachable {
 		return nil, false
 	}
 	neighbors := ap.Neighbors(g.Player.Pos)
@@ -87,15 +85,15 @@ func (g *game) NextAuto() (next *position, finished bool) {
 		return nil, false
 	}
 	n := neighbors[0]
-	ncost := DijkstraMapCache[n.idx()]
+	ncost := g.dijkstraMap[n.idx()]
 	for _, pos := range neighbors[1:] {
-		cost := DijkstraMapCache[pos.idx()]
+		cost := g.dijkstraMap[pos.idx()]
 		if cost < ncost {
 			n = pos
 			ncost = cost
 		}
 	}
-	if ncost >= DijkstraMapCache[g.Player.Pos.idx()] {
+	if ncost >= g.dijkstraMap[g.Player.Pos.idx()] {
 		finished = true
 	}
 	next = &n
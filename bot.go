@@ -0,0 +1,251 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PlayerController chooses the player actions in place of the user
+// interface. It is used for scripted players, like regression bots.
+type PlayerController interface {
+	NextAction(v *playerView) playerAction
+}
+
+// playerAction is an action chosen by a player controller. Item actions
+// (KeyDrink, KeyThrow and KeyEvoke) need the item to use, and targeted
+// items need a target position.
+type playerAction struct {
+	Key        keyAction
+	Consumable consumable // potion for KeyDrink, projectile for KeyThrow
+	Rod        rod        // rod for KeyEvoke
+	Target     position
+}
+
+// playerView is what the player legitimately knows at a given turn.
+type playerView struct {
+	Turn        int
+	Depth       int
+	Pos         position
+	HP          int
+	HPMax       int
+	MP          int
+	MPMax       int
+	Simellas    int
+	Statuses    map[status]int
+	LOS         map[position]bool
+	Explored    map[position]terrain
+	Stairs      []position // explored stairs
+	OnStairs    bool
+	Monsters    []monsterView
+	Armour      armour
+	Weapon      weapon
+	Shield      shield
+	Consumables map[consumable]int
+	Rods        map[rod]int // charges
+	LastAction  keyAction
+	Error       string // error of the last action, if any
+}
+
+type monsterView struct {
+	Kind  monsterKind
+	Pos   position
+	HP    int
+	State monsterState
+}
+
+func (g *game) SetController(c PlayerController) {
+	g.controller = c
+}
+
+func (g *game) PlayerView() *playerView {
+	v := &playerView{
+		Turn:        g.Turn,
+		Depth:       g.Depth,
+		Pos:         g.Player.Pos,
+		HP:          g.Player.HP,
+		HPMax:       g.Player.HPMax(),
+		MP:          g.Player.MP,
+		MPMax:       g.Player.MPMax(),
+		Simellas:    g.Player.Simellas,
+		Statuses:    map[status]int{},
+		LOS:         map[position]bool{},
+		Explored:    map[position]terrain{},
+		Stairs:      g.StairsSlice(),
+		Armour:      g.Player.Armour,
+		Weapon:      g.Player.Weapon,
+		Shield:      g.Player.Shield,
+		Consumables: map[consumable]int{},
+		Rods:        map[rod]int{},
+	}
+	_, v.OnStairs = g.Stairs[g.Player.Pos]
+	for st, n := range g.Player.Statuses {
+		if n > 0 {
+			v.Statuses[st] = n
+		}
+	}
+	for pos, b := range g.Player.LOS {
+		if b {
+			v.LOS[pos] = true
+		}
+	}
+	for i, c := range g.Dungeon.Cells {
+		if c.Explored {
+			pos := idxtopos(i)
+			v.Explored[pos] = g.RememberedTerrain(pos)
+		}
+	}
+	for _, mons := range g.Monsters {
+		if mons.Exists() && g.Player.LOS[mons.Pos] {
+			v.Monsters = append(v.Monsters, monsterView{Kind: mons.Kind, Pos: mons.Pos, HP: mons.HP, State: mons.State})
+		}
+	}
+	for c, n := range g.Player.Consumables {
+		if n > 0 {
+			v.Consumables[c] = n
+		}
+	}
+	for r, props := range g.Player.Rods {
+		v.Rods[r] = props.Charge
+	}
+	return v
+}
+
+// RememberedTerrain returns the terrain at an explored position as the
+// player last saw it, which may differ from the current one, as in the map
+// drawing.
+func (g *game) RememberedTerrain(pos position) terrain {
+	t := g.Dungeon.Cell(pos).T
+	if g.WrongWall[pos] {
+		if t == WallCell {
+			t = FreeCell
+		} else {
+			t = WallCell
+		}
+	}
+	return t
+}
+
+// maxInvalidActions is the number of invalid actions in a row after which
+// a player controller is considered stuck.
+const maxInvalidActions = 100
+
+// ControllerTurn asks the player controller for actions until one of them
+// is valid. The error of an invalid action is given to the controller with
+// the next view. A stuck controller ends the game with the "error" outcome
+// (see ControllerError). It returns true if the game should end.
+func (g *game) ControllerTurn(ev event) bool {
+	g.Animations().DrawDungeonView(NormalMode)
+	v := g.PlayerView()
+	for i := 0; i < maxInvalidActions; i++ {
+		a := g.controller.NextAction(v)
+		err, quit := g.PlayerAction(a, ev)
+		if err == nil || quit {
+			return quit
+		}
+		v.LastAction = a.Key
+		v.Error = err.Error()
+	}
+	g.controllerErr = fmt.Errorf("player controller stuck after %d invalid actions (last error: %s)", maxInvalidActions, v.Error)
+	g.StoryPrint("Game ended by a player controller error")
+	g.EndGame("error")
+	return true
+}
+
+// ControllerError returns the error that ended the game, if the player
+// controller got stuck.
+func (g *game) ControllerError() error {
+	return g.controllerErr
+}
+
+// ChooseTarget chooses a target for a targeted item. With a player
+// controller, the target is the one given with the action.
+func (g *game) ChooseTarget(targ Targeter) error {
+	if g.controller == nil {
+		ui, ok := g.ui.(promptUI)
+		if !ok {
+			return errors.New(DoNothing)
+		}
+		return ui.ChooseTarget(targ)
+	}
+	err := targ.Action(g, g.controllerTarget)
+	if err != nil {
+		return err
+	}
+	if !targ.Done() {
+		return errors.New(DoNothing)
+	}
+	return nil
+}
+
+// PlayerAction performs a player action that does not need any further
+// user interaction.
+func (g *game) PlayerAction(a playerAction, ev event) (err error, quit bool) {
+	g.controllerTarget = a.Target
+	switch k := a.Key; k {
+	case KeyW, KeyS, KeyN, KeyE, KeyNW, KeyNE, KeySW, KeySE:
+		err = g.MovePlayer(g.Player.Pos.To(KeyToDir(k)), ev)
+	case KeyRunW, KeyRunS, KeyRunN, KeyRunE, KeyRunNW, KeyRunNE, KeyRunSW, KeyRunSE:
+		err = g.GoToDir(KeyToDir(k), ev)
+	case KeyWaitTurn:
+		g.WaitTurn(ev)
+	case KeyRest:
+		err = g.Rest(ev)
+	case KeyDescend:
+		if _, ok := g.Stairs[g.Player.Pos]; !ok {
+			return errors.New("No stairs here."), false
+		}
+		if g.Descend() {
+			return nil, true
+		}
+	case KeyGoToStairs:
+		err = g.GoToStairs(ev)
+	case KeyEquip:
+		err = g.Equip(ev)
+	case KeyExplore:
+		err = g.Autoexplore(ev)
+	case KeyDrink, KeyThrow:
+		c := a.Consumable
+		if c == nil || g.Player.Consumables[c] <= 0 {
+			return errors.New("You do not have this item."), false
+		}
+		if _, ok := c.(potion); ok != (k == KeyDrink) {
+			if k == KeyDrink {
+				return errors.New("You cannot drink this item."), false
+			}
+			return errors.New("You cannot throw this item."), false
+		}
+		err = c.Use(g, ev)
+	case KeyEvoke:
+		if _, ok := g.Player.Rods[a.Rod]; !ok {
+			return errors.New("You do not have this rod."), false
+		}
+		err = a.Rod.Use(g, ev)
+	case KeyQuit:
+		return nil, true
+	default:
+		err = errors.New("Action not available for player controllers.")
+	}
+	return err, false
+}
+
+// exploreBot is a simple reference player controller: it explores each
+// level, fights adjacent monsters, and then goes to the nearest stairs.
+type exploreBot struct{}
+
+func (b exploreBot) NextAction(v *playerView) playerAction {
+	for _, m := range v.Monsters {
+		if m.Pos.Distance(v.Pos) == 1 {
+			return playerAction{Key: DirToKey(m.Pos.Dir(v.Pos))}
+		}
+	}
+	switch {
+	case v.Error == "":
+		return playerAction{Key: KeyExplore}
+	case v.LastAction == KeyExplore:
+		return playerAction{Key: KeyGoToStairs}
+	case v.LastAction == KeyGoToStairs && v.OnStairs:
+		return playerAction{Key: KeyDescend}
+	default:
+		return playerAction{Key: KeyWaitTurn}
+	}
+}
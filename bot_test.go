@@ -0,0 +1,149 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+type limitedBot struct {
+	exploreBot
+	actions int
+	max     int
+}
+
+func (b *limitedBot) NextAction(v *playerView) playerAction {
+	b.actions++
+	if b.actions > b.max {
+		return playerAction{Key: KeyQuit}
+	}
+	return b.exploreBot.NextAction(v)
+}
+
+func runBot(seed int64) *game {
+	g, _ := NewHeadlessGame(seed)
+	g.SetController(&limitedBot{max: 3000})
+	g.EventLoop()
+	return g
+}
+
+func TestExploreBot(t *testing.T) {
+	descended := false
+	for seed := int64(1); seed <= 10; seed++ {
+		g := runBot(seed)
+		depth, turn, hp, pos := g.Depth, g.Turn, g.Player.HP, g.Player.Pos
+		g = runBot(seed)
+		if depth != g.Depth || turn != g.Turn || hp != g.Player.HP || pos != g.Player.Pos {
+			t.Errorf("seed %d: bot games differ", seed)
+		}
+		if g.Depth != 1 {
+			descended = true
+		}
+	}
+	if !descended {
+		t.Errorf("bot never descended")
+	}
+}
+
+func TestParallelGames(t *testing.T) {
+	// each game has its own random source, so games running at the
+	// same time do not change each other
+	g := runBot(3)
+	games := make(chan *game)
+	for i := 0; i < 4; i++ {
+		go func() { games <- runBot(3) }()
+	}
+	for i := 0; i < 4; i++ {
+		pg := <-games
+		if pg.Depth != g.Depth || pg.Turn != g.Turn || pg.Player.HP != g.Player.HP || pg.Player.Pos != g.Player.Pos {
+			t.Errorf("parallel game differs")
+		}
+	}
+}
+
+func TestPlayerViewExplored(t *testing.T) {
+	g, _ := NewHeadlessGame(1)
+	pos := InvalidPos
+	for i, c := range g.Dungeon.Cells {
+		if c.Explored && c.T == WallCell {
+			pos = idxtopos(i)
+			break
+		}
+	}
+	// the wall was destroyed out of sight
+	g.Dungeon.SetCell(pos, FreeCell)
+	g.WrongWall[pos] = true
+	if v := g.PlayerView(); v.Explored[pos] != WallCell {
+		t.Errorf("player view shows current terrain instead of remembered one")
+	}
+}
+
+type scriptBot struct {
+	actions []playerAction
+	errors  []string
+	turns   []int
+}
+
+func (b *scriptBot) NextAction(v *playerView) playerAction {
+	b.errors = append(b.errors, v.Error)
+	b.turns = append(b.turns, v.Turn)
+	if len(b.actions) == 0 {
+		return playerAction{Key: KeyQuit}
+	}
+	a := b.actions[0]
+	b.actions = b.actions[1:]
+	return a
+}
+
+type stuckBot struct{}
+
+func (b stuckBot) NextAction(v *playerView) playerAction {
+	return playerAction{Key: KeyDrink, Consumable: ConfusingDart}
+}
+
+func TestControllerStuck(t *testing.T) {
+	g, _ := NewHeadlessGame(1)
+	g.Player.Consumables[ConfusingDart] = 1
+	g.SetController(stuckBot{})
+	g.EventLoop()
+	if g.ControllerError() == nil || g.Outcome() != "error" {
+		t.Errorf("stuck controller did not end the game: %v (%s)", g.ControllerError(), g.Outcome())
+	}
+}
+
+func TestControllerItemActions(t *testing.T) {
+	g, _ := NewHeadlessGame(1)
+	wall := InvalidPos
+	for _, pos := range g.Player.Pos.ValidNeighbors() {
+		if !g.MonsterAt(pos).Exists() {
+			wall = pos
+			break
+		}
+	}
+	g.Dungeon.SetCell(wall, WallCell)
+	g.ComputeLOS()
+	g.Player.Consumables[ConfusingDart] = 1
+	g.Player.Consumables[HealWoundsPotion] = 1
+	g.Player.Rods[RodDigging] = rodProps{Charge: 2}
+	g.Player.MP = g.Player.MPMax()
+	b := &scriptBot{actions: []playerAction{
+		{Key: KeyDrink, Consumable: ConfusingDart},
+		{Key: KeyEvoke, Rod: RodDigging, Target: g.Player.Pos},
+		{Key: KeyEvoke, Rod: RodDigging, Target: wall},
+		{Key: KeyDrink, Consumable: HealWoundsPotion},
+	}}
+	g.SetController(b)
+	g.EventLoop()
+	errs := []string{"", "You cannot drink this item.", "You are not a wall.", "", ""}
+	if !reflect.DeepEqual(b.errors, errs) {
+		t.Errorf("bad action errors: %q", b.errors)
+	}
+	if b.turns[0] != b.turns[2] || b.turns[3] == b.turns[2] {
+		t.Errorf("bad turns: %v", b.turns)
+	}
+	if g.Dungeon.Cell(wall).T != FreeCell || g.Player.Rods[RodDigging].Charge != 1 {
+		t.Errorf("rod of digging not evoked")
+	}
+	if g.Player.Consumables[HealWoundsPotion] != 0 || g.Player.Consumables[ConfusingDart] != 1 {
+		t.Errorf("bad consumables: %v", g.Player.Consumables)
+	}
+}
@@ -0,0 +1,199 @@
+// +build !js
+
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Spectators receive a magic line followed by a gob stream of frames. The
+// first frame is a keyframe drawing the whole screen.
+
+const broadcastMagic = "boohu broadcast\n"
+
+// spectatorQueue is the number of frames that can wait to be sent to a
+// spectator before it is considered too slow and disconnected.
+const spectatorQueue = 1000
+
+type spectator struct {
+	conn   net.Conn
+	frames chan drawFrame
+}
+
+// broadcaster streams the frames drawn by the game to connected spectators.
+type broadcaster struct {
+	ln         net.Listener
+	mu         sync.Mutex
+	screen     *replayScreen
+	spectators map[*spectator]bool
+}
+
+// StartBroadcast listens for spectators on the given address.
+func StartBroadcast(addr string) (*broadcaster, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	b := &broadcaster{
+		ln:         ln,
+		screen:     newReplayScreen(UIWidth, UIHeight),
+		spectators: map[*spectator]bool{},
+	}
+	go b.Accept()
+	return b, nil
+}
+
+func (b *broadcaster) Accept() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		sp := &spectator{conn: conn, frames: make(chan drawFrame, spectatorQueue)}
+		b.mu.Lock()
+		sp.frames <- drawFrame{Draws: b.screen.Draws(), Time: time.Now()}
+		b.spectators[sp] = true
+		b.mu.Unlock()
+		go b.Serve(sp)
+	}
+}
+
+func (b *broadcaster) Serve(sp *spectator) {
+	defer b.Remove(sp)
+	w := bufio.NewWriter(sp.conn)
+	_, err := w.WriteString(broadcastMagic)
+	if err != nil {
+		return
+	}
+	enc := gob.NewEncoder(w)
+	for df := range sp.frames {
+		err = enc.Encode(&df)
+		if err == nil && len(sp.frames) == 0 {
+			err = w.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (b *broadcaster) Remove(sp *spectator) {
+	b.mu.Lock()
+	if b.spectators[sp] {
+		delete(b.spectators, sp)
+		close(sp.frames)
+	}
+	b.mu.Unlock()
+	sp.conn.Close()
+}
+
+// Send sends a frame to every spectator. Spectators that cannot keep up are
+// disconnected.
+func (b *broadcaster) Send(df drawFrame) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.screen.w != UIWidth || b.screen.h != UIHeight {
+		// the layout was set or toggled since the last frame
+		b.screen.Resize(UIWidth, UIHeight)
+	}
+	b.screen.Apply(df)
+	for sp := range b.spectators {
+		select {
+		case sp.frames <- df:
+		default:
+			delete(b.spectators, sp)
+			close(sp.frames)
+		}
+	}
+}
+
+func (b *broadcaster) Close() error {
+	err := b.ln.Close()
+	b.mu.Lock()
+	for sp := range b.spectators {
+		delete(b.spectators, sp)
+		close(sp.frames)
+	}
+	b.mu.Unlock()
+	return err
+}
+
+// Watch shows the game broadcast at the given address.
+func Watch(addr string) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	magic, err := r.ReadString('\n')
+	if err != nil || magic != broadcastMagic {
+		return errors.New("not a boohu broadcast")
+	}
+	ui := &gameui{}
+	g := &game{}
+	ui.g = g
+	g.ui = ui
+	err = ui.Init()
+	if err != nil {
+		return err
+	}
+	defer ui.Close()
+	ui.DrawBufferInit()
+	return ui.Watch(addr, r)
+}
+
+func (ui *gameui) Watch(addr string, r io.Reader) error {
+	rep := &replay{ui: ui, live: addr, screen: newReplayScreen(UIWidth, UIHeight)}
+	if ColorBase03 == Color256Base03 {
+		rep.color256 = true
+	}
+	frames := make(chan drawFrame, spectatorQueue)
+	errc := make(chan error, 1)
+	go func() {
+		dec := gob.NewDecoder(r)
+		for {
+			var df drawFrame
+			err := dec.Decode(&df)
+			if err != nil {
+				errc <- err
+				return
+			}
+			frames <- df
+		}
+	}()
+	evch := make(chan repEvent, 100)
+	go PollReplayKeyboardEvents(ui, evch)
+	for {
+		select {
+		case df := <-frames:
+			rep.screen.Apply(df)
+			if len(frames) == 0 {
+				rep.Draw()
+			}
+		case e := <-evch:
+			if e == ReplayQuit {
+				return nil
+			}
+		case err := <-errc:
+			if err == io.EOF {
+				rep.msg = "Broadcast ended [press q to quit]"
+			} else {
+				rep.msg = fmt.Sprintf("Connection lost: %v [press q to quit]", err)
+			}
+			rep.Draw()
+			for e := range evch {
+				if e == ReplayQuit {
+					return nil
+				}
+			}
+		}
+	}
+}
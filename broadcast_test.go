@@ -0,0 +1,50 @@
+// +build !js
+
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"net"
+	"testing"
+)
+
+func TestBroadcast(t *testing.T) {
+	b, err := StartBroadcast("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	w, h := UIWidth, UIHeight
+	defer func() { UIWidth, UIHeight = w, h }()
+	UIWidth, UIHeight = 0, 0
+	b.Send(drawFrame{Draws: textDraws("x", 0, 0)})
+	UIWidth, UIHeight = w, h
+	b.Send(drawFrame{Draws: textDraws("@.", 3, 2)})
+	b.Send(drawFrame{Draws: textDraws("#", 4, 2)})
+	conn, err := net.Dial("tcp", b.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	if magic, err := r.ReadString('\n'); err != nil || magic != broadcastMagic {
+		t.Fatalf("bad magic: %q (%v)", magic, err)
+	}
+	dec := gob.NewDecoder(r)
+	var keyframe drawFrame
+	if err := dec.Decode(&keyframe); err != nil {
+		t.Fatal(err)
+	}
+	if len(keyframe.Draws) != 2 || keyframe.Draws[0].Cell.R != '@' || keyframe.Draws[1].Cell.R != '#' {
+		t.Errorf("bad keyframe: %+v", keyframe.Draws)
+	}
+	b.Send(drawFrame{Draws: textDraws("g", 5, 2)})
+	var df drawFrame
+	if err := dec.Decode(&df); err != nil {
+		t.Fatal(err)
+	}
+	if len(df.Draws) != 1 || df.Draws[0].Cell.R != 'g' || df.Draws[0].X != 5 {
+		t.Errorf("bad frame: %+v", df.Draws)
+	}
+}
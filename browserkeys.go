@@ -0,0 +1,39 @@
+// +build js web
+
+package main
+
+import "unicode/utf8"
+
+// BrowserKey converts the name of a key from a browser keyboard event into
+// a key of the game.
+func BrowserKey(key string) string {
+	switch key {
+	case "Escape", "Space":
+		key = "\x1b"
+	case "Enter", "\r", "\n":
+		key = "."
+	case "ArrowLeft":
+		key = "4"
+	case "ArrowRight":
+		key = "6"
+	case "ArrowUp", "BackSpace":
+		key = "8"
+	case "ArrowDown":
+		key = "2"
+	case "Home":
+		key = "7"
+	case "End":
+		key = "1"
+	case "PageUp":
+		key = "9"
+	case "PageDown":
+		key = "3"
+	case "Numpad5", "Delete":
+		key = "5"
+	default:
+		if utf8.RuneCountInString(key) != 1 {
+			key = ""
+		}
+	}
+	return key
+}
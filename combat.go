@@ -5,7 +5,7 @@ package main
 func (g *game) Absorb(armor int) int {
 	absorb := 0
 	for i := 0; i <= 2; i++ {
-		absorb += RandInt(armor + 1)
+		absorb += g.RandInt(armor + 1)
 	}
 	q := absorb / 3
 	r := absorb % 3
@@ -17,13 +17,13 @@ func (g *game) Absorb(armor int) int {
 
 func (g *game) HitDamage(dt dmgType, base int, armor int) (attack int, clang bool) {
 	min := base / 2
-	attack = min + RandInt(base-min+1)
+	attack = min + g.RandInt(base-min+1)
 	absorb := g.Absorb(armor)
 	if dt == DmgMagical {
 		absorb = 2 * absorb / 3
 	}
 	attack -= absorb
-	if absorb > 0 && absorb >= 2*armor/3 && RandInt(2) == 0 {
+	if absorb > 0 && absorb >= 2*armor/3 && g.RandInt(2) == 0 {
 		clang = true
 	}
 	if attack < 0 {
@@ -32,15 +32,27 @@ func (g *game) HitDamage(dt dmgType, base int, armor int) (attack int, clang boo
 	return attack, clang
 }
 
-func (m *monster) InflictDamage(g *game, damage, max int) {
+// DamageSource returns a description of an attack by the monster, like "a
+// cyclops's rock", or of its melee attack if what is empty.
+func (m *monster) DamageSource(what string) string {
+	if what == "" {
+		return m.Kind.Indefinite(false)
+	}
+	return m.Kind.Indefinite(false) + "'s " + what
+}
+
+func (m *monster) InflictDamage(g *game, damage, max int, source string) {
 	g.Stats.ReceivedHits++
 	g.Stats.Damage += damage
 	oldHP := g.Player.HP
 	g.Player.HP -= damage
-	g.ui.WoundedAnimation()
+	g.Killer = source
+	g.Animations().WoundedAnimation()
 	if oldHP > max && g.Player.HP <= max {
-		g.StoryPrintf("Critical HP: %d (hit by %s)", g.Player.HP, m.Kind.Indefinite(false))
-		g.ui.CriticalHPWarning()
+		g.StoryPrintf("Critical HP: %d (hit by %s)", g.Player.HP, source)
+		if ui, ok := g.ui.(promptUI); ok {
+			ui.CriticalHPWarning()
+		}
 	}
 	if g.Player.HP <= 0 {
 		return
@@ -132,7 +144,7 @@ func (g *game) MakeNoise(noise int, at position) {
 		if v > 25 {
 			v = 25
 		}
-		r := RandInt(30)
+		r := g.RandInt(30)
 		if m.State == Resting {
 			v /= 2
 		}
@@ -172,7 +184,7 @@ func (g *game) AttackMonster(mons *monster, ev event) {
 		if !g.HitMonster(DmgPhysical, g.Player.Attack(), mons, ev) {
 			break
 		}
-		if RandInt(2) == 0 {
+		if g.RandInt(2) == 0 {
 			mons.EnterConfusion(g, ev)
 			g.PrintfStyled("Frundis glows… %s appears confused.", logPlayerHit, mons.Kind.Definite(false))
 		}
@@ -253,7 +265,7 @@ func (g *game) AttractMonster(pos position) *monster {
 		mons := g.MonsterAt(cpos)
 		if mons.Exists() {
 			mons.MoveTo(g, pos)
-			g.ui.TeleportAnimation(cpos, pos, false)
+			g.Animations().TeleportAnimation(cpos, pos, false)
 			return mons
 		}
 	}
@@ -365,18 +377,18 @@ func (g *game) HitMonster(dt dmgType, dmg int, mons *monster, ev event) (hit boo
 	} else if g.Player.Weapon == FinalBlade {
 		maxacc += 10
 	}
-	acc := RandInt(maxacc)
+	acc := g.RandInt(maxacc)
 	if g.Player.AccScore == 1 && acc >= maxacc/2 {
-		acc -= RandInt(1 + maxacc/2)
+		acc -= g.RandInt(1 + maxacc/2)
 	} else if g.Player.AccScore == -1 && acc < maxacc/2 {
-		acc += RandInt(1 + maxacc/2)
+		acc += g.RandInt(1 + maxacc/2)
 	}
 	if acc >= maxacc/2 {
 		g.Player.AccScore = 1
 	} else {
 		g.Player.AccScore = -1
 	}
-	evasion := RandInt(mons.Evasion)
+	evasion := g.RandInt(mons.Evasion)
 	if mons.State == Resting {
 		evasion /= 2 + 1
 	}
@@ -394,14 +406,14 @@ func (g *game) HitMonster(dt dmgType, dmg int, mons *monster, ev event) (hit boo
 		}
 		bonus := 0
 		if g.Player.HasStatus(StatusBerserk) {
-			bonus += 2 + RandInt(4)
+			bonus += 2 + g.RandInt(4)
 		}
 		pa := dmg + bonus
 		if g.Player.Weapon.Cleave() && g.InOpenMons(mons) {
 			if g.Player.Attack() >= 15 {
-				pa += 1 + RandInt(3)
+				pa += 1 + g.RandInt(3)
 			} else {
-				pa += 1 + RandInt(2)
+				pa += 1 + g.RandInt(2)
 			}
 		}
 		marmor := mons.Armor
@@ -444,7 +456,7 @@ func (g *game) HitMonster(dt dmgType, dmg int, mons *monster, ev event) (hit boo
 				g.Player.HP += healing
 			}
 		}
-		g.ui.HitAnimation(mons.Pos, false)
+		g.Animations().HitAnimation(mons.Pos, false)
 		if mons.HP > 0 {
 			g.PrintfStyled("You hit %s (%d dmg).%s", logPlayerHit, mons.Kind.Definite(false), attack, sclang)
 		} else if oldHP > 0 {
@@ -452,10 +464,10 @@ func (g *game) HitMonster(dt dmgType, dmg int, mons *monster, ev event) (hit boo
 			g.PrintfStyled("You kill %s (%d dmg).%s", logPlayerHit, mons.Kind.Definite(false), attack, sclang)
 			g.HandleKill(mons, ev)
 		}
-		if mons.Kind == MonsBrizzia && RandInt(4) == 0 && !g.Player.HasStatus(StatusNausea) &&
+		if mons.Kind == MonsBrizzia && g.RandInt(4) == 0 && !g.Player.HasStatus(StatusNausea) &&
 			mons.Pos.Distance(g.Player.Pos) == 1 {
 			g.Player.Statuses[StatusNausea]++
-			g.PushEvent(&simpleEvent{ERank: ev.Rank() + 30 + RandInt(20), EAction: NauseaEnd})
+			g.PushEvent(&simpleEvent{ERank: ev.Rank() + 30 + g.RandInt(20), EAction: NauseaEnd})
 			g.Print("The brizzia's corpse releases some nauseating gas. You feel sick.")
 		}
 		if mons.Kind == MonsTinyHarpy && mons.HP > 0 {
@@ -583,7 +595,7 @@ func (g *game) BlockEffects(m *monster) {
 			if !pos.valid() {
 				continue
 			}
-			if RandInt(3) == 0 && g.Dungeon.Cell(pos).T == WallCell {
+			if g.RandInt(3) == 0 && g.Dungeon.Cell(pos).T == WallCell {
 				g.Dungeon.SetCell(pos, FreeCell)
 				g.Stats.Digs++
 				g.MakeNoise(WallNoise+3, pos)
@@ -595,7 +607,7 @@ func (g *game) BlockEffects(m *monster) {
 		if m.Kind == MonsSatowalgaPlant || m.Pos.Distance(g.Player.Pos) > 1 {
 			break
 		}
-		if RandInt(5) == 0 {
+		if g.RandInt(5) == 0 {
 			break
 		}
 		dir := m.Pos.Dir(g.Player.Pos)
@@ -626,14 +638,14 @@ func (g *game) BlockEffects(m *monster) {
 		if m.Pos.Distance(g.Player.Pos) > 1 {
 			break
 		}
-		if RandInt(4) == 0 {
+		if g.RandInt(4) == 0 {
 			m.EnterConfusion(g, g.Ev)
 			g.Printf("%s appears confused.", m.Kind.Definite(true))
 		}
 	case FireShield:
 		dir := m.Pos.Dir(g.Player.Pos)
 		burnpos := g.Player.Pos.To(dir)
-		if RandInt(4) == 0 {
+		if g.RandInt(4) == 0 {
 			g.Print("Sparks emerge out of the shield.")
 			g.Burn(burnpos, g.Ev)
 		}
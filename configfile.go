@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// The configuration is stored in a human-editable text file, with
+// “name = value” settings, followed by key map sections, where each line
+// binds keys (separated by spaces) to an action: “move-west = h 4”.
+
+const configFileHeader = `# Boohu configuration file.
+#
+# Settings: dark-los, small, tiles and animations are booleans (true or
+# false), palette is one of xterm256, solarized or 8colors (empty for
+# default).
+#
+# Key maps: each line of sections [normal-keys] and [target-keys] binds
+# keys to an action. Keys are separated by spaces, with “space” and “esc”
+# for the corresponding keys.
+`
+
+var keyActionNames = map[keyAction]string{
+	KeyW:                 "move-west",
+	KeyS:                 "move-south",
+	KeyN:                 "move-north",
+	KeyE:                 "move-east",
+	KeyNW:                "move-north-west",
+	KeyNE:                "move-north-east",
+	KeySW:                "move-south-west",
+	KeySE:                "move-south-east",
+	KeyRunW:              "travel-west",
+	KeyRunS:              "travel-south",
+	KeyRunN:              "travel-north",
+	KeyRunE:              "travel-east",
+	KeyRunNW:             "travel-north-west",
+	KeyRunNE:             "travel-north-east",
+	KeyRunSW:             "travel-south-west",
+	KeyRunSE:             "travel-south-east",
+	KeyRest:              "rest",
+	KeyWaitTurn:          "wait",
+	KeyDescend:           "descend",
+	KeyGoToStairs:        "go-to-stairs",
+	KeyExplore:           "explore",
+	KeyExamine:           "examine",
+	KeyEquip:             "equip",
+	KeyDrink:             "quaff",
+	KeyThrow:             "throw",
+	KeyEvoke:             "evoke",
+	KeyCharacterInfo:     "character-info",
+	KeyLogs:              "messages",
+	KeyDump:              "dump",
+	KeyHelp:              "help",
+	KeySave:              "save",
+	KeyQuit:              "quit",
+	KeyWizard:            "wizard",
+	KeyWizardInfo:        "wizard-info",
+	KeyPreviousMonster:   "previous-monster",
+	KeyNextMonster:       "next-monster",
+	KeyNextObject:        "next-object",
+	KeyDescription:       "describe",
+	KeyTarget:            "target",
+	KeyExclude:           "exclude",
+	KeyEscape:            "escape",
+	KeyConfigure:         "configure",
+	KeyMenu:              "menu",
+	KeyNextStairs:        "next-stairs",
+	KeyMenuCommandHelp:   "command-help",
+	KeyMenuTargetingHelp: "targeting-help",
+	KeyInventory:         "inventory",
+}
+
+func (k keyAction) ConfigName() string {
+	return keyActionNames[k]
+}
+
+func KeyActionByName(name string) (keyAction, bool) {
+	for k, s := range keyActionNames {
+		if s == name {
+			return k, true
+		}
+	}
+	return KeyNothing, false
+}
+
+func RuneKeyName(r rune) string {
+	switch r {
+	case ' ':
+		return "space"
+	case '\x1b':
+		return "esc"
+	default:
+		return string(r)
+	}
+}
+
+func ParseRuneKey(s string) (rune, bool) {
+	switch s {
+	case "space":
+		return ' ', true
+	case "esc":
+		return '\x1b', true
+	}
+	if utf8.RuneCountInString(s) != 1 {
+		return 0, false
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return r, true
+}
+
+// configSyntaxError lists the invalid lines of a configuration file.
+type configSyntaxError []string
+
+func (e configSyntaxError) Error() string {
+	return strings.Join(e, "; ")
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+func parseBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "true", "yes", "on", "1":
+		return true, true
+	case "false", "no", "off", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// WriteKeyMap writes a key map section with one line per bound action.
+func WriteKeyMap(buf *bytes.Buffer, section string, keys map[rune]keyAction) {
+	fmt.Fprintf(buf, "[%s]\n", section)
+	runes := map[keyAction][]string{}
+	for r, k := range keys {
+		runes[k] = append(runes[k], RuneKeyName(r))
+	}
+	for k := KeyW; k <= KeyInventory; k++ {
+		if len(runes[k]) == 0 || k.ConfigName() == "" {
+			continue
+		}
+		sort.Strings(runes[k])
+		fmt.Fprintf(buf, "%s = %s\n", k.ConfigName(), strings.Join(runes[k], " "))
+	}
+}
+
+func (c *config) Text() []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString(configFileHeader)
+	fmt.Fprintf(buf, "\ndark-los = %s\n", boolString(c.DarkLOS))
+	fmt.Fprintf(buf, "small = %s\n", boolString(c.Small))
+	fmt.Fprintf(buf, "tiles = %s\n", boolString(c.Tiles))
+	fmt.Fprintf(buf, "palette = %s\n", c.Palette)
+	fmt.Fprintf(buf, "animations = %s\n", boolString(!c.NoAnimations))
+	buf.WriteString("\n")
+	buf.Write(KeyMapText(c.RuneNormalModeKeys, c.RuneTargetModeKeys))
+	return buf.Bytes()
+}
+
+// ParseConfig parses a text configuration file. Invalid lines are skipped
+// and reported in a configSyntaxError, along with the configuration built
+// from valid lines. Missing key maps get default bindings.
+func ParseConfig(data []byte, file string) (*config, error) {
+	c := &config{DarkLOS: true}
+	var errs configSyntaxError
+	var keys map[rune]keyAction
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	lnum := 0
+	for sc.Scan() {
+		lnum++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		errorf := func(format string, a ...interface{}) {
+			errs = append(errs, fmt.Sprintf("%s:%d: %s", file, lnum, fmt.Sprintf(format, a...)))
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			switch line {
+			case "[normal-keys]":
+				c.RuneNormalModeKeys = map[rune]keyAction{}
+				keys = c.RuneNormalModeKeys
+			case "[target-keys]":
+				c.RuneTargetModeKeys = map[rune]keyAction{}
+				keys = c.RuneTargetModeKeys
+			default:
+				keys = nil
+				errorf("unknown section %s", line)
+			}
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			errorf("expected “name = value”")
+			continue
+		}
+		name := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		if keys != nil {
+			k, ok := KeyActionByName(name)
+			if !ok {
+				errorf("unknown action “%s”", name)
+				continue
+			}
+			for _, s := range strings.Fields(value) {
+				r, ok := ParseRuneKey(s)
+				if !ok {
+					errorf("invalid key “%s”", s)
+					continue
+				}
+				if prev, ok := keys[r]; ok && prev != k {
+					errorf("key “%s” bound to both %s and %s", s, prev.ConfigName(), k.ConfigName())
+				}
+				keys[r] = k
+			}
+			continue
+		}
+		if name == "palette" {
+			switch value {
+			case "", Palette256, PaletteSolarized, Palette8:
+				c.Palette = value
+			default:
+				errorf("unknown palette “%s”", value)
+			}
+			continue
+		}
+		b, ok := parseBool(value)
+		if !ok {
+			errorf("invalid boolean “%s” for %s", value, name)
+			continue
+		}
+		switch name {
+		case "dark-los":
+			c.DarkLOS = b
+		case "small":
+			c.Small = b
+		case "tiles":
+			c.Tiles = b
+		case "animations":
+			c.NoAnimations = !b
+		default:
+			errorf("unknown setting “%s”", name)
+		}
+	}
+	normal, target := DefaultKeyBindings()
+	if c.RuneNormalModeKeys == nil {
+		c.RuneNormalModeKeys = normal
+	}
+	if c.RuneTargetModeKeys == nil {
+		c.RuneTargetModeKeys = target
+	}
+	c.Version = Version
+	if len(errs) > 0 {
+		return c, errs
+	}
+	return c, nil
+}
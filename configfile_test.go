@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigText(t *testing.T) {
+	c := &config{DarkLOS: false, Tiles: true, Palette: PaletteSolarized, NoAnimations: true, Version: Version}
+	c.RuneNormalModeKeys, c.RuneTargetModeKeys = DefaultKeyBindings()
+	c.RuneNormalModeKeys['é'] = KeyExplore
+	delete(c.RuneTargetModeKeys, 'x')
+	lc, err := ParseConfig(c.Text(), "config.ini")
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	if !reflect.DeepEqual(c, lc) {
+		t.Errorf("config changed after text round trip:\n%+v\n%+v", c, lc)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	text := `# comment
+small = yes
+tiles = maybe
+palette = rainbow
+
+[normal-keys]
+explore = o
+fly = F
+rest = ab
+`
+	c, err := ParseConfig([]byte(text), "config.ini")
+	serr, ok := err.(configSyntaxError)
+	if !ok {
+		t.Fatalf("bad error: %v", err)
+	}
+	want := configSyntaxError{
+		"config.ini:3: invalid boolean “maybe” for tiles",
+		"config.ini:4: unknown palette “rainbow”",
+		"config.ini:8: unknown action “fly”",
+		"config.ini:9: invalid key “ab”",
+	}
+	if !reflect.DeepEqual(serr, want) {
+		t.Errorf("bad errors: %q", serr)
+	}
+	if !c.Small || !c.DarkLOS || c.RuneNormalModeKeys['o'] != KeyExplore || len(c.RuneNormalModeKeys) != 1 {
+		t.Errorf("valid lines not applied: %+v", c)
+	}
+	if _, ok := c.RuneTargetModeKeys['x']; !ok {
+		t.Errorf("missing target keys section did not get defaults")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The daily challenge is a game whose seed depends only on the current UTC
+// date, so that everyone plays the same dungeon on a given day. Only one
+// attempt per day is allowed: attempts and results are recorded in the
+// "daily" data file, one line per event.
+
+func DailyDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+func DailySeed(t time.Time) int64 {
+	y, m, d := t.UTC().Date()
+	return int64(y*10000 + int(m)*100 + d)
+}
+
+func (g *game) DailyResults() []string {
+	data, err := g.ReadDataFile("daily")
+	if err != nil {
+		// no daily challenge played yet
+		return nil
+	}
+	lines := []string{}
+	for _, l := range strings.Split(string(data), "\n") {
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+func (g *game) AppendDailyResult(s string) error {
+	return g.AppendDataFile("daily", []byte(s+"\n"))
+}
+
+// StartDaily prepares a new game for the daily challenge of the day of t.
+func (g *game) StartDaily(t time.Time) error {
+	date := DailyDate(t)
+	for _, l := range g.DailyResults() {
+		if strings.HasPrefix(l, date+" ") {
+			return fmt.Errorf("You already attempted the daily challenge of %s.", date)
+		}
+	}
+	err := g.AppendDailyResult(date + " started")
+	if err != nil {
+		return fmt.Errorf("Could not record daily challenge attempt: %v", err)
+	}
+	g.Daily = date
+	g.Seed = DailySeed(t)
+	return nil
+}
+
+// EndDaily records the outcome of a daily challenge game.
+func (g *game) EndDaily(outcome string) {
+	if g.Daily == "" {
+		return
+	}
+	depth := g.Depth
+	if depth == -1 {
+		depth = g.ExploredLevels
+	}
+	err := g.AppendDailyResult(fmt.Sprintf("%s %s depth %d simellas %d turns %d", g.Daily, outcome, depth, g.Player.Simellas, g.Turn/10))
+	if err != nil {
+		g.PrintfStyled("Error recording daily challenge result: %v", logError, err)
+	}
+}
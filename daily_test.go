@@ -0,0 +1,44 @@
+// +build !js
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDaily(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boohu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xdg := os.Getenv("XDG_DATA_HOME")
+	defer os.Setenv("XDG_DATA_HOME", xdg)
+	os.Setenv("XDG_DATA_HOME", dir)
+
+	day := time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC)
+	if DailySeed(day) != DailySeed(day.Add(-time.Hour)) || DailySeed(day) == DailySeed(day.Add(time.Hour)) {
+		t.Errorf("bad daily seed for UTC date")
+	}
+	g := &game{}
+	if err := g.StartDaily(day); err != nil {
+		t.Fatalf("StartDaily: %v", err)
+	}
+	g.InitLevel()
+	if g.Seed != DailySeed(day) {
+		t.Errorf("bad daily game seed: %d", g.Seed)
+	}
+	g.EndDaily("died")
+	ng := &game{}
+	if err := ng.StartDaily(day); err == nil {
+		t.Errorf("second daily attempt allowed")
+	}
+	res := g.DailyResults()
+	if len(res) != 2 || !strings.HasPrefix(res[1], "2026-10-17 died depth 1 ") {
+		t.Errorf("bad daily results: %q", res)
+	}
+}
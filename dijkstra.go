@@ -10,7 +10,6 @@ type Dijkstrer interface {
 }
 
 func Dijkstra(dij Dijkstrer, sources []position, maxCost int) nodeMap {
-	nodeCache = nodeCache[:0]
 	nm := nodeMap{}
 	nq := &priorityQueue{}
 	heap.Init(nq)
@@ -53,7 +52,7 @@ const unreachable = 9999
 
 func (g *game) AutoExploreDijkstra(dij Dijkstrer, sources []int) {
 	d := g.Dungeon
-	dmap := DijkstraMapCache[:]
+	dmap := g.dijkstraMap[:]
 	var visited [DungeonNCells]bool
 	var queue [DungeonNCells]int
 	var qstart, qend int
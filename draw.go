@@ -16,6 +16,10 @@ var (
 	DisableAnimations bool = false
 )
 
+// UISleep waits between the frames of animations and menu highlights. The
+// telnet server replaces it so that other games can run meanwhile.
+var UISleep = time.Sleep
+
 type uicolor int
 
 const (
@@ -74,7 +78,7 @@ var (
 	ColorGreen   uicolor = Color256Green
 )
 
-func (ui *gameui) Map256ColorTo16(c uicolor) uicolor {
+func Map256ColorTo16(c uicolor) uicolor {
 	switch c {
 	case Color256Base03:
 		return Color16Base03
@@ -113,7 +117,7 @@ func (ui *gameui) Map256ColorTo16(c uicolor) uicolor {
 	}
 }
 
-func (ui *gameui) Map16ColorTo256(c uicolor) uicolor {
+func Map16ColorTo256(c uicolor) uicolor {
 	switch c {
 	case Color16Base03:
 		return Color256Base03
@@ -152,6 +156,46 @@ func (ui *gameui) Map16ColorTo256(c uicolor) uicolor {
 	}
 }
 
+// String returns the HTML color of a 16-color palette color.
+func (c uicolor) String() string {
+	color := "#002b36"
+	switch c {
+	case 0:
+		color = "#073642"
+	case 1:
+		color = "#dc322f"
+	case 2:
+		color = "#859900"
+	case 3:
+		color = "#b58900"
+	case 4:
+		color = "#268bd2"
+	case 5:
+		color = "#d33682"
+	case 6:
+		color = "#2aa198"
+	case 7:
+		color = "#eee8d5"
+	case 8:
+		color = "#002b36"
+	case 9:
+		color = "#cb4b16"
+	case 10:
+		color = "#586e75"
+	case 11:
+		color = "#657b83"
+	case 12:
+		color = "#839496"
+	case 13:
+		color = "#6c71c4"
+	case 14:
+		color = "#93a1a1"
+	case 15:
+		color = "#fdf6e3"
+	}
+	return color
+}
+
 var (
 	ColorBg,
 	ColorBgBorder,
@@ -260,6 +304,55 @@ func ApplyLightLOS() {
 	}
 }
 
+const (
+	Palette256       = "xterm256"
+	PaletteSolarized = "solarized"
+	Palette8         = "8colors"
+)
+
+var (
+	// ForcedPalette is the palette given on the command line, if any. It
+	// takes precedence over the palette of the configuration.
+	ForcedPalette  string
+	DefaultPalette = Palette256
+	// ForcedNoAnimations is set by the command line.
+	ForcedNoAnimations bool
+)
+
+func ApplyPalette(name string) {
+	switch name {
+	case PaletteSolarized:
+		SolarizedPalette()
+		Only8Colors = false
+	case Palette8:
+		SolarizedPalette()
+		Simple8ColorPalette()
+	default:
+		Xterm256Palette()
+	}
+	LinkColors()
+}
+
+func Xterm256Palette() {
+	ColorBase03 = Color256Base03
+	ColorBase02 = Color256Base02
+	ColorBase01 = Color256Base01
+	ColorBase00 = Color256Base00
+	ColorBase0 = Color256Base0
+	ColorBase1 = Color256Base1
+	ColorBase2 = Color256Base2
+	ColorBase3 = Color256Base3
+	ColorYellow = Color256Yellow
+	ColorOrange = Color256Orange
+	ColorRed = Color256Red
+	ColorMagenta = Color256Magenta
+	ColorViolet = Color256Violet
+	ColorBlue = Color256Blue
+	ColorCyan = Color256Cyan
+	ColorGreen = Color256Green
+	Only8Colors = false
+}
+
 func SolarizedPalette() {
 	ColorBase03 = Color16Base03
 	ColorBase02 = Color16Base02
@@ -338,6 +431,14 @@ func Simple8ColorPalette() {
 type drawFrame struct {
 	Draws []cellDraw
 	Time  time.Time
+	Depth int  // depth of the game, 0 in replays of older versions
+	Dead  bool // the player died
+	Small bool // small layout
+}
+
+// Broadcast, if not nil, receives every drawn frame.
+var Broadcast interface {
+	Send(df drawFrame)
 }
 
 type cellDraw struct {
@@ -367,7 +468,7 @@ func (ui *gameui) DrawLogFrame() {
 	if len(ui.g.drawBackBuffer) != len(ui.g.DrawBuffer) {
 		ui.g.drawBackBuffer = make([]UICell, len(ui.g.DrawBuffer))
 	}
-	ui.g.DrawLog = append(ui.g.DrawLog, drawFrame{Time: time.Now()})
+	ui.g.DrawLog = append(ui.g.DrawLog, drawFrame{Time: time.Now(), Depth: ui.g.Depth, Dead: ui.g.outcome == "died", Small: ui.Small()})
 	for i := 0; i < len(ui.g.DrawBuffer); i++ {
 		if ui.g.DrawBuffer[i] == ui.g.drawBackBuffer[i] {
 			continue
@@ -379,6 +480,12 @@ func (ui *gameui) DrawLogFrame() {
 		ui.g.DrawLog[last].Draws = append(ui.g.DrawLog[last].Draws, cdraw)
 		ui.g.drawBackBuffer[i] = c
 	}
+	if Broadcast != nil {
+		Broadcast.Send(ui.g.DrawLog[len(ui.g.DrawLog)-1])
+	}
+	if ui.g.replay != nil {
+		ui.RecordReplayFrame()
+	}
 }
 
 func (ui *gameui) DrawWelcomeCommon() int {
@@ -451,19 +558,19 @@ func (ui *gameui) DrawWelcomeCommon() int {
 	ui.DrawDark("────│/\\/\\/\\/\\/\\/\\/\\│────", col, line, ColorText, false)
 	line++
 	line++
-	if runtime.GOARCH == "wasm" {
-		ui.DrawDark("- (P)lay", col-3, line, ColorFg, false)
-		ui.DrawDark("- (W)atch replay", col-3, line+1, ColorFg, false)
-	} else {
-		ui.DrawDark("───Press any key to continue───", col-3, line, ColorFg, false)
+	for i, a := range StartActions() {
+		ui.DrawDark("- "+a.String(), col-3, line+i, ColorFg, false)
+	}
+	if runtime.GOARCH != "wasm" {
+		ui.DrawDark("───Press any other key to play───", col-3, line+len(StartActions())+1, ColorFg, false)
 	}
 	ui.Flush()
 	return line
 }
 
-func (ui *gameui) DrawWelcome() {
-	ui.DrawWelcomeCommon()
-	ui.PressAnyKey()
+func (ui *gameui) DrawWelcome() startAction {
+	l := ui.DrawWelcomeCommon()
+	return ui.StartMenu(l)
 }
 
 func (ui *gameui) RestartDrawBuffers() {
@@ -792,6 +899,9 @@ func (ui *gameui) MonsterInfo(m *monster) string {
 
 var CenteredCamera bool
 
+// ForcedSmall forces the small layout, for terminals smaller than 100x26.
+var ForcedSmall bool
+
 func (ui *gameui) InView(pos position, targeting bool) bool {
 	g := ui.g
 	if targeting {
@@ -808,6 +918,26 @@ func (ui *gameui) CameraOffset(pos position, targeting bool) (int, int) {
 	return pos.X + 39 - g.Player.Pos.X, pos.Y + 10 - g.Player.Pos.Y
 }
 
+// MapPosition returns the map position drawn at screen position (x, y), or
+// InvalidPos if there is none.
+func (ui *gameui) MapPosition(x, y int, targeting bool) position {
+	if x < 0 || y < 0 || x >= DungeonWidth || y >= DungeonHeight {
+		return InvalidPos
+	}
+	pos := position{x, y}
+	if CenteredCamera {
+		ref := ui.g.Player.Pos
+		if targeting {
+			ref = ui.cursor
+		}
+		pos = position{x - 39 + ref.X, y - 10 + ref.Y}
+		if !pos.valid() {
+			return InvalidPos
+		}
+	}
+	return pos
+}
+
 func (ui *gameui) InViewBorder(pos position, targeting bool) bool {
 	g := ui.g
 	if targeting {
@@ -1365,6 +1495,12 @@ loop:
 			}
 			CustomKeys = true
 			ka := configurableKeyActions[s]
+			conflicts := KeyBindingConflicts(GameConfig.RuneNormalModeKeys, GameConfig.RuneTargetModeKeys)
+			if prev, ok := GameConfig.RuneNormalModeKeys[r]; ok && prev != ka {
+				g.Printf("“%c” was bound to “%s”.", r, prev.Description())
+			} else if prev, ok := GameConfig.RuneTargetModeKeys[r]; ok && prev != ka && ka.TargetingModeKey() {
+				g.Printf("“%c” was bound to “%s”.", r, prev.Description())
+			}
 			if ka.NormalModeKey() {
 				GameConfig.RuneNormalModeKeys[r] = ka
 			} else {
@@ -1375,6 +1511,7 @@ loop:
 			} else {
 				delete(GameConfig.RuneTargetModeKeys, r)
 			}
+			g.PrintNewConflicts(conflicts)
 			err := g.SaveConfig()
 			if err != nil {
 				g.Print(err.Error())
@@ -1392,6 +1529,19 @@ loop:
 	}
 }
 
+// PrintNewConflicts logs key binding conflicts that are not in old.
+func (g *game) PrintNewConflicts(old []string) {
+	known := map[string]bool{}
+	for _, s := range old {
+		known[s] = true
+	}
+	for _, s := range KeyBindingConflicts(GameConfig.RuneNormalModeKeys, GameConfig.RuneTargetModeKeys) {
+		if !known[s] {
+			g.PrintStyled(s, logError)
+		}
+	}
+}
+
 func (ui *gameui) DrawPreviousLogs() {
 	g := ui.g
 	bottom := 4
@@ -1612,7 +1762,7 @@ func (ui *gameui) SelectProjectile(ev event) error {
 		if err == nil {
 			ui.ConsumableItem(index, index+1, cs[index], ColorYellow)
 			ui.Flush()
-			time.Sleep(75 * time.Millisecond)
+			UISleep(75 * time.Millisecond)
 			if desc {
 				ui.DrawDescription(cs[index].Desc())
 				continue
@@ -1654,7 +1804,7 @@ func (ui *gameui) SelectPotion(ev event) error {
 		if err == nil {
 			ui.ConsumableItem(index, index+1, cs[index], ColorYellow)
 			ui.Flush()
-			time.Sleep(75 * time.Millisecond)
+			UISleep(75 * time.Millisecond)
 			if desc {
 				ui.DrawDescription(cs[index].Desc())
 				continue
@@ -1708,7 +1858,7 @@ func (ui *gameui) SelectRod(ev event) error {
 		if err == nil {
 			ui.RodItem(index, index+1, rs[index], ColorYellow)
 			ui.Flush()
-			time.Sleep(75 * time.Millisecond)
+			UISleep(75 * time.Millisecond)
 			if desc {
 				ui.DrawDescription(rs[index].Desc())
 				continue
@@ -1824,7 +1974,7 @@ func (ui *gameui) SelectAction(actions []keyAction, ev event) (keyAction, error)
 		}
 		ui.ActionItem(index, index+1, actions[index], ColorYellow)
 		ui.Flush()
-		time.Sleep(75 * time.Millisecond)
+		UISleep(75 * time.Millisecond)
 		ui.DrawDungeonView(NoFlushMode)
 		return actions[index], nil
 	}
@@ -1837,6 +1987,9 @@ const (
 	invertLOS
 	toggleLayout
 	toggleTiles
+	presetKeys
+	exportKeys
+	importKeys
 )
 
 func (s setting) String() (text string) {
@@ -1849,17 +2002,26 @@ func (s setting) String() (text string) {
 		text = "Toggle normal/compact layout"
 	case toggleTiles:
 		text = "Toggle Tiles/Ascii display"
+	case presetKeys:
+		text = "Choose key bindings preset"
+	case exportKeys:
+		text = "Export key bindings"
+	case importKeys:
+		text = "Import key bindings"
 	}
 	return text
 }
 
 var settingsActions = []setting{
 	setKeys,
+	presetKeys,
+	exportKeys,
+	importKeys,
 	invertLOS,
 	toggleLayout,
 }
 
-func (ui *gameui) ConfItem(i, lnum int, s setting, fg uicolor) {
+func (ui *gameui) ConfItem(i, lnum int, s fmt.Stringer, fg uicolor) {
 	bg := ui.ListItemBG(i)
 	ui.ClearLineWithColor(lnum, bg)
 	ui.DrawColoredTextOnBG(fmt.Sprintf("%c - %s", rune(i+97), s), 0, lnum, fg, bg)
@@ -1886,12 +2048,39 @@ func (ui *gameui) SelectConfigure(actions []setting) (setting, error) {
 		}
 		ui.ConfItem(index, index+1, actions[index], ColorYellow)
 		ui.Flush()
-		time.Sleep(75 * time.Millisecond)
+		UISleep(75 * time.Millisecond)
 		ui.DrawDungeonView(NoFlushMode)
 		return actions[index], nil
 	}
 }
 
+func (ui *gameui) SelectKeyPreset() (keyPreset, error) {
+	for {
+		ui.ClearLine(0)
+		ui.DrawColoredText("Choose", 0, 0, ColorCyan)
+		col := utf8.RuneCountInString("Choose")
+		ui.DrawText(" which key bindings preset?", col, 0)
+		for i, p := range keyPresets {
+			ui.ConfItem(i, i+1, p, ColorFg)
+		}
+		ui.DrawTextLine(" press (x) to cancel ", len(keyPresets)+1)
+		ui.Flush()
+		index, alt, err := ui.Select(len(keyPresets))
+		if alt {
+			continue
+		}
+		if err != nil {
+			ui.DrawDungeonView(NoFlushMode)
+			return PresetViKeys, err
+		}
+		ui.ConfItem(index, index+1, keyPresets[index], ColorYellow)
+		ui.Flush()
+		UISleep(75 * time.Millisecond)
+		ui.DrawDungeonView(NoFlushMode)
+		return keyPresets[index], nil
+	}
+}
+
 func (ui *gameui) HandleSettingAction() error {
 	g := ui.g
 	s, err := ui.SelectConfigure(settingsActions)
@@ -1901,6 +2090,38 @@ func (ui *gameui) HandleSettingAction() error {
 	switch s {
 	case setKeys:
 		ui.ChangeKeys()
+	case presetKeys:
+		p, err := ui.SelectKeyPreset()
+		if err != nil {
+			return err
+		}
+		ApplyKeyPreset(p)
+		g.Printf("Key bindings preset: %s.", p)
+		err = g.SaveConfig()
+		if err != nil {
+			g.Print(err.Error())
+		}
+	case exportKeys:
+		err := g.ExportKeys()
+		if err != nil {
+			g.Printf("Error exporting key bindings: %v", err)
+		} else {
+			g.Print("Key bindings exported to the keys.ini file.")
+		}
+	case importKeys:
+		msgs, err := g.ImportKeys()
+		if err != nil {
+			g.Printf("Error importing key bindings: %v", err)
+			break
+		}
+		g.Print("Key bindings imported from the keys.ini file.")
+		for _, s := range msgs {
+			g.PrintStyled(s, logError)
+		}
+		err = g.SaveConfig()
+		if err != nil {
+			g.Print(err.Error())
+		}
 	case invertLOS:
 		GameConfig.DarkLOS = !GameConfig.DarkLOS
 		err := g.SaveConfig()
@@ -1955,7 +2176,7 @@ func (ui *gameui) SelectWizardMagic(actions []wizardAction) (wizardAction, error
 		}
 		ui.WizardItem(index, index+1, actions[index], ColorYellow)
 		ui.Flush()
-		time.Sleep(75 * time.Millisecond)
+		UISleep(75 * time.Millisecond)
 		ui.DrawDungeonView(NoFlushMode)
 		return actions[index], nil
 	}
@@ -108,6 +108,15 @@ func (g *game) SortedProjectiles() consumableSlice {
 	return cs
 }
 
+// DeathSummary describes the death of the player, with the last damage
+// source if known.
+func (g *game) DeathSummary() string {
+	if g.Killer == "" {
+		return fmt.Sprintf("You died while exploring depth %d of Hareka's Underground", g.Depth)
+	}
+	return fmt.Sprintf("Killed by %s at depth %d of Hareka's Underground", g.Killer, g.Depth)
+}
+
 func (g *game) Dump() string {
 	buf := &bytes.Buffer{}
 	fmt.Fprintf(buf, " -- Boohu version %s character file --\n\n", Version)
@@ -117,7 +126,9 @@ func (g *game) Dump() string {
 	if g.Player.HP > 0 && g.Depth == -1 {
 		fmt.Fprintf(buf, "You escaped from Hareka's Underground alive!\n")
 	} else if g.Player.HP <= 0 {
-		fmt.Fprintf(buf, "You died while exploring depth %d of Hareka's Underground.\n", g.Depth)
+		fmt.Fprintf(buf, "%s.\n", g.DeathSummary())
+	} else if g.outcome == "quit" {
+		fmt.Fprintf(buf, "You quit while exploring depth %d of Hareka's Underground.\n", g.Depth)
 	} else {
 		fmt.Fprintf(buf, "You are exploring depth %d of Hareka's Underground.\n", g.Depth)
 	}
@@ -184,6 +195,13 @@ func (g *game) Dump() string {
 		s = ""
 	}
 	fmt.Fprintf(buf, "You explored %d level%s out of %d.\n", maxDepth, s, MaxDepth)
+	if g.Seed != 0 {
+		// unknown for games upgraded from older versions
+		fmt.Fprintf(buf, "The game seed was %d.\n", g.Seed)
+	}
+	if g.Daily != "" {
+		fmt.Fprintf(buf, "This was the daily challenge of %s.\n", g.Daily)
+	}
 	fmt.Fprintf(buf, "\n")
 	fmt.Fprintf(buf, "Last messages:\n")
 	for i := len(g.Log) - 10; i < len(g.Log); i++ {
@@ -382,7 +400,9 @@ func (g *game) SimplifedDump(err error) string {
 	if g.Player.HP > 0 && g.Depth == -1 {
 		fmt.Fprintf(buf, "You escaped from Hareka's Underground alive!\n")
 	} else if g.Player.HP <= 0 {
-		fmt.Fprintf(buf, "You died while exploring depth %d of Hareka's Underground.\n", g.Depth)
+		fmt.Fprintf(buf, "%s.\n", g.DeathSummary())
+	} else if g.outcome == "quit" {
+		fmt.Fprintf(buf, "You quit while exploring depth %d of Hareka's Underground.\n", g.Depth)
 	} else {
 		fmt.Fprintf(buf, "You are exploring depth %d of Hareka's Underground.\n", g.Depth)
 	}
@@ -395,6 +415,12 @@ func (g *game) SimplifedDump(err error) string {
 		s = ""
 	}
 	fmt.Fprintf(buf, "You explored %d level%s out of %d.\n", maxDepth, s, MaxDepth+1)
+	if g.Seed != 0 {
+		fmt.Fprintf(buf, "The game seed was %d.\n", g.Seed)
+	}
+	if g.Daily != "" {
+		fmt.Fprintf(buf, "This was the daily challenge of %s.\n", g.Daily)
+	}
 	fmt.Fprintf(buf, "\n")
 	if err != nil {
 		fmt.Fprintf(buf, "Error writing dump: %v.\n", err)
@@ -406,6 +432,9 @@ func (g *game) SimplifedDump(err error) string {
 			} else {
 				fmt.Fprintf(buf, "Full game statistics dump written to %s.\n", filepath.Join(dataDir, "dump"))
 			}
+			if g.morgueName != "" {
+				fmt.Fprintf(buf, "Game archived as %s.\n", filepath.Join(dataDir, "morgue", g.morgueName+".txt"))
+			}
 		}
 	}
 	fmt.Fprintf(buf, "\n\n")
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// jsonDump is a machine-readable version of the character dump. Per-depth
+// statistics are lists whose first element is for depth 1.
+type jsonDump struct {
+	Version     string         `json:"version"`
+	Seed        int64          `json:"seed"`
+	Daily       string         `json:"daily,omitempty"`
+	Wizard      bool           `json:"wizard"`
+	Outcome     string         `json:"outcome"`
+	Killer      string         `json:"killer,omitempty"`
+	Depth       int            `json:"depth"`
+	MaxDepth    int            `json:"maxdepth"`
+	Turns       int            `json:"turns"`
+	HP          int            `json:"hp"`
+	HPMax       int            `json:"hpmax"`
+	MP          int            `json:"mp"`
+	MPMax       int            `json:"mpmax"`
+	Simellas    int            `json:"simellas"`
+	Score       int            `json:"score"`
+	Aptitudes   []string       `json:"aptitudes"`
+	Statuses    map[string]int `json:"statuses"`
+	Armour      string         `json:"armour"`
+	Weapon      string         `json:"weapon"`
+	Shield      string         `json:"shield,omitempty"`
+	Rods        []jsonRod      `json:"rods"`
+	Consumables map[string]int `json:"consumables"`
+	Stats       jsonStats      `json:"stats"`
+	Story       []string       `json:"story"`
+	Map         []string       `json:"map"`
+}
+
+type jsonRod struct {
+	Name      string `json:"name"`
+	Charge    int    `json:"charge"`
+	MaxCharge int    `json:"maxcharge"`
+}
+
+type jsonStats struct {
+	Killed        int            `json:"killed"`
+	KilledMons    map[string]int `json:"killedmons"`
+	Moves         int            `json:"moves"`
+	Hits          int            `json:"hits"`
+	Misses        int            `json:"misses"`
+	ReceivedHits  int            `json:"receivedhits"`
+	Dodges        int            `json:"dodges"`
+	Blocks        int            `json:"blocks"`
+	Drinks        int            `json:"drinks"`
+	Evocations    int            `json:"evocations"`
+	UsedStones    int            `json:"usedstones"`
+	Throws        int            `json:"throws"`
+	TimesLucky    int            `json:"timeslucky"`
+	Damage        int            `json:"damage"`
+	DExplPerc     []int          `json:"dexplperc"`
+	DSleepingPerc []int          `json:"dsleepingperc"`
+	DKilledPerc   []int          `json:"dkilledperc"`
+	DLayout       []string       `json:"dlayout"`
+	Burns         int            `json:"burns"`
+	Digs          int            `json:"digs"`
+	Rest          int            `json:"rest"`
+	RestInterrupt int            `json:"restinterrupt"`
+	Turns         int            `json:"turns"`
+	TWounded      int            `json:"twounded"`
+	TMWounded     int            `json:"tmwounded"`
+	TMonsLOS      int            `json:"tmonslos"`
+	UsedRod       map[string]int `json:"usedrod"`
+}
+
+func (g *game) Outcome() string {
+	switch {
+	case g.outcome == "quit", g.outcome == "error":
+		return g.outcome
+	case g.Player.HP > 0 && g.Depth == -1:
+		return "escaped"
+	case g.Player.HP <= 0:
+		return "died"
+	default:
+		return "playing"
+	}
+}
+
+func (g *game) JSONDump() ([]byte, error) {
+	maxDepth := Max(g.Depth, g.ExploredLevels)
+	d := &jsonDump{
+		Version:     Version,
+		Seed:        g.Seed,
+		Daily:       g.Daily,
+		Wizard:      g.Wizard,
+		Outcome:     g.Outcome(),
+		Depth:       g.Depth,
+		MaxDepth:    maxDepth,
+		Turns:       g.Turn / 10,
+		HP:          g.Player.HP,
+		HPMax:       g.Player.HPMax(),
+		MP:          g.Player.MP,
+		MPMax:       g.Player.MPMax(),
+		Simellas:    g.Player.Simellas,
+		Score:       g.Score(),
+		Aptitudes:   []string{},
+		Statuses:    map[string]int{},
+		Armour:      g.Player.Armour.String(),
+		Weapon:      g.Player.Weapon.String(),
+		Rods:        []jsonRod{},
+		Consumables: map[string]int{},
+		Story:       append([]string{}, g.Stats.Story...),
+		Map:         []string{},
+	}
+	if d.Outcome == "died" {
+		d.Killer = g.Killer
+	}
+	if g.Player.Shield != NoShield {
+		d.Shield = g.Player.Shield.String()
+	}
+	for apt, b := range g.Player.Aptitudes {
+		if b {
+			d.Aptitudes = append(d.Aptitudes, apt.String())
+		}
+	}
+	sort.Strings(d.Aptitudes)
+	for st, n := range g.Player.Statuses {
+		if n > 0 {
+			d.Statuses[st.String()] = n
+		}
+	}
+	for _, r := range g.SortedRods() {
+		mc := r.MaxCharge()
+		if g.Player.Armour == CelmistRobe {
+			mc += 2
+		}
+		d.Rods = append(d.Rods, jsonRod{Name: r.String(), Charge: g.Player.Rods[r].Charge, MaxCharge: mc})
+	}
+	for c, n := range g.Player.Consumables {
+		if n > 0 {
+			d.Consumables[c.String()] = n
+		}
+	}
+	st := g.Stats
+	d.Stats = jsonStats{
+		Killed:        st.Killed,
+		KilledMons:    map[string]int{},
+		Moves:         st.Moves,
+		Hits:          st.Hits,
+		Misses:        st.Misses,
+		ReceivedHits:  st.ReceivedHits,
+		Dodges:        st.Dodges,
+		Blocks:        st.Blocks,
+		Drinks:        st.Drinks,
+		Evocations:    st.Evocations,
+		UsedStones:    st.UsedStones,
+		Throws:        st.Throws,
+		TimesLucky:    st.TimesLucky,
+		Damage:        st.Damage,
+		DExplPerc:     st.DExplPerc[1 : maxDepth+1],
+		DSleepingPerc: st.DSleepingPerc[1 : maxDepth+1],
+		DKilledPerc:   st.DKilledPerc[1 : maxDepth+1],
+		DLayout:       st.DLayout[1 : maxDepth+1],
+		Burns:         st.Burns,
+		Digs:          st.Digs,
+		Rest:          st.Rest,
+		RestInterrupt: st.RestInterrupt,
+		Turns:         st.Turns,
+		TWounded:      st.TWounded,
+		TMWounded:     st.TMWounded,
+		TMonsLOS:      st.TMonsLOS,
+		UsedRod:       map[string]int{},
+	}
+	for mk, n := range st.KilledMons {
+		if n > 0 {
+			d.Stats.KilledMons[mk.String()] = n
+		}
+	}
+	for r, n := range st.UsedRod {
+		if n > 0 {
+			d.Stats.UsedRod[rod(r).String()] = n
+		}
+	}
+	for _, l := range strings.Split(strings.TrimSuffix(g.DumpDungeon(), "\n"), "\n") {
+		d.Map = append(d.Map, strings.TrimSuffix(strings.TrimPrefix(l, "│"), "│"))
+	}
+	return json.MarshalIndent(d, "", "  ")
+}
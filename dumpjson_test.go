@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestJSONDump(t *testing.T) {
+	g := runBot(3)
+	data, err := g.JSONDump()
+	if err != nil {
+		t.Fatalf("JSONDump: %v", err)
+	}
+	d := &jsonDump{}
+	if err := json.Unmarshal(data, d); err != nil {
+		t.Fatalf("invalid JSON dump: %v", err)
+	}
+	if d.Seed != g.Seed || d.Simellas != g.Player.Simellas || d.Turns != g.Turn/10 {
+		t.Errorf("bad JSON dump: %+v", d)
+	}
+	if len(d.Stats.DExplPerc) != d.MaxDepth || len(d.Stats.DLayout) != d.MaxDepth || len(d.Story) != len(g.Stats.Story) {
+		t.Errorf("bad JSON dump statistics: %+v", d.Stats)
+	}
+	if len(d.Map) != DungeonHeight {
+		t.Fatalf("bad map height: %d", len(d.Map))
+	}
+	for _, l := range d.Map {
+		if utf8.RuneCountInString(l) != DungeonWidth {
+			t.Errorf("bad map line: %q", l)
+		}
+	}
+}
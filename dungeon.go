@@ -9,6 +9,11 @@ import (
 type dungeon struct {
 	Gen   dungen
 	Cells []cell
+	rand  *rng // random source of the game, only set during generation
+}
+
+func (d *dungeon) RandInt(n int) int {
+	return d.rand.Intn(n)
 }
 
 type cell struct {
@@ -115,15 +120,15 @@ func roomDistance(r1, r2 room) int {
 	return Abs(r1.pos.X-r2.pos.X) + Abs(r1.pos.Y-r2.pos.Y)
 }
 
-func nearRoom(rooms []room, r room) room {
+func (d *dungeon) nearRoom(rooms []room, r room) room {
 	closest := rooms[0]
-	d := roomDistance(r, closest)
+	dist := roomDistance(r, closest)
 	for _, nextRoom := range rooms {
 		nd := roomDistance(r, nextRoom)
-		if nd < d {
-			n := RandInt(10)
+		if nd < dist {
+			n := d.RandInt(10)
 			if n > 3 {
-				d = nd
+				dist = nd
 				closest = nextRoom
 			}
 		}
@@ -131,15 +136,15 @@ func nearRoom(rooms []room, r room) room {
 	return closest
 }
 
-func nearestRoom(rooms []room, r room) room {
+func (d *dungeon) nearestRoom(rooms []room, r room) room {
 	closest := rooms[0]
-	d := roomDistance(r, closest)
+	dist := roomDistance(r, closest)
 	for _, nextRoom := range rooms {
 		nd := roomDistance(r, nextRoom)
-		if nd < d {
-			n := RandInt(10)
+		if nd < dist {
+			n := d.RandInt(10)
 			if n > 0 {
-				d = nd
+				dist = nd
 				closest = nextRoom
 			}
 		}
@@ -282,19 +287,19 @@ func (d *dungeon) Area(area []position, pos position, radius int) []position {
 
 func (d *dungeon) ConnectRoomsShortestPath(r1, r2 room) {
 	var r1pos, r2pos position
-	r1pos.X = r1.pos.X + RandInt(r1.w)
+	r1pos.X = r1.pos.X + d.RandInt(r1.w)
 	if r1pos.X < r2.pos.X {
 		r1pos.X = r1.pos.X + r1.w - 1
 	}
-	r1pos.Y = r1.pos.Y + RandInt(r1.h)
+	r1pos.Y = r1.pos.Y + d.RandInt(r1.h)
 	if r1pos.Y < r2.pos.Y {
 		r1pos.Y = r1.pos.Y + r1.h - 1
 	}
-	r2pos.X = r2.pos.X + RandInt(r2.w)
+	r2pos.X = r2.pos.X + d.RandInt(r2.w)
 	if r2pos.X < r1.pos.X {
 		r2pos.X = r2.pos.X + r2.w - 1
 	}
-	r2pos.Y = r2.pos.Y + RandInt(r2.h)
+	r2pos.Y = r2.pos.Y + d.RandInt(r2.h)
 	if r2pos.Y < r1.pos.Y {
 		r2pos.Y = r2.pos.Y + r2.h - 1
 	}
@@ -350,7 +355,7 @@ func (d *dungeon) PutCols(r room) {
 }
 
 func (d *dungeon) PutDiagCols(r room) {
-	n := RandInt(2)
+	n := d.RandInt(2)
 	for i := r.pos.X + 1; i < r.pos.X+r.w-1; i++ {
 		m := n
 		for j := r.pos.Y + 1; j < r.pos.Y+r.h-1; j++ {
@@ -444,8 +449,8 @@ func (d *dungeon) BuildRoom(pos position, w, h int, outside bool) map[position]b
 		d.SetCell(position{pos.X, i}, WallCell)
 		d.SetCell(position{pos.X + w - 1, i}, WallCell)
 	}
-	if RandInt(2) == 0 || !outside {
-		n := RandInt(2)
+	if d.RandInt(2) == 0 || !outside {
+		n := d.RandInt(2)
 		for x := pos.X + 1; x < pos.X+w-1; x++ {
 			m := n
 			for y := pos.Y + 1; y < pos.Y+h-1; y++ {
@@ -457,8 +462,8 @@ func (d *dungeon) BuildRoom(pos position, w, h int, outside bool) map[position]b
 			n++
 		}
 	} else {
-		n := RandInt(2)
-		m := RandInt(2)
+		n := d.RandInt(2)
+		m := d.RandInt(2)
 		//if n == 0 && m == 0 {
 		//// round room
 		//d.SetCell(pos, FreeCell)
@@ -488,8 +493,8 @@ func (d *dungeon) BuildRoom(pos position, w, h int, outside bool) map[position]b
 		position{pos.X + w - 1, pos.Y + h/2},
 	}
 	doors := make(map[position]bool)
-	for i := 0; i < 3+RandInt(2); i++ {
-		dpos := doorsc[RandInt(4)]
+	for i := 0; i < 3+d.RandInt(2); i++ {
+		dpos := doorsc[d.RandInt(4)]
 		doors[dpos] = true
 		d.SetCell(dpos, FreeCell)
 	}
@@ -524,7 +529,7 @@ func (d *dungeon) DigSomeRoom(w, h int) map[position]bool {
 }
 
 func (d *dungeon) DigIsolatedRoom(w, h int) map[position]bool {
-	i := RandInt(DungeonNCells)
+	i := d.RandInt(DungeonNCells)
 	for j := 0; j < DungeonNCells; j++ {
 		i = (i + 1) % DungeonNCells
 		pos := idxtopos(i)
@@ -555,7 +560,7 @@ func (d *dungeon) ResizeRoom(r room) room {
 }
 
 func (g *game) GenRuinsMap(h, w int) {
-	d := &dungeon{}
+	d := &dungeon{rand: g.Rand}
 	d.Cells = make([]cell, h*w)
 	rooms := []room{}
 	for i := 0; i < 43; i++ {
@@ -564,9 +569,9 @@ func (g *game) GenRuinsMap(h, w int) {
 		for count > 0 {
 			count--
 			ro = room{
-				pos: position{RandInt(w - 1), RandInt(h - 1)},
-				w:   3 + RandInt(5),
-				h:   2 + RandInt(3)}
+				pos: position{g.RandInt(w - 1), g.RandInt(h - 1)},
+				w:   3 + g.RandInt(5),
+				h:   2 + g.RandInt(3)}
 			ro = d.ResizeRoom(ro)
 			if !intersectsRoom(rooms, ro) {
 				break
@@ -574,21 +579,21 @@ func (g *game) GenRuinsMap(h, w int) {
 		}
 
 		d.DigRoom(ro)
-		if RandInt(60) == 0 {
-			if RandInt(2) == 0 {
+		if g.RandInt(60) == 0 {
+			if g.RandInt(2) == 0 {
 				d.PutCols(ro)
 			} else {
 				d.PutDiagCols(ro)
 			}
 		}
 		if len(rooms) > 0 {
-			r := RandInt(100)
+			r := g.RandInt(100)
 			if r > 75 {
-				d.connectRooms(nearRoom(rooms, ro), ro)
+				d.connectRooms(d.nearRoom(rooms, ro), ro)
 			} else if r > 25 {
-				d.ConnectRoomsShortestPath(nearRoom(rooms, ro), ro)
+				d.ConnectRoomsShortestPath(d.nearRoom(rooms, ro), ro)
 			} else {
-				d.connectRoomsDiagonally(nearRoom(rooms, ro), ro)
+				d.connectRoomsDiagonally(d.nearRoom(rooms, ro), ro)
 			}
 		}
 		rooms = append(rooms, ro)
@@ -596,7 +601,7 @@ func (g *game) GenRuinsMap(h, w int) {
 	doors := d.DigSomeRooms(5)
 	g.Dungeon = d
 	g.Fungus = make(map[position]vegetation)
-	g.DigFungus(1 + RandInt(2))
+	g.DigFungus(1 + g.RandInt(2))
 	g.PutDoors(30)
 	g.PutDoorsList(doors, 20)
 }
@@ -646,7 +651,7 @@ func (rs roomSlice) Less(i, j int) bool {
 }
 
 func (g *game) GenRoomMap(h, w int) {
-	d := &dungeon{}
+	d := &dungeon{rand: g.Rand}
 	d.Cells = make([]cell, h*w)
 	rooms := []room{}
 	cols := 0
@@ -656,9 +661,9 @@ func (g *game) GenRoomMap(h, w int) {
 		for count > 0 {
 			count--
 			ro = room{
-				pos: position{RandInt(w - 1), RandInt(h - 1)},
-				w:   5 + RandInt(4),
-				h:   3 + RandInt(3)}
+				pos: position{g.RandInt(w - 1), g.RandInt(h - 1)},
+				w:   5 + g.RandInt(4),
+				h:   3 + g.RandInt(3)}
 			ro = d.ResizeRoom(ro)
 			if !intersectsRoom(rooms, ro) {
 				break
@@ -666,8 +671,8 @@ func (g *game) GenRoomMap(h, w int) {
 		}
 
 		d.DigRoom(ro)
-		if RandInt(10+15*cols) == 0 {
-			if RandInt(2) == 0 {
+		if g.RandInt(10+15*cols) == 0 {
+			if g.RandInt(2) == 0 {
 				d.PutCols(ro)
 			} else {
 				d.PutDiagCols(ro)
@@ -681,13 +686,13 @@ func (g *game) GenRoomMap(h, w int) {
 		if i == 0 {
 			continue
 		}
-		r := RandInt(100)
+		r := g.RandInt(100)
 		if r > 50 {
-			d.connectRooms(nearestRoom(rooms[:i], ro), ro)
+			d.connectRooms(d.nearestRoom(rooms[:i], ro), ro)
 		} else if r > 25 {
-			d.ConnectRoomsShortestPath(nearRoom(rooms[:i], ro), ro)
+			d.ConnectRoomsShortestPath(d.nearRoom(rooms[:i], ro), ro)
 		} else {
-			d.connectRoomsDiagonally(nearestRoom(rooms[:i], ro), ro)
+			d.connectRoomsDiagonally(d.nearestRoom(rooms[:i], ro), ro)
 		}
 	}
 	g.Dungeon = d
@@ -697,8 +702,8 @@ func (g *game) GenRoomMap(h, w int) {
 }
 
 func (g *game) PutDoorsList(doors map[position]bool, threshold int) {
-	for pos := range doors {
-		if g.DoorCandidate(pos) && RandInt(100) > threshold {
+	for _, pos := range SortedPositions(doors) {
+		if g.DoorCandidate(pos) && g.RandInt(100) > threshold {
 			g.Doors[pos] = true
 			if _, ok := g.Fungus[pos]; ok {
 				delete(g.Fungus, pos)
@@ -714,8 +719,8 @@ func (d *dungeon) FreeCell() position {
 		if count > 1000 {
 			panic("FreeCell")
 		}
-		x := RandInt(DungeonWidth)
-		y := RandInt(DungeonHeight)
+		x := d.RandInt(DungeonWidth)
+		y := d.RandInt(DungeonHeight)
 		pos := position{x, y}
 		c := d.Cell(pos)
 		if c.T == FreeCell {
@@ -731,8 +736,8 @@ func (d *dungeon) WallCell() position {
 		if count > 1000 {
 			panic("WallCell")
 		}
-		x := RandInt(DungeonWidth)
-		y := RandInt(DungeonHeight)
+		x := d.RandInt(DungeonWidth)
+		y := d.RandInt(DungeonHeight)
 		pos := position{x, y}
 		c := d.Cell(pos)
 		if c.T == WallCell {
@@ -742,7 +747,7 @@ func (d *dungeon) WallCell() position {
 }
 
 func (g *game) GenCaveMap(h, w int) {
-	d := &dungeon{}
+	d := &dungeon{rand: g.Rand}
 	d.Cells = make([]cell, h*w)
 	pos := position{40, 10}
 	max := 21 * 42
@@ -750,9 +755,9 @@ func (g *game) GenCaveMap(h, w int) {
 	cells := 1
 	notValid := 0
 	lastValid := pos
-	diag := RandInt(4) == 0
+	diag := g.RandInt(4) == 0
 	for cells < max {
-		npos := pos.RandomNeighbor(diag)
+		npos := d.RandomNeighbor(pos, diag)
 		if !pos.valid() && npos.valid() && d.Cell(npos).T == WallCell {
 			pos = lastValid
 			continue
@@ -786,7 +791,7 @@ loop:
 		if i > 1000 {
 			break
 		}
-		diag = RandInt(2) == 0
+		diag = g.RandInt(2) == 0
 		block = d.DigBlock(block, diag)
 		if len(block) == 0 {
 			continue loop
@@ -802,38 +807,37 @@ loop:
 	}
 	doors := make(map[position]bool)
 	rooms := 0
-	if RandInt(4) > 0 {
-		w, h := GenCaveRoomSize()
+	if g.RandInt(4) > 0 {
+		w, h := d.GenCaveRoomSize()
 		rooms++
-		for pos := range d.BuildSomeRoom(w, h) {
+		for _, pos := range SortedPositions(d.BuildSomeRoom(w, h)) {
 			doors[pos] = true
 		}
-		if RandInt(7) == 0 {
+		if g.RandInt(7) == 0 {
 			rooms++
-			w, h := GenCaveRoomSize()
-			for pos := range d.BuildSomeRoom(w, h) {
+			w, h := d.GenCaveRoomSize()
+			for _, pos := range SortedPositions(d.BuildSomeRoom(w, h)) {
 				doors[pos] = true
 			}
 
 		}
 	}
-	if RandInt(1+rooms) == 0 {
+	if g.RandInt(1+rooms) == 0 {
 		w, h := GenLittleRoomSize()
-		i := 0
-		for pos := range d.DigIsolatedRoom(w, h) {
+		rdoors := SortedPositions(d.DigIsolatedRoom(w, h))
+		for _, pos := range rdoors {
 			doors[pos] = true
-			if i == 0 {
-				d.ConnectIsolatedRoom(pos)
-			}
-			i++
+		}
+		if len(rdoors) > 0 {
+			d.ConnectIsolatedRoom(rdoors[g.RandInt(len(rdoors))])
 		}
 
 	}
 	g.Dungeon = d
 	g.Fungus = g.Foliage(DungeonHeight, DungeonWidth)
 	g.PutDoors(5)
-	for pos := range doors {
-		if g.DoorCandidate(pos) && RandInt(100) > 20 {
+	for _, pos := range SortedPositions(doors) {
+		if g.DoorCandidate(pos) && g.RandInt(100) > 20 {
 			g.Doors[pos] = true
 			if _, ok := g.Fungus[pos]; ok {
 				delete(g.Fungus, pos)
@@ -842,8 +846,8 @@ loop:
 	}
 }
 
-func GenCaveRoomSize() (int, int) {
-	return 7 + 2*RandInt(2), 5 + 2*RandInt(2)
+func (d *dungeon) GenCaveRoomSize() (int, int) {
+	return 7 + 2*d.RandInt(2), 5 + 2*d.RandInt(2)
 }
 
 func GenLittleRoomSize() (int, int) {
@@ -880,7 +884,7 @@ func (d *dungeon) DigBlock(block []position, diag bool) []position {
 		if d.HasFreeNeighbor(pos) {
 			break
 		}
-		pos = pos.RandomNeighbor(diag)
+		pos = d.RandomNeighbor(pos, diag)
 		if !pos.valid() {
 			block = block[:0]
 			pos = d.WallCell()
@@ -894,7 +898,7 @@ func (d *dungeon) DigBlock(block []position, diag bool) []position {
 }
 
 func (g *game) GenCaveMapTree(h, w int) {
-	d := &dungeon{}
+	d := &dungeon{rand: g.Rand}
 	d.Cells = make([]cell, h*w)
 	center := position{40, 10}
 	d.SetCell(center, FreeCell)
@@ -908,7 +912,7 @@ func (g *game) GenCaveMapTree(h, w int) {
 	d.SetCell(center.SW(), FreeCell)
 	max := 21 * 23
 	cells := 1
-	diag := RandInt(2) == 0
+	diag := g.RandInt(2) == 0
 	block := make([]position, 0, 64)
 loop:
 	for cells < max {
@@ -927,21 +931,21 @@ loop:
 	doors := d.DigSomeRooms(5)
 	g.Dungeon = d
 	g.Fungus = make(map[position]vegetation)
-	g.DigFungus(1 + RandInt(2))
+	g.DigFungus(1 + g.RandInt(2))
 	g.PutDoors(5)
 	g.PutDoorsList(doors, 20)
 }
 
 func (d *dungeon) DigSomeRooms(chances int) map[position]bool {
 	doors := make(map[position]bool)
-	if RandInt(chances) > 0 {
-		w, h := GenCaveRoomSize()
-		for pos := range d.DigSomeRoom(w, h) {
+	if d.RandInt(chances) > 0 {
+		w, h := d.GenCaveRoomSize()
+		for _, pos := range SortedPositions(d.DigSomeRoom(w, h)) {
 			doors[pos] = true
 		}
-		if RandInt(3) == 0 {
-			w, h := GenCaveRoomSize()
-			for pos := range d.DigSomeRoom(w, h) {
+		if d.RandInt(3) == 0 {
+			w, h := d.GenCaveRoomSize()
+			for _, pos := range SortedPositions(d.DigSomeRoom(w, h)) {
 				doors[pos] = true
 			}
 		}
@@ -999,10 +1003,10 @@ func (d *dungeon) connex() bool {
 }
 
 func (g *game) RunCellularAutomataCave(h, w int) bool {
-	d := &dungeon{}
+	d := &dungeon{rand: g.Rand}
 	d.Cells = make([]cell, h*w)
 	for i := range d.Cells {
-		r := RandInt(100)
+		r := g.RandInt(100)
 		pos := idxtopos(i)
 		if r >= 45 {
 			d.SetCell(pos, FreeCell)
@@ -1073,7 +1077,7 @@ loop:
 		if i > 1000 {
 			break
 		}
-		diag := RandInt(2) == 0
+		diag := g.RandInt(2) == 0
 		block = d.DigBlock(block, diag)
 		if len(block) == 0 {
 			continue loop
@@ -1088,32 +1092,30 @@ loop:
 		digs++
 	}
 	doors := make(map[position]bool)
-	if RandInt(5) > 0 {
+	if g.RandInt(5) > 0 {
 		w, h := GenLittleRoomSize()
-		i := 0
-		for pos := range d.DigIsolatedRoom(w, h) {
+		rdoors := SortedPositions(d.DigIsolatedRoom(w, h))
+		for _, pos := range rdoors {
 			doors[pos] = true
-			if i == 0 {
-				d.ConnectIsolatedRoom(pos)
-			}
-			i++
 		}
-		if RandInt(4) == 0 {
-			w, h := GenCaveRoomSize()
-			i := 0
-			for pos := range d.DigIsolatedRoom(w, h) {
+		if len(rdoors) > 0 {
+			d.ConnectIsolatedRoom(rdoors[g.RandInt(len(rdoors))])
+		}
+		if g.RandInt(4) == 0 {
+			w, h := d.GenCaveRoomSize()
+			rdoors := SortedPositions(d.DigIsolatedRoom(w, h))
+			for _, pos := range rdoors {
 				doors[pos] = true
-				if i == 0 {
-					d.ConnectIsolatedRoom(pos)
-				}
-				i++
+			}
+			if len(rdoors) > 0 {
+				d.ConnectIsolatedRoom(rdoors[g.RandInt(len(rdoors))])
 			}
 		}
 	}
 	g.Dungeon = d
 	g.PutDoors(10)
-	for pos := range doors {
-		if g.DoorCandidate(pos) && RandInt(100) > 20 {
+	for _, pos := range SortedPositions(doors) {
+		if g.DoorCandidate(pos) && g.RandInt(100) > 20 {
 			g.Doors[pos] = true
 			if _, ok := g.Fungus[pos]; ok {
 				delete(g.Fungus, pos)
@@ -1153,8 +1155,8 @@ func (d *dungeon) SimpleRoom(r room) map[position]bool {
 		position{r.pos.X + r.w - 1, r.pos.Y + r.h/2},
 	}
 	doors := make(map[position]bool)
-	for i := 0; i < 3+RandInt(2); i++ {
-		dpos := doorsc[RandInt(4)]
+	for i := 0; i < 3+d.RandInt(2); i++ {
+		dpos := doorsc[d.RandInt(4)]
 		doors[dpos] = true
 		d.SetCell(dpos, FreeCell)
 	}
@@ -1219,7 +1221,7 @@ func (g *game) ExtendEdgeRoom(r room, doors map[position]bool) room {
 	if !extend {
 		return r
 	}
-	for pos := range doors {
+	for _, pos := range SortedPositions(doors) {
 		if pos.X == 1 || pos.X == DungeonWidth-2 || pos.Y == 1 || pos.Y == DungeonHeight-2 {
 			delete(g.Doors, pos)
 			continue
@@ -1242,8 +1244,8 @@ func (g *game) ExtendEdgeRoom(r room, doors map[position]bool) room {
 		}
 		ndoorsc = append(ndoorsc, pos)
 	}
-	for i := 0; i < 1+RandInt(2-ndoors); i++ {
-		dpos := ndoorsc[RandInt(len(ndoorsc))]
+	for i := 0; i < 1+g.RandInt(2-ndoors); i++ {
+		dpos := ndoorsc[g.RandInt(len(ndoorsc))]
 		g.Doors[dpos] = true
 		g.Dungeon.SetCell(dpos, FreeCell)
 	}
@@ -1260,9 +1262,9 @@ func (g *game) DivideRoomVertically(r room) {
 	if r.h < 5 {
 		return
 	}
-	dx := 2 + RandInt(r.w/2-2)
-	if RandInt(2) == 0 {
-		dx = r.w - 3 - RandInt(r.w/2-3)
+	dx := 2 + g.RandInt(r.w/2-2)
+	if g.RandInt(2) == 0 {
+		dx = r.w - 3 - g.RandInt(r.w/2-3)
 	}
 	if dx == 2 && r.pos.X == 0 {
 		return
@@ -1301,9 +1303,9 @@ func (g *game) DivideRoomHorizontally(r room) {
 	if r.w < 5 {
 		return
 	}
-	dy := 2 + RandInt(r.h/2-2)
-	if RandInt(2) == 0 {
-		dy = r.h - 3 - RandInt(r.h/2-3)
+	dy := 2 + g.RandInt(r.h/2-2)
+	if g.RandInt(2) == 0 {
+		dy = r.h - 3 - g.RandInt(r.h/2-3)
 	}
 	if dy == 2 && r.pos.Y == 0 {
 		return
@@ -1340,18 +1342,18 @@ func (g *game) GenBSPMap(height, width int) {
 		r := crooms[0]
 		crooms = crooms[1:]
 		if r.h <= 8 && r.w <= 12 {
-			switch RandInt(6) {
+			switch g.RandInt(6) {
 			case 0:
 				if r.h >= 6 {
 					r.h--
-					if RandInt(2) == 0 {
+					if g.RandInt(2) == 0 {
 						r.pos.Y++
 					}
 				}
 			case 1:
 				if r.w >= 8 {
 					r.w--
-					if RandInt(2) == 0 {
+					if g.RandInt(2) == 0 {
 						r.pos.X++
 					}
 				}
@@ -1361,17 +1363,17 @@ func (g *game) GenBSPMap(height, width int) {
 			}
 			continue
 		}
-		if RandInt(2+big) == 0 && (r.h <= 12 && r.w <= 20) {
+		if g.RandInt(2+big) == 0 && (r.h <= 12 && r.w <= 20) {
 			big++
-			switch RandInt(4) {
+			switch g.RandInt(4) {
 			case 0:
 				r.h--
-				if RandInt(2) == 0 {
+				if g.RandInt(2) == 0 {
 					r.pos.Y++
 				}
 			case 1:
 				r.w--
-				if RandInt(2) == 0 {
+				if g.RandInt(2) == 0 {
 					r.pos.X++
 				}
 			}
@@ -1381,13 +1383,13 @@ func (g *game) GenBSPMap(height, width int) {
 			continue
 		}
 		horizontal := false
-		if r.h > 8 && r.w > 10 && r.w < 40 && RandInt(4) == 0 {
+		if r.h > 8 && r.w > 10 && r.w < 40 && g.RandInt(4) == 0 {
 			horizontal = true
-		} else if r.h > 8 && r.w <= 10+RandInt(5) {
+		} else if r.h > 8 && r.w <= 10+g.RandInt(5) {
 			horizontal = true
 		}
 		if horizontal {
-			h := r.h/2 - r.h/4 + RandInt(1+r.h/2)
+			h := r.h/2 - r.h/4 + g.RandInt(1+r.h/2)
 			if h <= 3 {
 				h++
 			}
@@ -1396,7 +1398,7 @@ func (g *game) GenBSPMap(height, width int) {
 			}
 			crooms = append(crooms, room{r.pos, r.w, h}, room{position{r.pos.X, r.pos.Y + 1 + h}, r.w, r.h - h - 1})
 		} else {
-			w := r.w/2 - r.w/4 + RandInt(1+r.w/2)
+			w := r.w/2 - r.w/4 + g.RandInt(1+r.w/2)
 			if w <= 3 {
 				w++
 			}
@@ -1407,7 +1409,7 @@ func (g *game) GenBSPMap(height, width int) {
 		}
 	}
 
-	d := &dungeon{}
+	d := &dungeon{rand: g.Rand}
 	d.Cells = make([]cell, height*width)
 	for i := 0; i < DungeonNCells; i++ {
 		d.SetCell(idxtopos(i), FreeCell)
@@ -1418,12 +1420,12 @@ func (g *game) GenBSPMap(height, width int) {
 	empty := 0
 	for i, r := range rooms {
 		var doors map[position]bool
-		if RandInt(2+special/3) == 0 && r.w%2 == 1 && r.h%2 == 1 && r.w >= 5 && r.h >= 5 {
+		if g.RandInt(2+special/3) == 0 && r.w%2 == 1 && r.h%2 == 1 && r.w >= 5 && r.h >= 5 {
 			doors = d.BuildRoom(r.pos, r.w, r.h, true)
 			special++
-		} else if empty > 0 || RandInt(20) > 0 {
+		} else if empty > 0 || g.RandInt(20) > 0 {
 			doors = d.SimpleRoom(r)
-			if RandInt(2) == 0 && r.w >= 7 && r.h >= 7 {
+			if g.RandInt(2) == 0 && r.w >= 7 && r.h >= 7 {
 				rn := r
 				rn.pos.X++
 				rn.pos.Y++
@@ -1431,12 +1433,12 @@ func (g *game) GenBSPMap(height, width int) {
 				rn.h--
 				rn.w--
 				rn.w--
-				if RandInt(2) == 0 {
+				if g.RandInt(2) == 0 {
 					d.PutCols(rn)
 				} else {
 					d.PutDiagCols(rn)
 				}
-			} else if RandInt(1+special/2) == 0 && r.w >= 11 && r.h >= 9 {
+			} else if g.RandInt(1+special/2) == 0 && r.w >= 11 && r.h >= 9 {
 				sx := (r.w - 11) / 2
 				sy := (r.h - 9) / 2
 				doors = d.BuildRoom(position{r.pos.X + 2 + sx, r.pos.Y + 2 + sy}, 7, 5, true)
@@ -1445,17 +1447,17 @@ func (g *game) GenBSPMap(height, width int) {
 		} else {
 			empty++
 		}
-		for pos := range doors {
-			if g.DoorCandidate(pos) && RandInt(100) > 10 {
+		for _, pos := range SortedPositions(doors) {
+			if g.DoorCandidate(pos) && g.RandInt(100) > 10 {
 				g.Doors[pos] = true
 			}
 		}
-		if RandInt(2) == 0 {
+		if g.RandInt(2) == 0 {
 			r = g.ExtendEdgeRoom(r, doors)
 			rooms[i] = r
 		}
-		if RandInt(5) > 0 {
-			if RandInt(2) == 0 {
+		if g.RandInt(5) > 0 {
+			if g.RandInt(2) == 0 {
 				g.DivideRoomVertically(r)
 			} else {
 				g.DivideRoomHorizontally(r)
@@ -1463,9 +1465,9 @@ func (g *game) GenBSPMap(height, width int) {
 		}
 	}
 	g.Fungus = make(map[position]vegetation)
-	g.DigFungus(RandInt(3))
-	for i := 0; i <= RandInt(2); i++ {
-		r := rooms[RandInt(len(rooms))]
+	g.DigFungus(g.RandInt(3))
+	for i := 0; i <= g.RandInt(2); i++ {
+		r := rooms[g.RandInt(len(rooms))]
 		for x := r.pos.X + 1; x < r.pos.X+r.w-1; x++ {
 			for y := r.pos.Y + 1; y < r.pos.Y+r.h-1; y++ {
 				g.Fungus[position{x, y}] = foliage
@@ -1483,10 +1485,10 @@ const (
 func (g *game) Foliage(h, w int) map[position]vegetation {
 	// use same structure as for the dungeon
 	// walls will become foliage
-	d := &dungeon{}
+	d := &dungeon{rand: g.Rand}
 	d.Cells = make([]cell, h*w)
 	for i := range d.Cells {
-		r := RandInt(100)
+		r := g.RandInt(100)
 		pos := idxtopos(i)
 		if r >= 43 {
 			d.SetCell(pos, WallCell)
@@ -1561,7 +1563,7 @@ func (g *game) PutDoors(percentage int) {
 	g.Doors = map[position]bool{}
 	for i := range g.Dungeon.Cells {
 		pos := idxtopos(i)
-		if g.DoorCandidate(pos) && RandInt(100) < percentage {
+		if g.DoorCandidate(pos) && g.RandInt(100) < percentage {
 			g.Doors[pos] = true
 			if _, ok := g.Fungus[pos]; ok {
 				delete(g.Fungus, pos)
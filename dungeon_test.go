@@ -11,6 +11,7 @@ var Rounds = 100
 func BenchmarkCellularAutomataCaveMap(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		g := &game{}
+		g.InitRand()
 		g.GenCellularAutomataCaveMap(DungeonHeight, DungeonWidth)
 	}
 }
@@ -18,6 +19,7 @@ func BenchmarkCellularAutomataCaveMap(b *testing.B) {
 func TestCellularAutomataCaveMap(t *testing.T) {
 	for i := 0; i < Rounds; i++ {
 		g := &game{}
+		g.InitRand()
 		g.GenCellularAutomataCaveMap(DungeonHeight, DungeonWidth)
 		if !g.Dungeon.connex() {
 			t.Errorf("Not connex:\n%s\n", g.Dungeon.String())
@@ -28,6 +30,7 @@ func TestCellularAutomataCaveMap(t *testing.T) {
 func TestCaveMap(t *testing.T) {
 	for i := 0; i < Rounds; i++ {
 		g := &game{}
+		g.InitRand()
 		g.GenCaveMap(DungeonHeight, DungeonWidth)
 		if !g.Dungeon.connex() {
 			t.Errorf("Not connex:\n%s\n", g.Dungeon.String())
@@ -38,6 +41,7 @@ func TestCaveMap(t *testing.T) {
 func TestCaveMapTree(t *testing.T) {
 	for i := 0; i < Rounds; i++ {
 		g := &game{}
+		g.InitRand()
 		g.GenCaveMapTree(DungeonHeight, DungeonWidth)
 		if !g.Dungeon.connex() {
 			t.Errorf("Not connex:\n%s\n", g.Dungeon.String())
@@ -48,6 +52,7 @@ func TestCaveMapTree(t *testing.T) {
 func TestRuinsMap(t *testing.T) {
 	for i := 0; i < Rounds; i++ {
 		g := &game{}
+		g.InitRand()
 		g.GenRuinsMap(DungeonHeight, DungeonWidth)
 		if !g.Dungeon.connex() {
 			t.Errorf("Not connex:\n%s\n", g.Dungeon.String())
@@ -58,6 +63,7 @@ func TestRuinsMap(t *testing.T) {
 func TestBSPMap(t *testing.T) {
 	for i := 0; i < Rounds; i++ {
 		g := &game{}
+		g.InitRand()
 		g.GenBSPMap(DungeonHeight, DungeonWidth)
 		if !g.Dungeon.connex() {
 			t.Errorf("Not connex:\n%s\n", g.Dungeon.String())
@@ -83,6 +89,7 @@ func (d *dungeon) String() string {
 func TestRoomMap(t *testing.T) {
 	for i := 0; i < Rounds; i++ {
 		g := &game{}
+		g.InitRand()
 		g.GenRoomMap(DungeonHeight, DungeonWidth)
 		if !g.Dungeon.connex() {
 			t.Errorf("Not connex:\n%s\n", g.Dungeon.String())
@@ -4,23 +4,116 @@ import (
 	"bytes"
 	"compress/zlib"
 	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
 )
 
 func init() {
-	gob.Register(potion(0))
-	gob.Register(projectile(0))
-	gob.Register(&simpleEvent{})
-	gob.Register(&monsterEvent{})
-	gob.Register(&cloudEvent{})
-	gob.Register(armour(0))
-	gob.Register(weapon(0))
-	gob.Register(shield(0))
+	// names are given explicitly, because gob.Register uses the import
+	// path of the package, which is not "main" in tests: saves of released
+	// versions could not be decoded there.
+	gob.RegisterName("main.potion", potion(0))
+	gob.RegisterName("main.projectile", projectile(0))
+	gob.RegisterName("*main.simpleEvent", &simpleEvent{})
+	gob.RegisterName("*main.monsterEvent", &monsterEvent{})
+	gob.RegisterName("*main.cloudEvent", &cloudEvent{})
+	gob.RegisterName("main.armour", armour(0))
+	gob.RegisterName("main.weapon", weapon(0))
+	gob.RegisterName("main.shield", shield(0))
+}
+
+// SaveFormat is the current save format number. It should be incremented
+// each time the game structure changes in a way that gob decoding cannot
+// handle by itself (for example renumbered status or rod values), with a
+// new migration function appended to saveMigrations.
+const SaveFormat = 3
+
+// saveMagic starts versioned saves. Since format 2, it is followed by a
+// checksum line of the compressed data. Saves without it (format 0, as
+// written by v0.13) are plain compressed gob encodings of the game.
+const saveMagic = "boohu-save\n"
+
+const saveChecksumPrefix = "crc32 "
+
+const saveChecksumLen = len("crc32 01234567\n")
+
+type saveEnvelope struct {
+	Format  int
+	Version string
+	Game    *game
+}
+
+// saveVersions are the versions, other than the current one, whose saves
+// can be upgraded with saveMigrations. Saves of releases before v0.13 have
+// other enum values and structures, and are refused.
+var saveVersions = map[string]bool{
+	"v0.13":     true,
+	"v0.14-dev": true,
+}
+
+// saveUpgrade is a saved game being upgraded to the current format.
+type saveUpgrade struct {
+	g    *game
+	data []byte // compressed game data, without the file header
+}
+
+// saveMigrations[i] upgrades a game from save format i to format i+1.
+var saveMigrations = []func(su *saveUpgrade) error{
+	migrateSaveRand,
+	migrateSaveChecksum,
+	migrateSaveInputs,
+}
+
+// migrateSaveRand upgrades saves from before the random number generator
+// state was saved with the game (v0.13 and older development saves). The
+// original seed is unknown, so the random source is seeded from the save
+// contents: loading the same save always gives the same game.
+func migrateSaveRand(su *saveUpgrade) error {
+	if su.g.Rand == nil {
+		su.g.Rand = newRng(int64(crc32.ChecksumIEEE(su.data)))
+	}
+	return nil
+}
+
+// migrateSaveChecksum upgrades saves from before the checksum line. Their
+// only integrity check is the Adler-32 checksum at the end of the zlib
+// stream, which is not verified when gob stops reading before it, so the
+// stream is read to the end.
+func migrateSaveChecksum(su *saveUpgrade) error {
+	r, err := zlib.NewReader(bytes.NewReader(su.data))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(ioutil.Discard, r)
+	if err != nil {
+		return fmt.Errorf("corrupted saved game (%v)", err)
+	}
+	return r.Close()
+}
+
+// migrateSaveInputs upgrades saves from before key actions and map
+// positions were recorded instead of keys and screen positions. Those
+// inputs cannot be replayed anymore, and v0.13 saves have none, so the
+// input record is marked incomplete.
+func migrateSaveInputs(su *saveUpgrade) error {
+	su.g.InputRecord.Inputs = nil
+	su.g.InputRecord.Incomplete = true
+	return nil
 }
 
 func (g *game) GameSave() ([]byte, error) {
+	if g.replay != nil {
+		// the replay is streamed to its own file
+		dl := g.DrawLog
+		g.DrawLog = nil
+		defer func() { g.DrawLog = dl }()
+	}
 	data := bytes.Buffer{}
 	enc := gob.NewEncoder(&data)
-	err := enc.Encode(g)
+	err := enc.Encode(&saveEnvelope{Format: SaveFormat, Version: Version, Game: g})
 	if err != nil {
 		return nil, err
 	}
@@ -28,7 +121,8 @@ func (g *game) GameSave() ([]byte, error) {
 	w := zlib.NewWriter(&buf)
 	w.Write(data.Bytes())
 	w.Close()
-	return buf.Bytes(), nil
+	header := fmt.Sprintf("%s%s%08x\n", saveMagic, saveChecksumPrefix, crc32.ChecksumIEEE(buf.Bytes()))
+	return append([]byte(header), buf.Bytes()...), nil
 }
 
 type config struct {
@@ -38,6 +132,8 @@ type config struct {
 	Small              bool
 	Tiles              bool
 	Version            string
+	Palette            string
+	NoAnimations       bool
 }
 
 func (c *config) ConfigSave() ([]byte, error) {
@@ -51,6 +147,25 @@ func (c *config) ConfigSave() ([]byte, error) {
 }
 
 func (g *game) DecodeGameSave(data []byte) (*game, error) {
+	format := 0
+	if bytes.HasPrefix(data, []byte(saveMagic)) {
+		format = -1 // given by the envelope
+		data = data[len(saveMagic):]
+	}
+	if format != 0 && bytes.HasPrefix(data, []byte(saveChecksumPrefix)) {
+		if len(data) < saveChecksumLen {
+			return nil, errors.New("truncated saved game")
+		}
+		var sum uint32
+		_, err := fmt.Sscanf(string(data[:saveChecksumLen]), "crc32 %08x\n", &sum)
+		if err != nil {
+			return nil, fmt.Errorf("bad saved game checksum header: %v", err)
+		}
+		data = data[saveChecksumLen:]
+		if crc32.ChecksumIEEE(data) != sum {
+			return nil, errors.New("corrupted saved game (checksum mismatch)")
+		}
+	}
 	buf := bytes.NewReader(data)
 	r, err := zlib.NewReader(buf)
 	if err != nil {
@@ -58,11 +173,39 @@ func (g *game) DecodeGameSave(data []byte) (*game, error) {
 	}
 	dec := gob.NewDecoder(r)
 	lg := &game{}
-	err = dec.Decode(lg)
+	if format == 0 {
+		// v0.13 layout
+		err = dec.Decode(lg)
+	} else {
+		env := &saveEnvelope{}
+		err = dec.Decode(env)
+		format = env.Format
+		lg = env.Game
+		if err == nil && lg == nil {
+			err = errors.New("saved game without game data")
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 	r.Close()
+	if format > SaveFormat {
+		return nil, fmt.Errorf("saved game for newer version %s", lg.Version)
+	}
+	if lg.Version != Version && !saveVersions[lg.Version] {
+		return nil, fmt.Errorf("saved game for version %s cannot be upgraded", lg.Version)
+	}
+	su := &saveUpgrade{g: lg, data: data}
+	for ; format < SaveFormat; format++ {
+		err = saveMigrations[format](su)
+		if err != nil {
+			return nil, fmt.Errorf("upgrading saved game for version %s: %v", lg.Version, err)
+		}
+	}
+	lg.Version = Version
+	if lg.Rand == nil {
+		return nil, errors.New("saved game without random number generator state")
+	}
 	return lg, nil
 }
 
@@ -106,3 +249,33 @@ func (g *game) DecodeDrawLog(data []byte) ([]drawFrame, error) {
 	r.Close()
 	return dl, nil
 }
+
+func (g *game) EncodeInputRecord() ([]byte, error) {
+	data := bytes.Buffer{}
+	enc := gob.NewEncoder(&data)
+	err := enc.Encode(&g.InputRecord)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(data.Bytes())
+	w.Close()
+	return buf.Bytes(), nil
+}
+
+func (g *game) DecodeInputRecord(data []byte) (*inputRecord, error) {
+	buf := bytes.NewReader(data)
+	r, err := zlib.NewReader(buf)
+	if err != nil {
+		return nil, err
+	}
+	dec := gob.NewDecoder(r)
+	rec := &inputRecord{}
+	err = dec.Decode(rec)
+	if err != nil {
+		return nil, err
+	}
+	r.Close()
+	return rec, nil
+}
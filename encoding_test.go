@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/gob"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestGameSaveRand(t *testing.T) {
+	g := &game{Seed: 42}
+	g.InitLevel()
+	g.RandInt(100)
+	data, err := g.GameSave()
+	if err != nil {
+		t.Fatalf("GameSave: %v", err)
+	}
+	nums := []int{}
+	for i := 0; i < 100; i++ {
+		nums = append(nums, g.RandInt(1000))
+	}
+	state := g.Rand.State
+	lg, err := g.DecodeGameSave(data)
+	if err != nil {
+		t.Fatalf("DecodeGameSave: %v", err)
+	}
+	if g.Rand.State != state {
+		t.Fatalf("decoding changed the random source in use")
+	}
+	if lg.Rand.State == g.Rand.State {
+		t.Fatalf("random state not restored")
+	}
+	for i, n := range nums {
+		if m := lg.RandInt(1000); m != n {
+			t.Errorf("different random number %d after load: %d vs %d", i, m, n)
+		}
+	}
+}
+
+func TestSaveMigration(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "saves", "*.save"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no sample saves: %v", err)
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := &game{}
+		lg, err := g.DecodeGameSave(data)
+		if err != nil {
+			t.Errorf("%s: %v", f, err)
+			continue
+		}
+		if lg.Version != Version || lg.Rand == nil || lg.Player == nil || lg.Dungeon == nil {
+			t.Errorf("%s: bad upgraded game", f)
+			continue
+		}
+		// the upgraded game should be playable
+		ui := &headlessUI{g: lg}
+		lg.ui = ui
+		lg.noIO = true
+		lg.SetController(&limitedBot{max: 200})
+		lg.EventLoop()
+		if lg.Turn == 0 {
+			t.Errorf("%s: upgraded game did not run", f)
+		}
+		data, err = lg.GameSave()
+		if err != nil {
+			t.Errorf("%s: saving upgraded game: %v", f, err)
+		}
+	}
+}
+
+func TestSaveFormat1(t *testing.T) {
+	// format 1 saves have no checksum line after the magic
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "saves", "format1-v0.14-dev.save"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(saveMagic)) || bytes.HasPrefix(data[len(saveMagic):], []byte(saveChecksumPrefix)) {
+		t.Fatalf("format 1 sample save does not have the format 1 layout")
+	}
+	g := &game{}
+	lg, err := g.DecodeGameSave(data)
+	if err != nil {
+		t.Fatalf("format 1 save not loaded: %v", err)
+	}
+	if lg.Player == nil || lg.Rand == nil {
+		t.Errorf("bad format 1 game")
+	}
+	// only the Adler-32 checksum of the zlib stream protects its end
+	data[len(data)-1] ^= 0xff
+	if _, err := g.DecodeGameSave(data); err == nil {
+		t.Errorf("format 1 save with bad zlib checksum loaded")
+	}
+	data[len(data)-1] ^= 0xff
+	data[len(data)-10] ^= 0xff
+	if _, err := g.DecodeGameSave(data); err == nil {
+		t.Errorf("corrupted format 1 save loaded")
+	}
+}
+
+func TestSaveV013(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "saves", "format0-v0.13.save"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &game{}
+	lg, err := g.DecodeGameSave(data)
+	if err != nil {
+		t.Fatalf("v0.13 save not loaded: %v", err)
+	}
+	if lg.Version != Version || lg.Turn != 160 || lg.Depth != 1 || lg.Player.HP != 42 || lg.Player.Pos != (position{14, 11}) {
+		t.Errorf("bad v0.13 game: %s turn %d depth %d", lg.Version, lg.Turn, lg.Depth)
+	}
+	if lg.Player.Consumables[ConfusingDart] != 2 || lg.Player.Rods[RodLignification].Charge != 3 || lg.Player.Weapon != Dagger {
+		t.Errorf("bad v0.13 inventory: %v %v %v", lg.Player.Consumables, lg.Player.Rods, lg.Player.Weapon)
+	}
+	if len(lg.Monsters) != 5 || lg.Events.Len() != 6 || len(lg.DrawLog) != 42 {
+		t.Errorf("bad v0.13 monsters, events or replay")
+	}
+	if lg.Seed != 0 || !lg.InputRecord.Incomplete {
+		t.Errorf("v0.13 game not marked without seed and inputs")
+	}
+	lg2, err := g.DecodeGameSave(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lg.Rand == nil || lg.Rand.State != lg2.Rand.State {
+		t.Errorf("random source of v0.13 game not derived from the save")
+	}
+}
+
+func TestSaveReleasedVersion(t *testing.T) {
+	g := &game{Seed: 4}
+	g.InitLevel()
+	g.Version = "v0.12.0"
+	// format 0 layout, as written by released versions
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if err := gob.NewEncoder(w).Encode(g); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if _, err := g.DecodeGameSave(buf.Bytes()); err == nil {
+		t.Errorf("save of released version %s accepted", g.Version)
+	}
+}
@@ -108,7 +108,11 @@ func (sev *simpleEvent) Action(g *game) {
 			g.TurnStats()
 			return
 		}
-		g.Quit = g.ui.HandlePlayerTurn(sev)
+		if g.controller != nil {
+			g.Quit = g.ControllerTurn(sev)
+		} else {
+			g.Quit = g.ui.HandlePlayerTurn(sev)
+		}
 		if g.Quit {
 			return
 		}
@@ -125,74 +129,76 @@ func (sev *simpleEvent) Action(g *game) {
 		g.Player.Statuses[StatusSlow]++
 		g.Player.Statuses[StatusExhausted] = 1
 		g.Player.HP -= int(10 * g.Player.HP / Max(g.Player.HPMax(), g.Player.HP))
+		g.Killer = "berserk exhaustion"
 		g.PrintStyled("You are no longer berserk.", logStatusEnd)
-		g.PushEvent(&simpleEvent{ERank: sev.Rank() + 90 + RandInt(30), EAction: SlowEnd})
-		g.PushEvent(&simpleEvent{ERank: sev.Rank() + 270 + RandInt(60), EAction: ExhaustionEnd})
-		g.ui.StatusEndAnimation()
+		g.PushEvent(&simpleEvent{ERank: sev.Rank() + 90 + g.RandInt(30), EAction: SlowEnd})
+		g.PushEvent(&simpleEvent{ERank: sev.Rank() + 270 + g.RandInt(60), EAction: ExhaustionEnd})
+		g.Animations().StatusEndAnimation()
 	case SlowEnd:
 		g.Player.Statuses[StatusSlow]--
 		if g.Player.Statuses[StatusSlow] <= 0 {
 			g.PrintStyled("You no longer feel slow.", logStatusEnd)
-			g.ui.StatusEndAnimation()
+			g.Animations().StatusEndAnimation()
 		}
 	case ExhaustionEnd:
 		g.PrintStyled("You no longer feel exhausted.", logStatusEnd)
 		g.Player.Statuses[StatusExhausted] = 0
-		g.ui.StatusEndAnimation()
+		g.Animations().StatusEndAnimation()
 	case HasteEnd:
 		g.Player.Statuses[StatusSwift]--
 		if g.Player.Statuses[StatusSwift] == 0 {
 			g.PrintStyled("You no longer feel speedy.", logStatusEnd)
-			g.ui.StatusEndAnimation()
+			g.Animations().StatusEndAnimation()
 		}
 	case EvasionEnd:
 		g.Player.Statuses[StatusAgile]--
 		if g.Player.Statuses[StatusAgile] == 0 {
 			g.PrintStyled("You no longer feel agile.", logStatusEnd)
-			g.ui.StatusEndAnimation()
+			g.Animations().StatusEndAnimation()
 		}
 	case LignificationEnd:
 		g.Player.Statuses[StatusLignification]--
 		g.Player.HP -= int(10 * g.Player.HP / Max(g.Player.HPMax(), g.Player.HP))
+		g.Killer = "lignification"
 		if g.Player.Statuses[StatusLignification] == 0 {
 			g.PrintStyled("You no longer feel attached to the ground.", logStatusEnd)
-			g.ui.StatusEndAnimation()
+			g.Animations().StatusEndAnimation()
 		}
 	case ConfusionEnd:
 		g.PrintStyled("You no longer feel confused.", logStatusEnd)
 		g.Player.Statuses[StatusConfusion] = 0
-		g.ui.StatusEndAnimation()
+		g.Animations().StatusEndAnimation()
 	case NauseaEnd:
 		g.PrintStyled("You no longer feel sick.", logStatusEnd)
 		g.Player.Statuses[StatusNausea] = 0
-		g.ui.StatusEndAnimation()
+		g.Animations().StatusEndAnimation()
 	case DisabledShieldEnd:
 		g.PrintStyled("You manage to dislodge the projectile from your shield.", logStatusEnd)
 		g.Player.Statuses[StatusDisabledShield] = 0
-		g.ui.StatusEndAnimation()
+		g.Animations().StatusEndAnimation()
 	case CorrosionEnd:
 		g.Player.Statuses[StatusCorrosion]--
 		if g.Player.Statuses[StatusCorrosion] == 0 {
 			g.PrintStyled("Your equipment is now free from acid.", logStatusEnd)
-			g.ui.StatusEndAnimation()
+			g.Animations().StatusEndAnimation()
 		}
 	case DigEnd:
 		g.Player.Statuses[StatusDig]--
 		if g.Player.Statuses[StatusDig] == 0 {
 			g.PrintStyled("You no longer feel like an earth dragon.", logStatusEnd)
-			g.ui.StatusEndAnimation()
+			g.Animations().StatusEndAnimation()
 		}
 	case SwapEnd:
 		g.Player.Statuses[StatusSwap]--
 		if g.Player.Statuses[StatusSwap] == 0 {
 			g.PrintStyled("You no longer feel light-footed.", logStatusEnd)
-			g.ui.StatusEndAnimation()
+			g.Animations().StatusEndAnimation()
 		}
 	case ShadowsEnd:
 		g.Player.Statuses[StatusShadows]--
 		if g.Player.Statuses[StatusShadows] == 0 {
 			g.PrintStyled("The shadows leave you.", logStatusEnd)
-			g.ui.StatusEndAnimation()
+			g.Animations().StatusEndAnimation()
 			g.ComputeLOS()
 			g.MakeMonstersAware()
 		}
@@ -203,7 +209,7 @@ func (sev *simpleEvent) Action(g *game) {
 		g.Player.Statuses[StatusSlay]--
 		if g.Player.Statuses[StatusSlay] == 0 {
 			g.PrintStyled("You no longer feel extra slaying power.", logStatusEnd)
-			g.ui.StatusEndAnimation()
+			g.Animations().StatusEndAnimation()
 			g.ComputeLOS()
 			g.MakeMonstersAware()
 		}
@@ -211,7 +217,7 @@ func (sev *simpleEvent) Action(g *game) {
 		g.Player.Statuses[StatusAccurate]--
 		if g.Player.Statuses[StatusAccurate] == 0 {
 			g.PrintStyled("You no longer feel accurate.", logStatusEnd)
-			g.ui.StatusEndAnimation()
+			g.Animations().StatusEndAnimation()
 		}
 	case BlockEnd:
 		g.Player.Blocked = false
@@ -333,20 +339,20 @@ func (cev *cloudEvent) Action(g *game) {
 			g.Printf("You see a wall appear out of thin air.")
 			g.StopAuto()
 		}
-		g.PushEvent(&cloudEvent{ERank: cev.Rank() + 200 + RandInt(50), EAction: ObstructionProgression})
+		g.PushEvent(&cloudEvent{ERank: cev.Rank() + 200 + g.RandInt(50), EAction: ObstructionProgression})
 	case FireProgression:
 		if _, ok := g.Clouds[cev.Pos]; !ok {
 			break
 		}
 		g.BurnCreature(cev.Pos, cev)
-		if RandInt(10) == 0 {
+		if g.RandInt(10) == 0 {
 			delete(g.Clouds, cev.Pos)
 			g.Fog(cev.Pos, 1, &simpleEvent{ERank: cev.Rank()})
 			g.ComputeLOS()
 			break
 		}
 		for _, pos := range g.Dungeon.FreeNeighbors(cev.Pos) {
-			if RandInt(3) > 0 {
+			if g.RandInt(3) > 0 {
 				continue
 			}
 			g.Burn(pos, cev)
@@ -357,7 +363,7 @@ func (cev *cloudEvent) Action(g *game) {
 			break
 		}
 		g.MakeCreatureSleep(cev.Pos, cev)
-		if RandInt(20) == 0 {
+		if g.RandInt(20) == 0 {
 			delete(g.Clouds, cev.Pos)
 			g.ComputeLOS()
 			break
@@ -369,12 +375,12 @@ func (cev *cloudEvent) Action(g *game) {
 func (g *game) MakeCreatureSleep(pos position, ev event) {
 	if pos == g.Player.Pos {
 		g.Player.Statuses[StatusSlow]++
-		g.PushEvent(&simpleEvent{ERank: ev.Rank() + 30 + RandInt(10), EAction: SlowEnd})
+		g.PushEvent(&simpleEvent{ERank: ev.Rank() + 30 + g.RandInt(10), EAction: SlowEnd})
 		g.Print("The clouds of night make you sleepy.")
 		return
 	}
 	mons := g.MonsterAt(pos)
-	if !mons.Exists() || (RandInt(2) == 0 && mons.Status(MonsExhausted)) {
+	if !mons.Exists() || (g.RandInt(2) == 0 && mons.Status(MonsExhausted)) {
 		// do not always make already exhausted monsters sleep (they were probably awaken)
 		return
 	}
@@ -382,13 +388,13 @@ func (g *game) MakeCreatureSleep(pos position, ev event) {
 		g.Printf("%s falls asleep.", mons.Kind.Definite(true))
 	}
 	mons.State = Resting
-	mons.ExhaustTime(g, 40+RandInt(10))
+	mons.ExhaustTime(g, 40+g.RandInt(10))
 }
 
 func (g *game) BurnCreature(pos position, ev event) {
 	mons := g.MonsterAt(pos)
 	if mons.Exists() {
-		mons.HP -= 1 + RandInt(10)
+		mons.HP -= 1 + g.RandInt(10)
 		if mons.HP <= 0 {
 			if g.Player.LOS[mons.Pos] {
 				g.PrintfStyled("%s is killed by the fire.", logPlayerHit, mons.Kind.Definite(true))
@@ -399,11 +405,12 @@ func (g *game) BurnCreature(pos position, ev event) {
 		}
 	}
 	if pos == g.Player.Pos {
-		damage := 1 + RandInt(10)
+		damage := 1 + g.RandInt(10)
 		if damage > g.Player.HP {
-			damage = 1 + RandInt(10)
+			damage = 1 + g.RandInt(10)
 		}
 		g.Player.HP -= damage
+		g.Killer = "fire"
 		g.PrintfStyled("The fire burns you (%d dmg).", logMonsterHit, damage)
 		if g.Player.HP+damage < 10 {
 			g.Stats.TimesLucky++
@@ -3,6 +3,7 @@ package main
 import (
 	"container/heap"
 	"fmt"
+	"time"
 )
 
 var Version string = "v0.14-dev"
@@ -57,6 +58,7 @@ type game struct {
 	DrawBuffer          []UICell
 	drawBackBuffer      []UICell
 	DrawLog             []drawFrame
+	ReplayFrames        int
 	Log                 []logEntry
 	LogIndex            int
 	LogNextTick         int
@@ -68,7 +70,22 @@ type game struct {
 	WizardMap           bool
 	Version             string
 	Opts                startOpts
-	ui                  *gameui
+	Seed                int64
+	Daily               string // date of the daily challenge, if any
+	Killer              string // last monster or hazard that hurt the player
+	Rand                *rng
+	InputRecord         inputRecord
+	inputReplay         *inputReplayer
+	noIO                bool // do not write any files (e.g. input replay)
+	replay              *replayRecorder
+	morgueName          string
+	outcome             string // outcome of a finished game (see EndGame)
+	hangup              bool   // input closed: save and quit at next player turn
+	ui                  engineUI
+	controller          PlayerController
+	controllerTarget    position           // target of the current controller action
+	controllerErr       error              // why the player controller ended the game
+	dijkstraMap         [DungeonNCells]int // distances of the last AutoExploreDijkstra
 }
 
 type startOpts struct {
@@ -86,8 +103,8 @@ func (g *game) FreeCell() position {
 		if count > 1000 {
 			panic("FreeCell")
 		}
-		x := RandInt(DungeonWidth)
-		y := RandInt(DungeonHeight)
+		x := g.RandInt(DungeonWidth)
+		y := g.RandInt(DungeonHeight)
 		pos := position{x, y}
 		c := d.Cell(pos)
 		if c.T != FreeCell {
@@ -123,7 +140,7 @@ func (g *game) FreeCellForStair(dist int) position {
 		pos := g.FreeCellForStatic()
 		adjust := 0
 		for i := 0; i < 4; i++ {
-			adjust += RandInt(dist)
+			adjust += g.RandInt(dist)
 		}
 		adjust /= 4
 		if pos.Distance(g.Player.Pos) <= 6+adjust {
@@ -147,8 +164,8 @@ func (g *game) FreeCellForStatic() position {
 		if count > 1000 {
 			panic("FreeCellForStatic")
 		}
-		x := RandInt(DungeonWidth)
-		y := RandInt(DungeonHeight)
+		x := g.RandInt(DungeonWidth)
+		y := g.RandInt(DungeonHeight)
 		pos := position{x, y}
 		c := d.Cell(pos)
 		if c.T != FreeCell {
@@ -194,8 +211,8 @@ func (g *game) FreeCellForMonster() position {
 		if count > 1000 {
 			panic("FreeCellForMonster")
 		}
-		x := RandInt(DungeonWidth)
-		y := RandInt(DungeonHeight)
+		x := g.RandInt(DungeonWidth)
+		y := g.RandInt(DungeonHeight)
 		pos := position{x, y}
 		c := d.Cell(pos)
 		if c.T != FreeCell {
@@ -220,7 +237,7 @@ func (g *game) FreeCellForBandMonster(pos position) position {
 			return g.FreeCellForMonster()
 		}
 		neighbors := g.Dungeon.FreeNeighbors(pos)
-		r := RandInt(len(neighbors))
+		r := g.RandInt(len(neighbors))
 		pos = neighbors[r]
 		if g.Player != nil && g.Player.Pos.Distance(pos) < 8 {
 			continue
@@ -241,8 +258,8 @@ func (g *game) FreeForStairs() position {
 		if count > 1000 {
 			panic("FreeForStairs")
 		}
-		x := RandInt(DungeonWidth)
-		y := RandInt(DungeonHeight)
+		x := g.RandInt(DungeonWidth)
+		y := g.RandInt(DungeonHeight)
 		pos := position{x, y}
 		c := d.Cell(pos)
 		if c.T != FreeCell {
@@ -269,7 +286,7 @@ func (g *game) GenDungeon() {
 	g.Fungus = make(map[position]vegetation)
 	for {
 		dg := GenRuinsMap
-		switch RandInt(7) {
+		switch g.RandInt(7) {
 		//switch 4 {
 		case 0:
 			dg = GenCaveMap
@@ -282,7 +299,7 @@ func (g *game) GenDungeon() {
 		case 4:
 			dg = GenBSPMap
 		}
-		if g.Depth > 1 && dg.String() == g.Stats.DLayout[g.Depth-1] && RandInt(4) > 0 {
+		if g.Depth > 1 && dg.String() == g.Stats.DLayout[g.Depth-1] && g.RandInt(4) > 0 {
 			// avoid too often the same layout in a row
 			continue
 		}
@@ -301,7 +318,7 @@ func (g *game) InitPlayer() {
 	g.Player.Consumables = map[consumable]int{
 		HealWoundsPotion: 1,
 	}
-	switch RandInt(7) {
+	switch g.RandInt(7) {
 	case 0:
 		g.Player.Consumables[ExplosiveMagara] = 1
 	case 1:
@@ -315,7 +332,7 @@ func (g *game) InitPlayer() {
 	default:
 		g.Player.Consumables[ConfusingDart] = 2
 	}
-	switch RandInt(12) {
+	switch g.RandInt(12) {
 	case 0, 1:
 		g.Player.Consumables[TeleportationPotion] = 1
 	case 2, 3:
@@ -394,18 +411,18 @@ func (g *game) InitPlayer() {
 
 func (g *game) InitSpecialBands() {
 	g.Opts.SpecialBands = map[int][]monsterBandData{}
-	sb := MonsSpecialBands[RandInt(len(MonsSpecialBands))]
-	depth := sb.minDepth + RandInt(sb.maxDepth-sb.minDepth+1)
+	sb := MonsSpecialBands[g.RandInt(len(MonsSpecialBands))]
+	depth := sb.minDepth + g.RandInt(sb.maxDepth-sb.minDepth+1)
 	g.Opts.SpecialBands[depth] = sb.bands
-	seb := MonsSpecialEndBands[RandInt(len(MonsSpecialEndBands))]
-	if RandInt(4) == 0 {
-		if RandInt(5) > 1 || depth == WinDepth {
+	seb := MonsSpecialEndBands[g.RandInt(len(MonsSpecialEndBands))]
+	if g.RandInt(4) == 0 {
+		if g.RandInt(5) > 1 || depth == WinDepth {
 			g.Opts.SpecialBands[WinDepth+1] = seb.bands
 		} else {
 			g.Opts.SpecialBands[WinDepth] = seb.bands
 		}
-	} else if RandInt(5) > 0 {
-		if RandInt(3) > 0 {
+	} else if g.RandInt(5) > 0 {
+		if g.RandInt(3) > 0 {
 			g.Opts.SpecialBands[MaxDepth] = seb.bands
 		} else {
 			g.Opts.SpecialBands[MaxDepth-1] = seb.bands
@@ -423,7 +440,17 @@ const (
 	GenExtraCollectables
 )
 
+// InitRand initializes the game's random source from g.Seed, choosing a
+// new seed first if none was given.
+func (g *game) InitRand() {
+	if g.Seed == 0 {
+		g.Seed = time.Now().UnixNano()
+	}
+	g.Rand = newRng(g.Seed)
+}
+
 func (g *game) InitFirstLevel() {
+	g.InitRand()
 	g.Depth++ // start at 1
 	g.InitPlayer()
 	g.AutoTarget = InvalidPos
@@ -434,22 +461,22 @@ func (g *game) InitFirstLevel() {
 	g.GeneratedUniques = map[monsterBand]int{}
 	g.Stats.KilledMons = map[monsterKind]int{}
 	g.InitSpecialBands()
-	if RandInt(4) > 0 {
-		g.Opts.UnstableLevel = 1 + RandInt(MaxDepth)
+	if g.RandInt(4) > 0 {
+		g.Opts.UnstableLevel = 1 + g.RandInt(MaxDepth)
 	}
 	if g.Opts.UnstableLevel >= 1 && g.Opts.UnstableLevel <= 3 {
 		// it should happen less often in the first levels
-		g.Opts.UnstableLevel += RandInt(MaxDepth - 2)
+		g.Opts.UnstableLevel += g.RandInt(MaxDepth - 2)
 	}
-	if RandInt(3) > 0 || RandInt(2) == 0 && g.Opts.UnstableLevel == 0 {
-		g.Opts.StoneLevel = 1 + RandInt(MaxDepth)
+	if g.RandInt(3) > 0 || g.RandInt(2) == 0 && g.Opts.UnstableLevel == 0 {
+		g.Opts.StoneLevel = 1 + g.RandInt(MaxDepth)
 	}
 	if g.Opts.StoneLevel >= 1 && g.Opts.StoneLevel <= 3 {
-		g.Opts.StoneLevel += RandInt(MaxDepth - 2)
+		g.Opts.StoneLevel += g.RandInt(MaxDepth - 2)
 	}
-	if RandInt(3) == 0 {
+	if g.RandInt(3) == 0 {
 		g.Opts.Alternate = MonsTinyHarpy
-		if RandInt(10) == 0 {
+		if g.RandInt(10) == 0 {
 			g.Opts.Alternate = MonsWorm
 		}
 	}
@@ -467,14 +494,15 @@ func (g *game) InitFirstLevel() {
 		10: GenExtraCollectables,
 		11: GenExtraCollectables,
 	}
-	permi := RandInt(7)
+	permi := g.RandInt(7)
 	switch permi {
 	case 0, 1, 2, 3:
 		g.GenPlan[permi+1], g.GenPlan[permi+2] = g.GenPlan[permi+2], g.GenPlan[permi+1]
 	}
-	if RandInt(4) == 0 {
+	if g.RandInt(4) == 0 {
 		g.GenPlan[6], g.GenPlan[7] = g.GenPlan[7], g.GenPlan[6]
 	}
+	g.InitInputRecord()
 }
 
 func (g *game) InitLevel() {
@@ -543,8 +571,8 @@ func (g *game) InitLevel() {
 	// Stairs
 	g.Stairs = make(map[position]stair)
 	nstairs := 2
-	if RandInt(3) == 0 {
-		if RandInt(2) == 0 {
+	if g.RandInt(3) == 0 {
+		if g.RandInt(2) == 0 {
 			nstairs++
 		} else {
 			nstairs--
@@ -574,7 +602,7 @@ func (g *game) InitLevel() {
 	// Magical Stones
 	g.MagicalStones = map[position]stone{}
 	nstones := 1
-	switch RandInt(8) {
+	switch g.RandInt(8) {
 	case 0:
 		nstones = 0
 	case 1, 2, 3:
@@ -584,10 +612,10 @@ func (g *game) InitLevel() {
 	}
 	ustone := stone(0)
 	if g.Depth == g.Opts.StoneLevel {
-		ustone = stone(1 + RandInt(NumStones-1))
-		nstones = 10 + RandInt(3)
-		if RandInt(4) == 0 {
-			g.Opts.StoneLevel = g.Opts.StoneLevel + RandInt(MaxDepth-g.Opts.StoneLevel) + 1
+		ustone = stone(1 + g.RandInt(NumStones-1))
+		nstones = 10 + g.RandInt(3)
+		if g.RandInt(4) == 0 {
+			g.Opts.StoneLevel = g.Opts.StoneLevel + g.RandInt(MaxDepth-g.Opts.StoneLevel) + 1
 		}
 	}
 	for i := 0; i < nstones; i++ {
@@ -596,7 +624,7 @@ func (g *game) InitLevel() {
 		if ustone != stone(0) {
 			st = ustone
 		} else {
-			st = stone(1 + RandInt(NumStones-1))
+			st = stone(1 + g.RandInt(NumStones-1))
 		}
 		g.MagicalStones[pos] = st
 	}
@@ -607,7 +635,7 @@ func (g *game) InitLevel() {
 		pos := g.FreeCellForStatic()
 		const rounds = 5
 		for j := 0; j < rounds; j++ {
-			g.Simellas[pos] += 1 + RandInt(g.Depth+g.Depth*g.Depth/6)
+			g.Simellas[pos] += 1 + g.RandInt(g.Depth+g.Depth*g.Depth/6)
 		}
 		g.Simellas[pos] /= rounds
 		if g.Simellas[pos] == 0 {
@@ -650,15 +678,15 @@ func (g *game) InitLevel() {
 		g.CleanEvents()
 	}
 	for i := range g.Monsters {
-		g.PushEvent(&monsterEvent{ERank: g.Turn + RandInt(10), EAction: MonsterTurn, NMons: i})
+		g.PushEvent(&monsterEvent{ERank: g.Turn + g.RandInt(10), EAction: MonsterTurn, NMons: i})
 	}
 	if g.Depth == g.Opts.UnstableLevel {
 		g.PrintStyled("You sense magic instability on this level.", logSpecial)
 		for i := 0; i < 15; i++ {
-			g.PushEvent(&cloudEvent{ERank: g.Turn + 100 + RandInt(900), EAction: ObstructionProgression})
+			g.PushEvent(&cloudEvent{ERank: g.Turn + 100 + g.RandInt(900), EAction: ObstructionProgression})
 		}
-		if RandInt(4) == 0 {
-			g.Opts.UnstableLevel = g.Opts.UnstableLevel + RandInt(MaxDepth-g.Opts.UnstableLevel) + 1
+		if g.RandInt(4) == 0 {
+			g.Opts.UnstableLevel = g.Opts.UnstableLevel + g.RandInt(MaxDepth-g.Opts.UnstableLevel) + 1
 		}
 	}
 }
@@ -684,6 +712,7 @@ func (g *game) StairsSlice() []position {
 			stairs = append(stairs, stairPos)
 		}
 	}
+	sortPositions(stairs)
 	return stairs
 }
 
@@ -694,15 +723,16 @@ func (g *game) GenCollectable() {
 	}
 	for {
 	loopcons:
-		for c, data := range ConsumablesCollectData {
-			r := RandInt(data.rarity * rounds)
+		for _, c := range ConsumablesCollectOrder {
+			data := ConsumablesCollectData[c]
+			r := g.RandInt(data.rarity * rounds)
 			if r != 0 {
 				continue
 			}
 
 			// avoid too many of the same
 			for _, co := range g.LastConsumables {
-				if co == c && RandInt(4) > 0 {
+				if co == c && g.RandInt(4) > 0 {
 					continue loopcons
 				}
 			}
@@ -719,10 +749,10 @@ func (g *game) GenCollectable() {
 func (g *game) GenCollectables() {
 	score := g.CollectableScore - 2*(g.Depth-1)
 	n := 2
-	if score >= 0 && RandInt(4) == 0 {
+	if score >= 0 && g.RandInt(4) == 0 {
 		n--
 	}
-	if score <= 0 && RandInt(4) == 0 {
+	if score <= 0 && g.RandInt(4) == 0 {
 		n++
 	}
 	if score > 0 && n >= 2 {
@@ -739,7 +769,7 @@ func (g *game) GenCollectables() {
 func (g *game) GenShield() {
 	ars := [4]shield{ConfusingShield, BashingShield, EarthShield, FireShield}
 	for {
-		i := RandInt(len(ars))
+		i := g.RandInt(len(ars))
 		if g.GeneratedEquipables[ars[i]] {
 			// do not generate duplicates
 			continue
@@ -754,7 +784,7 @@ func (g *game) GenShield() {
 func (g *game) GenArmour() {
 	ars := [6]armour{SmokingScales, ShinyPlates, TurtlePlates, SpeedRobe, CelmistRobe, HarmonistRobe}
 	for {
-		i := RandInt(len(ars))
+		i := g.RandInt(len(ars))
 		if g.GeneratedEquipables[ars[i]] {
 			// do not generate duplicates
 			continue
@@ -770,7 +800,7 @@ func (g *game) GenWeapon() {
 	wps := [WeaponNum - 1]weapon{Axe, BattleAxe, Spear, Halberd, AssassinSabre, DancingRapier, HopeSword, Frundis, ElecWhip, HarKarGauntlets, VampDagger, DragonSabre, FinalBlade, DefenderFlail}
 	onehanded := false
 	for {
-		i := RandInt(len(wps))
+		i := g.RandInt(len(wps))
 		if g.GeneratedEquipables[wps[i]] {
 			// do not generate duplicates
 			continue
@@ -803,6 +833,7 @@ func (g *game) Descend() bool {
 		g.StoryPrint("Escaped!")
 		g.ExploredLevels = g.Depth
 		g.Depth = -1
+		g.EndGame("escaped")
 		return true
 	}
 	g.Print("You descend deeper in the dungeon.")
@@ -836,8 +867,8 @@ func (g *game) ApplyRest() {
 		// the harmonist robe mitigates the sound of your snorts
 		adjust = 100
 	}
-	if g.DepthPlayerTurn < 100+adjust && RandInt(5) > 2 || g.DepthPlayerTurn >= 100+adjust && g.DepthPlayerTurn < 250+adjust && RandInt(2) == 0 ||
-		g.DepthPlayerTurn >= 250+adjust && RandInt(3) > 0 {
+	if g.DepthPlayerTurn < 100+adjust && g.RandInt(5) > 2 || g.DepthPlayerTurn >= 100+adjust && g.DepthPlayerTurn < 250+adjust && g.RandInt(2) == 0 ||
+		g.DepthPlayerTurn >= 250+adjust && g.RandInt(3) > 0 {
 		rmons := []int{}
 		for i, mons := range g.Monsters {
 			if mons.Exists() && mons.State == Resting {
@@ -845,7 +876,7 @@ func (g *game) ApplyRest() {
 			}
 		}
 		if len(rmons) > 0 {
-			g.Monsters[rmons[RandInt(len(rmons))]].NaturalAwake(g)
+			g.Monsters[rmons[g.RandInt(len(rmons))]].NaturalAwake(g)
 		}
 	}
 	g.Stats.Rest++
@@ -933,10 +964,16 @@ loop:
 				g.Player.HP = g.Player.HPMax()
 			} else {
 				g.LevelStats()
+				if g.Killer != "" {
+					g.StoryPrintf("Killed by %s", g.Killer)
+				} else {
+					g.StoryPrint("Died")
+				}
 				err := g.RemoveSaveFile()
 				if err != nil {
 					g.PrintfStyled("Error removing save file: %v", logError, err.Error())
 				}
+				g.EndGame("died")
 				g.ui.Death()
 				break loop
 			}
@@ -1,6 +1,9 @@
 package main
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestInitLevel(t *testing.T) {
 	for i := 0; i < 10; i++ {
@@ -16,3 +19,47 @@ func TestInitLevel(t *testing.T) {
 		}
 	}
 }
+
+func genLevels(seed int64) []*game {
+	g := &game{Seed: seed}
+	levels := []*game{}
+	for depth := 0; depth < MaxDepth; depth++ {
+		g.Depth = depth
+		g.InitLevel()
+		lg := *g
+		lg.Dungeon = &dungeon{Cells: append([]cell{}, g.Dungeon.Cells...)}
+		levels = append(levels, &lg)
+	}
+	return levels
+}
+
+func TestSeed(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		l1 := genLevels(seed)
+		l2 := genLevels(seed)
+		for depth, g1 := range l1 {
+			g2 := l2[depth]
+			if !reflect.DeepEqual(g1.Dungeon.Cells, g2.Dungeon.Cells) {
+				t.Fatalf("seed %d, depth %d: different dungeons", seed, depth)
+			}
+			if !reflect.DeepEqual(g1.Monsters, g2.Monsters) {
+				t.Fatalf("seed %d, depth %d: different monsters", seed, depth)
+			}
+			if !reflect.DeepEqual(g1.Collectables, g2.Collectables) {
+				t.Fatalf("seed %d, depth %d: different collectables", seed, depth)
+			}
+			if !reflect.DeepEqual(g1.Equipables, g2.Equipables) || !reflect.DeepEqual(g1.Rods, g2.Rods) {
+				t.Fatalf("seed %d, depth %d: different equipment", seed, depth)
+			}
+			if !reflect.DeepEqual(g1.Stairs, g2.Stairs) || !reflect.DeepEqual(g1.MagicalStones, g2.MagicalStones) {
+				t.Fatalf("seed %d, depth %d: different stairs or stones", seed, depth)
+			}
+			if !reflect.DeepEqual(g1.Player, g2.Player) {
+				t.Fatalf("seed %d, depth %d: different player", seed, depth)
+			}
+			if !reflect.DeepEqual(g1.Opts, g2.Opts) || g1.GenPlan != g2.GenPlan {
+				t.Fatalf("seed %d, depth %d: different game options", seed, depth)
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// +build ignore
+
+// This program generates htmldumpstyle.go from assets/style.css, so that
+// HTML dumps use the same style as the browser version. Run it with go
+// generate.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+func main() {
+	style, err := ioutil.ReadFile("assets/style.css")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if strings.Contains(string(style), "`") {
+		log.Fatal("assets/style.css contains a backquote")
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by gendumpstyle.go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package main\n\n")
+	fmt.Fprintf(buf, "// htmlDumpStyle is the content of assets/style.css.\n")
+	fmt.Fprintf(buf, "const htmlDumpStyle = `%s`\n", style)
+	err = ioutil.WriteFile("htmldumpstyle.go", buf.Bytes(), 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,110 @@
+// +build gif tk web
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// gifMaxDelay is the maximum delay of a GIF frame, in 100ths of second,
+// so that long pauses of the player do not show.
+const gifMaxDelay = 200
+
+type gifRenderer struct {
+	opts   gifOptions
+	pal    color.Palette
+	cache  map[UICell]*image.Paletted
+	tw, th int
+}
+
+func (gr *gifRenderer) Tile(c UICell) *image.Paletted {
+	c.Fg = Map256ColorTo16(c.Fg)
+	c.Bg = Map256ColorTo16(c.Bg)
+	if img, ok := gr.cache[c]; ok {
+		return img
+	}
+	rgba := TileImage(c, gr.opts.Tiles)
+	img := image.NewPaletted(rgba.Bounds(), gr.pal)
+	draw.Draw(img, img.Bounds(), rgba, image.Point{}, draw.Src)
+	gr.cache[c] = img
+	return img
+}
+
+// WriteGIF renders replay frames with tiles into an animated GIF. Only the
+// part of the image that changed is encoded for each frame.
+func WriteGIF(w io.Writer, frames []drawFrame, opts gifOptions) error {
+	gr := &gifRenderer{opts: opts, cache: map[UICell]*image.Paletted{}}
+	for c := uicolor(0); c < 16; c++ {
+		gr.pal = append(gr.pal, c.Color())
+	}
+	tile := gr.Tile(UICell{R: ' '})
+	gr.tw, gr.th = tile.Bounds().Dx(), tile.Bounds().Dy()
+	width, height := 80, 24
+	for _, df := range frames {
+		for _, dr := range df.Draws {
+			width = Max(width, dr.X+1)
+			height = Max(height, dr.Y+1)
+		}
+	}
+	end := opts.End
+	if end <= 0 || end >= len(frames) {
+		end = len(frames) - 1
+	}
+	canvas := image.NewPaletted(image.Rect(0, 0, width*gr.tw, height*gr.th), gr.pal)
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{gr.pal[Color16Base03]}, image.Point{}, draw.Src)
+	anim := &gif.GIF{Config: image.Config{ColorModel: gr.pal, Width: canvas.Bounds().Dx(), Height: canvas.Bounds().Dy()}}
+	minInterval := time.Duration(0)
+	if opts.MaxFPS > 0 {
+		minInterval = time.Second / time.Duration(opts.MaxFPS)
+	}
+	var dirty image.Rectangle
+	var last time.Time
+	emit := func(t time.Time) {
+		if len(anim.Image) > 0 {
+			delay := int(t.Sub(last) / (10 * time.Millisecond))
+			if delay > gifMaxDelay {
+				delay = gifMaxDelay
+			}
+			anim.Delay[len(anim.Delay)-1] = delay
+		}
+		if len(anim.Image) == 0 {
+			dirty = canvas.Bounds()
+		}
+		img := image.NewPaletted(dirty, gr.pal)
+		draw.Draw(img, dirty, canvas, dirty.Min, draw.Src)
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, 10)
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+		dirty = image.Rectangle{}
+		last = t
+	}
+	for i := 0; i <= end; i++ {
+		df := frames[i]
+		for _, dr := range df.Draws {
+			r := image.Rect(dr.X*gr.tw, dr.Y*gr.th, (dr.X+1)*gr.tw, (dr.Y+1)*gr.th)
+			draw.Draw(canvas, r, gr.Tile(dr.Cell), image.Point{}, draw.Src)
+			dirty = dirty.Union(r)
+		}
+		if i < opts.Start || dirty.Empty() && len(anim.Image) > 0 {
+			continue
+		}
+		t := df.Time
+		if t.IsZero() {
+			// no timing information
+			t = time.Unix(0, 0).Add(time.Duration(i) * 100 * time.Millisecond)
+		}
+		if len(anim.Image) > 0 && t.Sub(last) < minInterval && i != end {
+			continue
+		}
+		emit(t)
+	}
+	if len(anim.Image) == 0 {
+		emit(time.Time{})
+	}
+	return gif.EncodeAll(w, anim)
+}
@@ -0,0 +1,12 @@
+// +build !gif,!tk,!web,!js
+
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+func WriteGIF(w io.Writer, frames []drawFrame, opts gifOptions) error {
+	return errors.New("GIF export needs a build with the gif tag")
+}
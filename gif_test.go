@@ -0,0 +1,59 @@
+// +build gif tk web
+
+package main
+
+import (
+	"bytes"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+func TestWriteGIF(t *testing.T) {
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	frames := []drawFrame{
+		{Time: start, Draws: []cellDraw{
+			{Cell: UICell{R: '@', Fg: Color16Blue, Bg: Color16Base03, InMap: true}, X: 3, Y: 2},
+		}},
+		{Time: start.Add(20 * time.Millisecond), Draws: []cellDraw{
+			{Cell: UICell{R: '.', Fg: Color16Base0, Bg: Color16Base03, InMap: true}, X: 3, Y: 2},
+		}},
+		{Time: start.Add(10 * time.Second), Draws: []cellDraw{
+			{Cell: UICell{R: 'g', Fg: Color16Red, Bg: Color16Base03, InMap: true}, X: 5, Y: 2},
+		}},
+	}
+	for _, tiles := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		if err := WriteGIF(buf, frames, gifOptions{MaxFPS: 10, Tiles: tiles}); err != nil {
+			t.Fatal(err)
+		}
+		anim, err := gif.DecodeAll(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(anim.Image) != 2 {
+			t.Fatalf("bad number of frames: %d", len(anim.Image))
+		}
+		if anim.Delay[0] != gifMaxDelay {
+			t.Errorf("bad delay: %d", anim.Delay[0])
+		}
+		if anim.Image[0].Bounds().Dx() != anim.Config.Width || anim.Image[0].Bounds().Dy() != anim.Config.Height {
+			t.Errorf("bad first frame size: %v", anim.Image[0].Bounds())
+		}
+		if anim.Image[1].Bounds().Dx() != anim.Config.Width/80*3 {
+			// only the changed part from x = 3 to x = 5
+			t.Errorf("bad second frame size: %v", anim.Image[1].Bounds())
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteGIF(buf, frames, gifOptions{Start: 1, End: 1}); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 1 {
+		t.Errorf("bad number of frames: %d", len(anim.Image))
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import "errors"
+
+// engineUI is the set of user interface hooks called by the game engine.
+// It is implemented by gameui for normal play, and by headlessUI for
+// running games without any terminal or graphical backend. A user
+// interface can also implement animationUI and promptUI.
+type engineUI interface {
+	HandlePlayerTurn(ev event) bool
+	ExploreStep() bool
+	Death()
+}
+
+// animationUI is implemented by user interfaces that draw the map and
+// animations during the turns of the game.
+type animationUI interface {
+	DrawDungeonView(m uiMode)
+	DrinkingPotionAnimation()
+	ExplosionAnimation(es explosionStyle, pos position)
+	FireBoltAnimation(ray []position)
+	HitAnimation(pos position, targeting bool)
+	LightningHitAnimation(targets []position)
+	MagicMappingAnimation(border []int)
+	MonsterJavelinAnimation(ray []position, hit bool)
+	MonsterProjectileAnimation(ray []position, r rune, fg uicolor)
+	ProjectileTrajectoryAnimation(ray []position, fg uicolor)
+	SlowingMagaraAnimation(ray []position)
+	StatusEndAnimation()
+	SwappingAnimation(mpos, ppos position)
+	TeleportAnimation(from, to position, showto bool)
+	ThrowAnimation(ray []position, hit bool)
+	TormentExplosionAnimation()
+	WallExplosionAnimation(pos position)
+	WoundedAnimation()
+}
+
+// promptUI is implemented by user interfaces that can ask the player
+// something during a turn. Without it, targeting is always cancelled.
+type promptUI interface {
+	ChooseTarget(targ Targeter) error
+	CriticalHPWarning()
+}
+
+var (
+	_ animationUI = &gameui{}
+	_ promptUI    = &gameui{}
+)
+
+// Animations returns the animation hooks of the user interface, or hooks
+// that do nothing if it does not implement animationUI.
+func (g *game) Animations() animationUI {
+	if ui, ok := g.ui.(animationUI); ok {
+		return ui
+	}
+	return noAnimations{}
+}
+
+type noAnimations struct{}
+
+func (noAnimations) DrawDungeonView(m uiMode)                                      {}
+func (noAnimations) DrinkingPotionAnimation()                                      {}
+func (noAnimations) ExplosionAnimation(es explosionStyle, pos position)            {}
+func (noAnimations) FireBoltAnimation(ray []position)                              {}
+func (noAnimations) HitAnimation(pos position, targeting bool)                     {}
+func (noAnimations) LightningHitAnimation(targets []position)                      {}
+func (noAnimations) MagicMappingAnimation(border []int)                            {}
+func (noAnimations) MonsterJavelinAnimation(ray []position, hit bool)              {}
+func (noAnimations) MonsterProjectileAnimation(ray []position, r rune, fg uicolor) {}
+func (noAnimations) ProjectileTrajectoryAnimation(ray []position, fg uicolor)      {}
+func (noAnimations) SlowingMagaraAnimation(ray []position)                         {}
+func (noAnimations) StatusEndAnimation()                                           {}
+func (noAnimations) SwappingAnimation(mpos, ppos position)                         {}
+func (noAnimations) TeleportAnimation(from, to position, showto bool)              {}
+func (noAnimations) ThrowAnimation(ray []position, hit bool)                       {}
+func (noAnimations) TormentExplosionAnimation()                                    {}
+func (noAnimations) WallExplosionAnimation(pos position)                           {}
+func (noAnimations) WoundedAnimation()                                             {}
+
+// headlessUI runs the engine without drawing anything nor reading any
+// input. Player decisions are delegated to optional hooks, so that games
+// can be simulated in tests.
+type headlessUI struct {
+	g *game
+	// PlayerTurn handles a player turn, and returns true to end the game.
+	// If nil, the game ends at the first player turn.
+	PlayerTurn func(g *game, ev event) bool
+	// Target chooses a target for targeted items. If nil, targeting is
+	// always cancelled.
+	Target func(g *game, targ Targeter) error
+	// Record enables recording of hook names in Calls.
+	Record bool
+	Calls  []string
+	Dead   bool
+}
+
+// NewHeadlessGame returns a new game with given seed that uses a headless
+// ui and does not write any files.
+func NewHeadlessGame(seed int64) (*game, *headlessUI) {
+	g := &game{Seed: seed, noIO: true}
+	ui := &headlessUI{g: g}
+	g.ui = ui
+	g.InitLevel()
+	return g, ui
+}
+
+func (ui *headlessUI) record(call string) {
+	if ui.Record {
+		ui.Calls = append(ui.Calls, call)
+	}
+}
+
+func (ui *headlessUI) HandlePlayerTurn(ev event) bool {
+	ui.record("HandlePlayerTurn")
+	if ui.PlayerTurn == nil {
+		return true
+	}
+	return ui.PlayerTurn(ui.g, ev)
+}
+
+func (ui *headlessUI) ExploreStep() bool {
+	ui.record("ExploreStep")
+	return false
+}
+
+func (ui *headlessUI) ChooseTarget(targ Targeter) error {
+	ui.record("ChooseTarget")
+	if ui.Target == nil {
+		return errors.New(DoNothing)
+	}
+	return ui.Target(ui.g, targ)
+}
+
+func (ui *headlessUI) CriticalHPWarning() {
+	ui.record("CriticalHPWarning")
+}
+
+func (ui *headlessUI) Death() {
+	ui.record("Death")
+	ui.Dead = true
+}
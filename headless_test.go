@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHeadlessGame(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		g, ui := NewHeadlessGame(seed)
+		ui.Record = true
+		turns := 0
+		ui.PlayerTurn = func(g *game, ev event) bool {
+			turns++
+			if turns > 500 {
+				return true
+			}
+			if err := g.Autoexplore(ev); err != nil {
+				g.WaitTurn(ev)
+			}
+			return false
+		}
+		g.EventLoop()
+		if turns <= 500 && !ui.Dead {
+			t.Errorf("seed %d: game ended early after %d turns", seed, turns)
+		}
+		if len(ui.Calls) == 0 || ui.Calls[0] != "HandlePlayerTurn" {
+			t.Errorf("seed %d: player turn hook not called first", seed)
+		}
+	}
+}
+
+func TestKiller(t *testing.T) {
+	g, ui := NewHeadlessGame(1)
+	m := &monster{Kind: MonsCyclop}
+	m.InflictDamage(g, g.Player.HP, 15, m.DamageSource("rock"))
+	if g.Killer != "a cyclops's rock" {
+		t.Fatalf("bad killer: %q", g.Killer)
+	}
+	g.EventLoop()
+	if !ui.Dead {
+		t.Fatal("player did not die")
+	}
+	want := "Killed by a cyclops's rock at depth 1"
+	if !strings.Contains(g.Dump(), want) || !strings.Contains(g.SimplifedDump(nil), want) {
+		t.Errorf("death cause not in dumps")
+	}
+	if story := g.Stats.Story[len(g.Stats.Story)-1]; !strings.HasSuffix(story, "Killed by a cyclops's rock") {
+		t.Errorf("bad last story entry: %q", story)
+	}
+}
+
+type minimalUI struct{}
+
+func (ui *minimalUI) HandlePlayerTurn(ev event) bool { return true }
+func (ui *minimalUI) ExploreStep() bool              { return false }
+func (ui *minimalUI) Death()                         {}
+
+func TestMinimalUI(t *testing.T) {
+	// animations and prompts are optional
+	ui := &minimalUI{}
+	g := &game{Seed: 2, noIO: true, ui: ui}
+	g.InitLevel()
+	g.SetController(&limitedBot{max: 500})
+	g.EventLoop()
+	if g.Turn == 0 {
+		t.Errorf("game did not run")
+	}
+}
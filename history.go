@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Finished games are recorded in the "history" data file, one line per
+// run, with tab-separated fields.
+
+type runRecord struct {
+	Date     string
+	Version  string
+	Outcome  string // died, escaped or quit
+	Depth    int
+	Simellas int
+	Turns    int
+	Score    int
+	Wizard   bool
+	Killer   string
+}
+
+const maxHallOfFame = 15
+
+// Score computes the score of the current game:
+//
+//	10 × simellas + 500 × deepest level + 20 × killed monsters
+//	+ if escaped: 5000 + max(0, 10000 − turns) / 2
+//
+// Turns are counted as in run records. Wizard mode games have no score.
+func (g *game) Score() int {
+	if g.Wizard {
+		return 0
+	}
+	depth := Max(g.Depth, g.ExploredLevels)
+	score := 10*g.Player.Simellas + 500*depth + 20*g.Stats.Killed
+	if g.Depth == -1 {
+		score += 5000 + Max(0, 10000-g.Turn/10)/2
+	}
+	return score
+}
+
+func (g *game) NewRunRecord(outcome string, t time.Time) runRecord {
+	r := runRecord{
+		Date:     t.UTC().Format("2006-01-02 15:04"),
+		Version:  Version,
+		Outcome:  outcome,
+		Depth:    Max(g.Depth, g.ExploredLevels),
+		Simellas: g.Player.Simellas,
+		Turns:    g.Turn / 10,
+		Score:    g.Score(),
+		Wizard:   g.Wizard,
+	}
+	if outcome == "died" {
+		r.Killer = g.Killer
+	}
+	return r
+}
+
+func (r runRecord) String() string {
+	wizard := "-"
+	if r.Wizard {
+		wizard = "wizard"
+	}
+	return strings.Join([]string{r.Date, r.Version, r.Outcome, strconv.Itoa(r.Depth),
+		strconv.Itoa(r.Simellas), strconv.Itoa(r.Turns), strconv.Itoa(r.Score),
+		wizard, r.Killer}, "\t")
+}
+
+func ParseRunRecord(s string) (runRecord, error) {
+	var r runRecord
+	fields := strings.Split(s, "\t")
+	if len(fields) != 9 {
+		return r, fmt.Errorf("bad number of fields: %d", len(fields))
+	}
+	r.Date, r.Version, r.Outcome = fields[0], fields[1], fields[2]
+	nums := []*int{&r.Depth, &r.Simellas, &r.Turns, &r.Score}
+	for i, n := range nums {
+		var err error
+		*n, err = strconv.Atoi(fields[3+i])
+		if err != nil {
+			return r, err
+		}
+	}
+	r.Wizard = fields[7] == "wizard"
+	r.Killer = fields[8]
+	return r, nil
+}
+
+// RunHistory returns the recorded runs, in order. Invalid lines are
+// skipped.
+func (g *game) RunHistory() []runRecord {
+	data, err := g.ReadDataFile("history")
+	if err != nil {
+		// no game finished yet
+		return nil
+	}
+	runs := []runRecord{}
+	for _, l := range strings.Split(string(data), "\n") {
+		if l == "" {
+			continue
+		}
+		r, err := ParseRunRecord(l)
+		if err == nil {
+			runs = append(runs, r)
+		}
+	}
+	return runs
+}
+
+func (g *game) RecordRun(outcome string) {
+	err := g.AppendDataFile("history", []byte(g.NewRunRecord(outcome, time.Now()).String()+"\n"))
+	if err != nil {
+		g.PrintfStyled("Error recording game in history: %v", logError, err)
+	}
+}
+
+// EndGame records the outcome of a finished game.
+func (g *game) EndGame(outcome string) {
+	g.outcome = outcome
+	g.EndDaily(outcome)
+	g.RecordRun(outcome)
+}
+
+// HallOfFame returns the best non-wizard runs, by decreasing score.
+func HallOfFame(runs []runRecord) []runRecord {
+	best := []runRecord{}
+	for _, r := range runs {
+		if !r.Wizard {
+			best = append(best, r)
+		}
+	}
+	sort.SliceStable(best, func(i, j int) bool { return best[i].Score > best[j].Score })
+	if len(best) > maxHallOfFame {
+		best = best[:maxHallOfFame]
+	}
+	return best
+}
+
+func (r runRecord) Summary() string {
+	switch r.Outcome {
+	case "escaped":
+		return "escaped"
+	case "died":
+		if r.Killer != "" {
+			return fmt.Sprintf("killed by %s at depth %d", r.Killer, r.Depth)
+		}
+		return fmt.Sprintf("died at depth %d", r.Depth)
+	default:
+		return fmt.Sprintf("%s at depth %d", r.Outcome, r.Depth)
+	}
+}
+
+func (ui *gameui) HallOfFame() {
+	g := ui.g
+	ui.DrawBufferInit()
+	ui.Clear()
+	ui.DrawColoredText(fmt.Sprintf("Hall of fame [%s]", ProfileString(Profile)), 1, 1, ColorCyan)
+	best := HallOfFame(g.RunHistory())
+	if len(best) == 0 {
+		ui.DrawText("No game finished yet.", 1, 3)
+	} else {
+		ui.DrawColoredText(fmt.Sprintf("    %6s %4s %6s %-16s %s", "score", "♣", "turns", "date", "outcome"), 1, 3, ColorFgDark)
+	}
+	for i, r := range best {
+		ui.DrawText(fmt.Sprintf("%2d. %6d %4d %6d %-16s %s", i+1, r.Score, r.Simellas, r.Turns, r.Date, r.Summary()), 1, 4+i)
+	}
+	ui.DrawColoredText("───Press any key to continue───", 1, 6+maxHallOfFame, ColorFg)
+	ui.Flush()
+	ui.PressAnyKey()
+}
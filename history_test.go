@@ -0,0 +1,76 @@
+// +build !js
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boohu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xdg := os.Getenv("XDG_DATA_HOME")
+	defer os.Setenv("XDG_DATA_HOME", xdg)
+	os.Setenv("XDG_DATA_HOME", dir)
+
+	g := &game{Seed: 5}
+	g.InitLevel()
+	g.Player.Simellas = 30
+	g.Stats.Killed = 2
+	g.Killer = "an orc"
+	if score := g.Score(); score != 10*30+500*1+20*2 {
+		t.Errorf("bad score: %d", score)
+	}
+	r := g.NewRunRecord("died", time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+	lr, err := ParseRunRecord(r.String())
+	if err != nil || lr != r {
+		t.Errorf("bad run record round trip: %+v %v", lr, err)
+	}
+	g.RecordRun("died")
+	g.Player.Simellas = 50
+	g.RecordRun("quit")
+	g.Wizard = true
+	g.RecordRun("quit")
+	runs := g.RunHistory()
+	if len(runs) != 3 || runs[0].Killer != "an orc" || runs[1].Killer != "" {
+		t.Fatalf("bad history: %+v", runs)
+	}
+	best := HallOfFame(runs)
+	if len(best) != 2 || best[0].Simellas != 50 || best[1].Simellas != 30 {
+		t.Errorf("bad hall of fame: %+v", best)
+	}
+	// records are appended to existing contents
+	f := filepath.Join(dir, "boohu", "history")
+	data, err := ioutil.ReadFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(f, append(data, "invalid line\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g.Wizard = false
+	g.Depth = -1
+	g.ExploredLevels = 8
+	g.Turn = 4321 * 10
+	g.RecordRun("escaped")
+	data, err = ioutil.ReadFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "invalid line\n") {
+		t.Errorf("history contents rewritten")
+	}
+	runs = g.RunHistory()
+	r = runs[len(runs)-1]
+	if r.Score != 10*r.Simellas+500*r.Depth+20*2+5000+(10000-r.Turns)/2 {
+		t.Errorf("score cannot be computed from record: %+v", r)
+	}
+}
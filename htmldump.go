@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+)
+
+//go:generate go run gendumpstyle.go
+
+// htmlDumpMapStyle completes htmlDumpStyle for the map of the HTML dump.
+const htmlDumpMapStyle = `pre.map{
+	display:inline-block;
+	line-height:1.1;
+}
+`
+
+// positionDrawer is implemented by user interfaces that can tell how a
+// map position is drawn.
+type positionDrawer interface {
+	PositionDrawing(pos position) (r rune, fg, bg uicolor)
+}
+
+// HTMLDump returns a self-contained HTML page with the last seen map in
+// color, the timeline and the statistics of the game.
+func (g *game) HTMLDump() string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(buf, "<title>Boohu %s character file</title>\n<style>\n%s%s", Version, htmlDumpStyle, htmlDumpMapStyle)
+	// map colors follow the game palette
+	for c := uicolor(0); c < 16; c++ {
+		fmt.Fprintf(buf, "span.fg%d {color: %s}\nspan.bg%d {background-color: %s}\n", c, c.String(), c, c.String())
+	}
+	fmt.Fprintf(buf, "</style>\n</head>\n<body>\n")
+	fmt.Fprintf(buf, "<h1>Boohu %s character file</h1>\n", Version)
+	fmt.Fprintf(buf, "<p>%s</p>\n", html.EscapeString(g.OutcomeSentence()))
+	fmt.Fprintf(buf, "<pre class=\"map\">\n%s</pre>\n", g.HTMLMap())
+	fmt.Fprintf(buf, "<h2>Timeline</h2>\n<pre>\n%s\n</pre>\n", html.EscapeString(g.DumpStory()))
+	stats := &bytes.Buffer{}
+	g.DetailedStatistics(stats)
+	fmt.Fprintf(buf, "<h2>Statistics</h2>\n<pre>%s\n</pre>\n", html.EscapeString(stats.String()))
+	fmt.Fprintf(buf, "<details>\n<summary>Full character dump</summary>\n<pre>\n%s</pre>\n</details>\n", html.EscapeString(g.Dump()))
+	fmt.Fprintf(buf, "</body>\n</html>\n")
+	return buf.String()
+}
+
+func (g *game) OutcomeSentence() string {
+	switch g.Outcome() {
+	case "escaped":
+		return "Escaped from Hareka's Underground alive!"
+	case "died":
+		return g.DeathSummary() + "."
+	case "quit":
+		return fmt.Sprintf("Quit while exploring depth %d of Hareka's Underground.", g.Depth)
+	default:
+		return fmt.Sprintf("Exploring depth %d of Hareka's Underground.", g.Depth)
+	}
+}
+
+// HTMLMap returns the last seen map as HTML, with colors if the user
+// interface can provide them.
+func (g *game) HTMLMap() string {
+	pd, ok := g.ui.(positionDrawer)
+	if !ok {
+		return html.EscapeString(strings.Replace(g.DumpDungeon(), "│", "", -1))
+	}
+	buf := &bytes.Buffer{}
+	span := &bytes.Buffer{}
+	var fg, bg uicolor
+	flush := func() {
+		if span.Len() > 0 {
+			fmt.Fprintf(buf, "<span class=\"fg%d bg%d\">%s</span>", fg, bg, html.EscapeString(span.String()))
+			span.Reset()
+		}
+	}
+	for y := 0; y < DungeonHeight; y++ {
+		for x := 0; x < DungeonWidth; x++ {
+			r, cfg, cbg := pd.PositionDrawing(position{x, y})
+			cfg, cbg = Map256ColorTo16(cfg), Map256ColorTo16(cbg)
+			if cfg != fg || cbg != bg {
+				flush()
+				fg, bg = cfg, cbg
+			}
+			span.WriteRune(r)
+		}
+		flush()
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTMLDump(t *testing.T) {
+	g := runBot(4)
+	page := g.HTMLDump()
+	if !strings.Contains(page, "<pre class=\"map\">") || !strings.Contains(page, "Timeline") {
+		t.Errorf("bad HTML dump")
+	}
+	if strings.Contains(page, "<span class=") {
+		t.Errorf("colors without position drawing")
+	}
+	ui := &gameui{g: g}
+	g.ui = ui
+	LinkColors()
+	page = g.HTMLDump()
+	if !strings.Contains(page, "<span class=\"fg") || !strings.Contains(page, "span.fg4 {color: #268bd2}") {
+		t.Errorf("colors missing in HTML dump")
+	}
+	if strings.Count(g.HTMLMap(), "\n") != DungeonHeight {
+		t.Errorf("bad HTML map height")
+	}
+}
+
+func TestHTMLDumpStyle(t *testing.T) {
+	style, err := ioutil.ReadFile(filepath.Join("assets", "style.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(style) != htmlDumpStyle {
+		t.Errorf("htmldumpstyle.go is out of date with assets/style.css: run go generate")
+	}
+}
@@ -1,4 +1,4 @@
-// +build js tk
+// +build js tk web gif
 
 // font used for letters: source code pro
 
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"time"
+)
+
+// inputRecord contains everything needed to re-run a game from the start:
+// the game is generated again from the seed, and then every input the
+// player gave during the game is fed again to the engine. Contrary to the
+// frame replay of DrawLog, the game is simulated again, so it can be
+// rendered with any layout or backend.
+type inputRecord struct {
+	Version    string
+	Seed       int64
+	Opts       startOpts
+	Inputs     []recordedInput
+	Incomplete bool // game upgraded from an older save: inputs are missing
+}
+
+type inputKind int
+
+const (
+	InputEvent       inputKind = iota // menu input event returned by PollGameEvent
+	InputExploreStep                  // result of an ExploreStep
+	InputKeyAction                    // key action of normal or targeting mode
+	InputMapClick                     // mouse click in normal or targeting mode
+	InputMapPoint                     // mouse move in normal or targeting mode
+)
+
+// recordedInput is an exported version of uiInput, so that it can be
+// encoded, or of mapInput for inputs of normal and targeting mode, with
+// additional information about ExploreStep results.
+type recordedInput struct {
+	Kind   inputKind
+	Key    string
+	Mouse  bool
+	MouseX int
+	MouseY int
+	Button int
+	Stop   bool      // ExploreStep result
+	Rune   rune      // key of a key action, 0 for menu buttons
+	Action keyAction // key action, KeyNothing for unbound keys
+	Pos    position  // map position, InvalidPos outside the map
+}
+
+// mapInput is an input of normal or targeting mode, resolved from the
+// screen: a key action, or a mouse click or move on a map position
+// (InvalidPos outside the map). Contrary to uiInput, it does not depend on
+// the layout nor the key bindings.
+type mapInput struct {
+	kind   inputKind
+	rka    runeKeyAction
+	pos    position
+	button int
+}
+
+func (g *game) InitInputRecord() {
+	g.InputRecord = inputRecord{
+		Version: Version,
+		Seed:    g.Seed,
+		Opts:    g.Opts,
+	}
+}
+
+func (g *game) RecordInput(in recordedInput) {
+	if g.inputReplay != nil || g.InputRecord.Incomplete {
+		return
+	}
+	g.InputRecord.Inputs = append(g.InputRecord.Inputs, in)
+}
+
+// PollGameEvent returns the next input event during a game. Events are
+// recorded in the game's input record, or taken from it in input replay
+// mode.
+func (ui *gameui) PollGameEvent() (in uiInput) {
+	g := ui.g
+	if g.inputReplay != nil {
+		return g.inputReplay.NextEvent()
+	}
+	in = ui.PollEvent()
+	if in.interrupt || in.mouse && in.button == -1 {
+		// interruptions and mouse moves do not change the game state
+		return in
+	}
+	g.RecordInput(recordedInput{Kind: InputEvent, Key: in.key, Mouse: in.mouse, MouseX: in.mouseX, MouseY: in.mouseY, Button: in.button})
+	return in
+}
+
+// PollMapEvent returns the next input of normal or targeting mode. Inputs
+// are recorded resolved, so that they can be replayed in any layout and
+// with any key bindings. It returns false for inputs without effect, like
+// interruptions or mouse moves outside the map in normal mode, which are
+// not recorded.
+func (ui *gameui) PollMapEvent(targeting bool) (mapInput, bool) {
+	g := ui.g
+	if g.inputReplay != nil {
+		return g.inputReplay.NextMapInput(targeting)
+	}
+	in := ui.PollEvent()
+	var mi mapInput
+	switch {
+	case in.interrupt:
+		return mi, false
+	case in.key != "":
+		r := ui.KeyToRuneKeyAction(in)
+		if targeting && in.key == "Escape" {
+			r = '\x1b'
+		}
+		if r == 0 {
+			return mi, false
+		}
+		keys := GameConfig.RuneNormalModeKeys
+		if targeting {
+			keys = GameConfig.RuneTargetModeKeys
+		}
+		mi = mapInput{kind: InputKeyAction, rka: runeKeyAction{r: r, k: keys[r]}}
+	case !in.mouse:
+		return mi, false
+	case in.button == -1:
+		ui.HoverMenus(in.mouseX, in.mouseY)
+		mi = mapInput{kind: InputMapPoint, pos: ui.MapPosition(in.mouseX, in.mouseY, targeting)}
+		if !targeting && !mi.pos.valid() {
+			return mi, false
+		}
+	case in.button == 0 && in.mouseY == DungeonHeight:
+		mi = mapInput{kind: InputMapClick, pos: InvalidPos}
+		if m, ok := ui.WhichButton(in.mouseX); ok {
+			mi = mapInput{kind: InputKeyAction, rka: runeKeyAction{k: m.Key(g)}}
+		}
+	default:
+		mi = mapInput{kind: InputMapClick, pos: ui.MapPosition(in.mouseX, in.mouseY, targeting), button: in.button}
+	}
+	g.RecordInput(recordedInput{Kind: mi.kind, Rune: mi.rka.r, Action: mi.rka.k, Pos: mi.pos, Button: mi.button})
+	return mi, true
+}
+
+const inputReplayDelay = 100 * time.Millisecond
+
+// inputReplayer feeds the inputs of an input record to the engine.
+type inputReplayer struct {
+	ui     *gameui
+	record *inputRecord
+	index  int
+	speed  time.Duration
+	pause  bool
+	evch   chan repEvent
+	done   bool
+	quit   bool
+	err    error
+}
+
+func newInputReplayer(ui *gameui, rec *inputRecord) *inputReplayer {
+	ir := &inputReplayer{ui: ui, record: rec, speed: 1}
+	ir.evch = make(chan repEvent, 100)
+	go func() {
+		PollReplayKeyboardEvents(ui, ir.evch)
+	}()
+	return ir
+}
+
+// Wait waits for duration d (adjusted to the replay speed), handling replay
+// control events meanwhile.
+func (ir *inputReplayer) Wait(d time.Duration) {
+	for {
+		if ir.pause {
+			e := <-ir.evch
+			if ir.HandleControl(e) {
+				return
+			}
+			continue
+		}
+		t := time.NewTimer(d / ir.speed)
+		select {
+		case e := <-ir.evch:
+			t.Stop()
+			if ir.HandleControl(e) {
+				return
+			}
+		case <-t.C:
+			return
+		}
+	}
+}
+
+// HandleControl handles a replay control event. It returns true if the
+// replay should proceed to next input immediately.
+func (ir *inputReplayer) HandleControl(e repEvent) bool {
+	switch e {
+	case ReplayQuit:
+		ir.quit = true
+		ir.done = true
+		return true
+	case ReplayTogglePause:
+		ir.pause = !ir.pause
+	case ReplayNext:
+		return ir.pause
+	case ReplaySpeedMore:
+		ir.speed *= 2
+		if ir.speed > 16 {
+			ir.speed = 16
+		}
+	case ReplaySpeedLess:
+		ir.speed /= 2
+		if ir.speed < 1 {
+			ir.speed = 1
+		}
+	}
+	return false
+}
+
+// next returns the next recorded input, which should be of one of the
+// given kinds.
+func (ir *inputReplayer) next(kinds ...inputKind) (recordedInput, bool) {
+	if ir.done {
+		return recordedInput{}, false
+	}
+	if ir.index >= len(ir.record.Inputs) {
+		ir.done = true
+		return recordedInput{}, false
+	}
+	in := ir.record.Inputs[ir.index]
+	for _, k := range kinds {
+		if in.Kind == k {
+			ir.index++
+			return in, true
+		}
+	}
+	ir.err = errors.New("input replay out of sync with the game")
+	ir.done = true
+	return recordedInput{}, false
+}
+
+// NextEvent returns the next recorded input event. Once there are no more
+// inputs, it returns escape key events, so that the game exits any menu
+// and can end the replay at next player turn.
+func (ir *inputReplayer) NextEvent() uiInput {
+	if !ir.done {
+		ir.Wait(inputReplayDelay)
+	}
+	in, ok := ir.next(InputEvent)
+	if !ok {
+		return uiInput{key: "\x1b"}
+	}
+	return uiInput{key: in.Key, mouse: in.Mouse, mouseX: in.MouseX, mouseY: in.MouseY, button: in.Button}
+}
+
+// NextMapInput returns the next recorded input of normal or targeting
+// mode. Once there are no more inputs, it returns escape key actions in
+// targeting mode, and no input in normal mode, where the replay ends.
+func (ir *inputReplayer) NextMapInput(targeting bool) (mapInput, bool) {
+	if !ir.done {
+		ir.Wait(inputReplayDelay)
+	}
+	in, ok := ir.next(InputKeyAction, InputMapClick, InputMapPoint)
+	if !ok {
+		return mapInput{kind: InputKeyAction, rka: runeKeyAction{r: '\x1b'}}, targeting
+	}
+	return mapInput{kind: in.Kind, rka: runeKeyAction{r: in.Rune, k: in.Action}, pos: in.Pos, button: in.Button}, true
+}
+
+// NextExploreStep returns the recorded result of an ExploreStep.
+func (ir *inputReplayer) NextExploreStep() bool {
+	ir.Wait(10 * time.Millisecond)
+	in, ok := ir.next(InputExploreStep)
+	if !ok {
+		return true
+	}
+	return in.Stop
+}
+
+// ReplayInputRecord re-runs a game from an input record.
+func (ui *gameui) ReplayInputRecord(rec *inputRecord) error {
+	g := ui.g
+	if rec.Incomplete {
+		return errors.New("no input replay for games upgraded from an older version")
+	}
+	if rec.Version != Version {
+		return errors.New("input replay for another version: " + rec.Version)
+	}
+	ir := newInputReplayer(ui, rec)
+	if err := ui.RunInputReplay(ir); err != nil {
+		return err
+	}
+	if ir.err != nil {
+		g.PrintfStyled("Error: %v", logError, ir.err)
+	}
+	if ir.quit {
+		return ir.err
+	}
+	g.Print("End of input replay. [(q) to quit]")
+	ui.DrawDungeonView(NormalMode)
+	for e := range ir.evch {
+		if e == ReplayQuit {
+			break
+		}
+	}
+	return ir.err
+}
+
+// RunInputReplay generates the game of an input record and runs it with
+// the recorded inputs.
+func (ui *gameui) RunInputReplay(ir *inputReplayer) error {
+	g := ui.g
+	g.inputReplay = ir
+	g.noIO = true
+	g.Seed = ir.record.Seed
+	g.InitLevel()
+	if !reflect.DeepEqual(g.InputRecord.Opts, ir.record.Opts) {
+		return errors.New("input replay does not match the generated game")
+	}
+	ui.DrawBufferInit()
+	g.ui = ui
+	for {
+		g.EventLoop()
+		if !g.Quit || ir.done || g.Depth == -1 {
+			break
+		}
+		// saving and quitting was recorded: the game continued after
+		// loading
+		g.Quit = false
+	}
+	return nil
+}
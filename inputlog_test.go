@@ -0,0 +1,111 @@
+// +build ansi
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// newInputTestUI returns a user interface for g that draws nothing. Input
+// events can be sent to its channel, and the writer closes the input.
+func newInputTestUI(t *testing.T, g *game) (*gameui, *io.PipeWriter) {
+	r, w := io.Pipe()
+	ui := &gameui{g: g, in: r, out: ioutil.Discard}
+	if err := ui.Init(); err != nil {
+		t.Fatal(err)
+	}
+	g.ui = ui
+	return ui, w
+}
+
+func TestInputRecord(t *testing.T) {
+	defer func(c config, w, h int, centered, anim bool) {
+		GameConfig, UIWidth, UIHeight, CenteredCamera, DisableAnimations = c, w, h, centered, anim
+	}(GameConfig, UIWidth, UIHeight, CenteredCamera, DisableAnimations)
+	ApplyDefaultKeyBindings()
+	GameConfig.Small = false
+	UIWidth, UIHeight = 100, 26
+	CenteredCamera = false
+	DisableAnimations = true
+
+	g := &game{Seed: 7, noIO: true}
+	ui, w := newInputTestUI(t, g)
+	ui.DrawBufferInit()
+	g.InitLevel()
+	target := InvalidPos
+	for i, c := range g.Dungeon.Cells {
+		pos := idxtopos(i)
+		if c.Explored && c.T == FreeCell && pos.Distance(g.Player.Pos) > 2 {
+			target = pos
+			break
+		}
+	}
+	if !target.valid() {
+		t.Fatal("no travel target")
+	}
+	look := g.Player.Pos
+	inputs := []uiInput{
+		{key: "o"}, {key: "l"}, {key: "j"},
+		{mouse: true, mouseX: DungeonWidth + 5, mouseY: 3, button: -1},
+		{mouse: true, mouseX: look.X, mouseY: look.Y, button: -1},
+		{mouse: true, mouseX: target.X, mouseY: target.Y},
+		{mouse: true, mouseX: target.X, mouseY: target.Y},
+		{key: "k"}, {key: "\x1b"}, {key: "h"}, {key: "5"},
+		{key: "i"}, {key: "\x1b"}, {key: "L"},
+	}
+	for _, in := range inputs {
+		ui.ch <- in
+	}
+	// the game is saved and ends once input is closed
+	w.Close()
+	g.EventLoop()
+	rec := g.InputRecord
+	clicks := 0
+	for _, in := range rec.Inputs {
+		if in.Button == -1 {
+			t.Errorf("mouse move recorded: %+v", in)
+		}
+		if in.Kind == InputMapClick && in.Pos == target {
+			clicks++
+		}
+	}
+	if clicks != 2 {
+		t.Errorf("map clicks not recorded as map positions: %+v", rec.Inputs)
+	}
+
+	data, err := g.EncodeInputRecord()
+	if err != nil {
+		t.Fatalf("EncodeInputRecord: %v", err)
+	}
+	drec, err := g.DecodeInputRecord(data)
+	if err != nil {
+		t.Fatalf("DecodeInputRecord: %v", err)
+	}
+	if !reflect.DeepEqual(*drec, rec) {
+		t.Errorf("bad decoded input record")
+	}
+
+	// replay in another layout, without key bindings
+	GameConfig.Small = true
+	UIWidth, UIHeight = 80, 24
+	CenteredCamera = true
+	GameConfig.RuneNormalModeKeys = map[rune]keyAction{}
+	GameConfig.RuneTargetModeKeys = map[rune]keyAction{}
+	rg := &game{}
+	rui, rw := newInputTestUI(t, rg)
+	defer rw.Close()
+	ir := &inputReplayer{ui: rui, record: drec, speed: 1 << 20, evch: make(chan repEvent)}
+	if err := rui.RunInputReplay(ir); err != nil {
+		t.Fatalf("RunInputReplay: %v", err)
+	}
+	if ir.err != nil || ir.index != len(rec.Inputs) {
+		t.Errorf("replay stopped at input %d: %v", ir.index, ir.err)
+	}
+	if rg.Turn != g.Turn || rg.Depth != g.Depth || rg.Player.Pos != g.Player.Pos || rg.Player.HP != g.Player.HP ||
+		!reflect.DeepEqual(rg.Log, g.Log) {
+		t.Errorf("replayed game differs: turn %d/%d, pos %v/%v", rg.Turn, g.Turn, rg.Player.Pos, g.Player.Pos)
+	}
+}
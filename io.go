@@ -3,10 +3,15 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 func Replay(file string) error {
@@ -29,17 +34,124 @@ func Replay(file string) error {
 	return nil
 }
 
-func (g *game) DataDir() (string, error) {
-	var xdg string
-	if os.Getenv("GOOS") == "windows" {
-		xdg = os.Getenv("LOCALAPPDATA")
-	} else {
-		xdg = os.Getenv("XDG_DATA_HOME")
+// ExportAsciicast converts the replay file into an asciicast v2 file
+// named out.
+func ExportAsciicast(file, out string) error {
+	g := &game{}
+	err := g.LoadReplay(file)
+	if err != nil {
+		return fmt.Errorf("loading replay: %v", err)
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = WriteAsciicast(w, g.DrawLog, ColorBase03 == Color256Base03)
+	if err == nil {
+		err = w.Flush()
+	}
+	if errc := f.Close(); err == nil {
+		err = errc
+	}
+	return err
+}
+
+type gifOptions struct {
+	MaxFPS int  // maximum number of frames per second (0 for no limit)
+	Start  int  // first replay frame
+	End    int  // last replay frame (0 for the last one)
+	Tiles  bool // use map tiles instead of letters for the map
+}
+
+// ExportGIF renders the replay file into an animated GIF file named out.
+func ExportGIF(file, out string, opts gifOptions) error {
+	g := &game{}
+	err := g.LoadReplay(file)
+	if err != nil {
+		return fmt.Errorf("loading replay: %v", err)
 	}
-	if xdg == "" {
-		xdg = filepath.Join(os.Getenv("HOME"), ".local", "share")
+	if opts.Start < 0 || opts.Start >= len(g.DrawLog) {
+		return fmt.Errorf("start frame %d out of range (replay has %d frames)", opts.Start, len(g.DrawLog))
+	}
+	if opts.End > 0 && opts.End < opts.Start {
+		return fmt.Errorf("end frame %d before start frame %d", opts.End, opts.Start)
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = WriteGIF(w, g.DrawLog, opts)
+	if err == nil {
+		err = w.Flush()
+	}
+	if errc := f.Close(); err == nil {
+		err = errc
+	}
+	return err
+}
+
+// PrintStats writes statistics aggregated over the run history and the
+// archived JSON dumps of the current profile.
+func PrintStats(w io.Writer) error {
+	g := &game{}
+	dataDir, err := g.DataDir()
+	if err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(dataDir, "morgue", "*.json"))
+	if err != nil {
+		return err
+	}
+	dumps := []jsonDump{}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		var d jsonDump
+		err = json.Unmarshal(data, &d)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "boohu: skipping %s: %v\n", f, err)
+			continue
+		}
+		dumps = append(dumps, d)
+	}
+	AggregateStats(g.RunHistory(), dumps).Write(w)
+	return nil
+}
+
+func ReplayInput(file string) error {
+	ui := &gameui{}
+	g := &game{}
+	ui.g = g
+	rec, err := g.LoadInputRecord(file)
+	if err != nil {
+		return fmt.Errorf("loading input replay: %v", err)
+	}
+	err = ui.Init()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "boohu: %v\n", err)
+		os.Exit(1)
+	}
+	defer ui.Close()
+	LinkColors()
+	GameConfig.DarkLOS = true
+	load, err := g.LoadConfig()
+	if load && err == nil {
+		CustomKeys = true
+	}
+	ApplyConfig()
+	ui.PostConfig()
+	return ui.ReplayInputRecord(rec)
+}
+
+func (g *game) DataDir() (string, error) {
+	dataDir := BaseDataDir()
+	if Profile != "" {
+		dataDir = filepath.Join(dataDir, "profiles", Profile)
 	}
-	dataDir := filepath.Join(xdg, "boohu")
 	_, err := os.Stat(dataDir)
 	if err != nil {
 		err = os.MkdirAll(dataDir, 0755)
@@ -51,18 +163,25 @@ func (g *game) DataDir() (string, error) {
 }
 
 func (g *game) Save() error {
+	if g.noIO {
+		return nil
+	}
 	dataDir, err := g.DataDir()
 	if err != nil {
 		g.Print(err.Error())
 		return err
 	}
+	err = g.FlushReplay()
+	if err != nil {
+		g.PrintfStyled("Error writing replay: %v", logError, err)
+	}
 	saveFile := filepath.Join(dataDir, "save")
 	data, err := g.GameSave()
 	if err != nil {
 		g.Print(err.Error())
 		return err
 	}
-	err = ioutil.WriteFile(saveFile, data, 0644)
+	err = WriteFileAtomic(saveFile, data, filepath.Join(dataDir, "save.bak"))
 	if err != nil {
 		g.Print(err.Error())
 		return err
@@ -70,8 +189,54 @@ func (g *game) Save() error {
 	return nil
 }
 
+// WriteFileAtomic writes data to file through a temporary file, so that an
+// interrupted write cannot corrupt the file. If backup is not empty, the
+// previous content of file is kept there.
+func WriteFileAtomic(file string, data []byte, backup string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if errc := tmp.Close(); err == nil {
+		err = errc
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if backup != "" {
+		if _, err := os.Stat(file); err == nil {
+			err = os.Rename(file, backup)
+			if err != nil {
+				os.Remove(tmp.Name())
+				return err
+			}
+		}
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
 func (g *game) RemoveSaveFile() error {
-	return g.RemoveDataFile("save")
+	err := g.RemoveDataFile("save")
+	if err != nil {
+		return err
+	}
+	return g.RemoveDataFile("save.bak")
+}
+
+func (g *game) LoadSaveFile(file string) (*game, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return g.DecodeGameSave(data)
 }
 
 func (g *game) Load() (bool, error) {
@@ -80,39 +245,41 @@ func (g *game) Load() (bool, error) {
 		return false, err
 	}
 	saveFile := filepath.Join(dataDir, "save")
-	_, err = os.Stat(saveFile)
-	if err != nil {
-		// no save file, new game
-		return false, err
-	}
-	data, err := ioutil.ReadFile(saveFile)
-	if err != nil {
-		return true, err
+	backupFile := filepath.Join(dataDir, "save.bak")
+	lg, err := g.LoadSaveFile(saveFile)
+	if err == nil {
+		lg.controller = g.controller
+		*g = *lg
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		if _, errb := os.Stat(backupFile); errb != nil {
+			// no save file, new game
+			return false, err
+		}
 	}
-	lg, err := g.DecodeGameSave(data)
-	if err != nil {
+	lg, errb := g.LoadSaveFile(backupFile)
+	if errb != nil {
 		return true, err
 	}
-	if lg.Version != Version {
-		return true, fmt.Errorf("saved game for previous version %s.", lg.Version)
-	}
+	lg.controller = g.controller
 	*g = *lg
+	g.PrintfStyled("Error loading saved game: %v", logError, err)
+	g.PrintStyled("Restored the backup of the previous save instead.", logError)
 	return true, nil
 }
 
 func (g *game) SaveConfig() error {
-	dataDir, err := g.DataDir()
-	if err != nil {
-		g.Print(err.Error())
-		return err
+	if g.noIO {
+		return nil
 	}
-	saveFile := filepath.Join(dataDir, "config.gob")
-	data, err := GameConfig.ConfigSave()
+	dataDir, err := g.DataDir()
 	if err != nil {
 		g.Print(err.Error())
 		return err
 	}
-	err = ioutil.WriteFile(saveFile, data, 0644)
+	saveFile := filepath.Join(dataDir, "config.ini")
+	err = WriteFileAtomic(saveFile, GameConfig.Text(), "")
 	if err != nil {
 		g.Print(err.Error())
 		return err
@@ -120,30 +287,62 @@ func (g *game) SaveConfig() error {
 	return nil
 }
 
+// LoadConfig loads the text configuration file. A configuration from an
+// older version in binary format is converted. A configSyntaxError is
+// returned if some lines are invalid: the valid ones are still applied.
 func (g *game) LoadConfig() (bool, error) {
 	dataDir, err := g.DataDir()
 	if err != nil {
 		return false, err
 	}
-	saveFile := filepath.Join(dataDir, "config.gob")
+	saveFile := filepath.Join(dataDir, "config.ini")
 	_, err = os.Stat(saveFile)
 	if err != nil {
-		// no save file, new game
-		return false, err
+		return g.LoadGobConfig()
 	}
 	data, err := ioutil.ReadFile(saveFile)
 	if err != nil {
 		return true, err
 	}
+	c, err := ParseConfig(data, "config.ini")
+	GameConfig = *c
+	return true, err
+}
+
+func (g *game) LoadGobConfig() (bool, error) {
+	dataDir, err := g.DataDir()
+	if err != nil {
+		return false, err
+	}
+	gobFile := filepath.Join(dataDir, "config.gob")
+	_, err = os.Stat(gobFile)
+	if err != nil {
+		// no config file
+		return false, err
+	}
+	data, err := ioutil.ReadFile(gobFile)
+	if err != nil {
+		return true, err
+	}
 	c, err := g.DecodeConfigSave(data)
 	if err != nil {
 		return true, err
 	}
 	GameConfig = *c
-	return true, nil
+	if GameConfig.RuneNormalModeKeys == nil || GameConfig.RuneTargetModeKeys == nil {
+		GameConfig.RuneNormalModeKeys, GameConfig.RuneTargetModeKeys = DefaultKeyBindings()
+	}
+	err = g.SaveConfig()
+	if err != nil {
+		return true, err
+	}
+	return true, os.Remove(gobFile)
 }
 
 func (g *game) RemoveDataFile(file string) error {
+	if g.noIO {
+		return nil
+	}
 	dataDir, err := g.DataDir()
 	if err != nil {
 		return err
@@ -159,19 +358,66 @@ func (g *game) RemoveDataFile(file string) error {
 	return nil
 }
 
+func (g *game) ReadDataFile(file string) ([]byte, error) {
+	dataDir, err := g.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(filepath.Join(dataDir, file))
+}
+
+func (g *game) WriteDataFile(file string, data []byte) error {
+	if g.noIO {
+		return nil
+	}
+	dataDir, err := g.DataDir()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dataDir, file), data, 0644)
+}
+
+// AppendDataFile appends data to a data file, creating it if necessary.
+// Previous contents are never rewritten.
+func (g *game) AppendDataFile(file string, data []byte) error {
+	if g.noIO {
+		return nil
+	}
+	dataDir, err := g.DataDir()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dataDir, file), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if errc := f.Close(); err == nil {
+		err = errc
+	}
+	return err
+}
+
 func (g *game) SaveReplay() error {
+	if g.noIO {
+		return nil
+	}
 	dataDir, err := g.DataDir()
 	if err != nil {
 		g.Print(err.Error())
 		return err
 	}
+	if g.replay != nil {
+		return g.FlushReplay()
+	}
 	saveFile := filepath.Join(dataDir, "replay")
-	data, err := g.EncodeDrawLog()
+	buf := &bytes.Buffer{}
+	err = WriteReplayStream(buf, g.DrawLog, UIWidth, UIHeight)
 	if err != nil {
 		g.Print(err.Error())
 		return err
 	}
-	err = ioutil.WriteFile(saveFile, data, 0644)
+	err = ioutil.WriteFile(saveFile, buf.Bytes(), 0644)
 	if err != nil {
 		g.Print(err.Error())
 		return err
@@ -179,6 +425,57 @@ func (g *game) SaveReplay() error {
 	return nil
 }
 
+// StartReplayRecord starts streaming the replay of the game to the replay
+// file. A resumed game appends to the replay of its previous sessions.
+func (g *game) StartReplayRecord(resume bool) error {
+	if g.noIO {
+		return nil
+	}
+	dataDir, err := g.DataDir()
+	if err != nil {
+		return err
+	}
+	replayFile := filepath.Join(dataDir, "replay")
+	if resume && len(g.DrawLog) == 0 {
+		f, err := os.OpenFile(replayFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		if fi, err := f.Stat(); err == nil && fi.Size() == 0 {
+			_, err = f.WriteString(replayStreamMagic)
+			if err != nil {
+				f.Close()
+				return err
+			}
+		}
+		g.replay = &replayRecorder{w: f, closer: f}
+		return nil
+	}
+	// new game, or game saved by an older version with the replay inside
+	f, err := os.Create(replayFile)
+	if err != nil {
+		return err
+	}
+	err = WriteReplayStream(f, g.DrawLog, UIWidth, UIHeight)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	g.replay = &replayRecorder{w: f, closer: f}
+	g.ReplayFrames = len(g.DrawLog)
+	g.DrawLog = nil
+	return nil
+}
+
+func (g *game) StopReplayRecord() error {
+	if g.replay == nil {
+		return nil
+	}
+	err := g.replay.Close()
+	g.replay = nil
+	return err
+}
+
 func (g *game) LoadReplay(file string) error {
 	dataDir, err := g.DataDir()
 	if err != nil {
@@ -186,7 +483,7 @@ func (g *game) LoadReplay(file string) error {
 	}
 	replayFile := filepath.Join(dataDir, "replay")
 	if file != "_" {
-		replayFile = file
+		replayFile = g.ArchiveFile(file, ".replay")
 	}
 	_, err = os.Stat(replayFile)
 	if err != nil {
@@ -197,7 +494,12 @@ func (g *game) LoadReplay(file string) error {
 	if err != nil {
 		return err
 	}
-	dl, err := g.DecodeDrawLog(data)
+	var dl []drawFrame
+	if IsReplayStream(data) {
+		dl, err = ReadReplayStream(data)
+	} else {
+		dl, err = g.DecodeDrawLog(data)
+	}
 	if err != nil {
 		return err
 	}
@@ -205,7 +507,42 @@ func (g *game) LoadReplay(file string) error {
 	return nil
 }
 
+func (g *game) SaveInputRecord() error {
+	if g.noIO {
+		return nil
+	}
+	dataDir, err := g.DataDir()
+	if err != nil {
+		return err
+	}
+	saveFile := filepath.Join(dataDir, "inputs")
+	data, err := g.EncodeInputRecord()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(saveFile, data, 0644)
+}
+
+func (g *game) LoadInputRecord(file string) (*inputRecord, error) {
+	dataDir, err := g.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	recordFile := filepath.Join(dataDir, "inputs")
+	if file != "_" {
+		recordFile = g.ArchiveFile(file, ".inputs")
+	}
+	data, err := ioutil.ReadFile(recordFile)
+	if err != nil {
+		return nil, err
+	}
+	return g.DecodeInputRecord(data)
+}
+
 func (g *game) WriteDump() error {
+	if g.noIO {
+		return nil
+	}
 	dataDir, err := g.DataDir()
 	if err != nil {
 		return err
@@ -214,9 +551,96 @@ func (g *game) WriteDump() error {
 	if err != nil {
 		return fmt.Errorf("writing game statistics: %v", err)
 	}
+	data, err := g.JSONDump()
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(dataDir, "dump.json"), data, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("writing JSON game statistics: %v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dataDir, "dump.html"), []byte(g.HTMLDump()), 0644)
+	if err != nil {
+		return fmt.Errorf("writing HTML game statistics: %v", err)
+	}
 	err = g.SaveReplay()
 	if err != nil {
 		return fmt.Errorf("writing replay: %v", err)
 	}
+	err = g.SaveInputRecord()
+	if err != nil {
+		return fmt.Errorf("writing input replay: %v", err)
+	}
+	return nil
+}
+
+// WriteMorgue writes the dump and replay files of a finished game, and
+// archives copies of them in the morgue directory, with the date of the
+// end of the game as name.
+func (g *game) WriteMorgue() error {
+	err := g.WriteDump()
+	if err != nil || g.noIO {
+		return err
+	}
+	dataDir, err := g.DataDir()
+	if err != nil {
+		return err
+	}
+	morgueDir := filepath.Join(dataDir, "morgue")
+	err = os.MkdirAll(morgueDir, 0755)
+	if err != nil {
+		return fmt.Errorf("archiving game: %v", err)
+	}
+	name, err := reserveMorgueName(morgueDir, time.Now().Format("2006-01-02T15-04-05"))
+	if err != nil {
+		return fmt.Errorf("archiving game: %v", err)
+	}
+	for _, f := range [][2]string{{"dump", ".txt"}, {"dump.json", ".json"}, {"dump.html", ".html"}, {"replay", ".replay"}, {"inputs", ".inputs"}} {
+		data, err := ioutil.ReadFile(filepath.Join(dataDir, f[0]))
+		if err != nil {
+			return fmt.Errorf("archiving game: %v", err)
+		}
+		err = ioutil.WriteFile(filepath.Join(morgueDir, name+f[1]), data, 0644)
+		if err != nil {
+			return fmt.Errorf("archiving game: %v", err)
+		}
+	}
+	g.morgueName = name
 	return nil
 }
+
+// reserveMorgueName returns a name for a new archived game, adding a
+// numbered suffix to base if a game was already archived with that name
+// (for example if two games ended in the same second).
+func reserveMorgueName(morgueDir, base string) (string, error) {
+	name := base
+	for i := 2; ; i++ {
+		f, err := os.OpenFile(filepath.Join(morgueDir, name+".txt"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			return name, f.Close()
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// ArchiveFile returns the path of file, which may be the name of a game of
+// the morgue archive, with or without extension ext.
+func (g *game) ArchiveFile(file, ext string) string {
+	if _, err := os.Stat(file); err == nil {
+		return file
+	}
+	dataDir, err := g.DataDir()
+	if err != nil {
+		return file
+	}
+	afile := filepath.Join(dataDir, "morgue", file)
+	if filepath.Ext(file) != ext {
+		afile += ext
+	}
+	if _, err := os.Stat(afile); err != nil {
+		return file
+	}
+	return afile
+}
@@ -0,0 +1,191 @@
+// +build !js
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boohu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xdg := os.Getenv("XDG_DATA_HOME")
+	defer os.Setenv("XDG_DATA_HOME", xdg)
+	os.Setenv("XDG_DATA_HOME", dir)
+
+	g := &game{Seed: 3}
+	g.InitLevel()
+	if err := g.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	g.Player.Simellas = 42
+	if err := g.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	saveFile := filepath.Join(dir, "boohu", "save")
+	data, err := ioutil.ReadFile(saveFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.DecodeGameSave(data); err != nil {
+		t.Fatalf("DecodeGameSave: %v", err)
+	}
+	data[len(data)/2] ^= 0xff
+	if _, err := g.DecodeGameSave(data); err == nil {
+		t.Errorf("corrupted save not detected")
+	}
+	if err := ioutil.WriteFile(saveFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	lg := &game{}
+	load, err := lg.Load()
+	if !load || err != nil {
+		t.Fatalf("backup not loaded: %v", err)
+	}
+	if lg.Player.Simellas == 42 || lg.Log[len(lg.Log)-1].Style != logError {
+		t.Errorf("bad backup restoration")
+	}
+	if err := g.RemoveSaveFile(); err != nil {
+		t.Fatal(err)
+	}
+	if load, _ := lg.Load(); load {
+		t.Errorf("save files not removed")
+	}
+}
+
+func TestProfileDataDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boohu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xdg := os.Getenv("XDG_DATA_HOME")
+	defer os.Setenv("XDG_DATA_HOME", xdg)
+	os.Setenv("XDG_DATA_HOME", dir)
+	defer SetProfile("")
+
+	for _, name := range []string{"../x", "a b", "toolongprofilename_xyz", "é"} {
+		if SetProfile(name) == nil {
+			t.Errorf("invalid profile name %q accepted", name)
+		}
+	}
+	g := &game{}
+	for _, name := range []string{"bob", "alice"} {
+		if err := SetProfile(name); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.WriteDataFile("test", []byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	SetProfile("bob")
+	data, err := g.ReadDataFile("test")
+	if err != nil || string(data) != "bob" {
+		t.Errorf("profile data not independent: %q %v", data, err)
+	}
+	if ps := Profiles(); len(ps) != 2 || ps[0] != "alice" || ps[1] != "bob" {
+		t.Errorf("bad profile list: %v", ps)
+	}
+}
+
+func TestConfigMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boohu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xdg := os.Getenv("XDG_DATA_HOME")
+	defer os.Setenv("XDG_DATA_HOME", xdg)
+	os.Setenv("XDG_DATA_HOME", dir)
+	defer func(c config) { GameConfig = c }(GameConfig)
+
+	g := &game{}
+	GameConfig = config{Small: true}
+	ApplyDefaultKeyBindings()
+	GameConfig.RuneNormalModeKeys['Q'] = KeyExplore
+	data, err := GameConfig.ConfigSave()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.WriteDataFile("config.gob", data); err != nil {
+		t.Fatal(err)
+	}
+	GameConfig = config{}
+	load, err := g.LoadConfig()
+	if !load || err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if !GameConfig.Small || GameConfig.RuneNormalModeKeys['Q'] != KeyExplore {
+		t.Errorf("bad migrated config: %+v", GameConfig)
+	}
+	if _, err := g.ReadDataFile("config.gob"); err == nil {
+		t.Errorf("old config file not removed")
+	}
+	data, err = g.ReadDataFile("config.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ParseConfig(data, "config.ini")
+	if err != nil || !c.Small || c.RuneNormalModeKeys['Q'] != KeyExplore {
+		t.Errorf("bad text config: %v", err)
+	}
+}
+
+func TestMorgue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boohu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xdg := os.Getenv("XDG_DATA_HOME")
+	defer os.Setenv("XDG_DATA_HOME", xdg)
+	os.Setenv("XDG_DATA_HOME", dir)
+
+	g, _ := NewHeadlessGame(11)
+	g.noIO = false
+	g.DrawLog = []drawFrame{{Draws: []cellDraw{{Cell: UICell{R: '@'}}}}}
+	if err := g.WriteMorgue(); err != nil {
+		t.Fatalf("WriteMorgue: %v", err)
+	}
+	if g.morgueName == "" {
+		t.Fatal("game not archived")
+	}
+	for _, ext := range []string{".txt", ".json", ".html", ".replay", ".inputs"} {
+		if _, err := os.Stat(filepath.Join(dir, "boohu", "morgue", g.morgueName+ext)); err != nil {
+			t.Errorf("missing archive file: %v", err)
+		}
+	}
+	for _, name := range []string{g.morgueName, g.morgueName + ".replay"} {
+		lg := &game{}
+		if err := lg.LoadReplay(name); err != nil || len(lg.DrawLog) != 1 {
+			t.Errorf("archived replay %s not loaded: %v", name, err)
+		}
+	}
+	// a game ending in the same second does not overwrite the archive
+	first := g.morgueName
+	g.EndGame("quit")
+	if err := g.WriteMorgue(); err != nil {
+		t.Fatalf("WriteMorgue: %v", err)
+	}
+	if g.morgueName == first {
+		t.Errorf("archive name reused: %s", first)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "boohu", "morgue", g.morgueName+".json"))
+	var d jsonDump
+	if err == nil {
+		err = json.Unmarshal(data, &d)
+	}
+	if err != nil || d.Outcome != "quit" {
+		t.Errorf("bad archived quit game: %q %v", d.Outcome, err)
+	}
+	if first != g.morgueName[:len(first)] {
+		t.Errorf("bad unique archive name: %s", g.morgueName)
+	}
+}
@@ -190,7 +190,7 @@ func (p potion) Use(g *game, ev event) error {
 	ev.Renew(g, 5)
 	g.UseConsumable(p)
 	g.Stats.Drinks++
-	g.ui.DrinkingPotionAnimation()
+	g.Animations().DrinkingPotionAnimation()
 	return nil
 }
 
@@ -201,7 +201,7 @@ func (g *game) QuaffTeleportation(ev event) error {
 	if g.Player.HasStatus(StatusTele) {
 		return errors.New("You already quaffed a potion of teleportation.")
 	}
-	delay := 20 + RandInt(30)
+	delay := 20 + g.RandInt(30)
 	g.Player.Statuses[StatusTele] = 1
 	g.PushEvent(&simpleEvent{ERank: ev.Rank() + delay, EAction: Teleportation})
 	g.Printf("You quaff the %s. You feel unstable.", TeleportationPotion)
@@ -216,7 +216,7 @@ func (g *game) QuaffBerserk(ev event) error {
 		return errors.New("You are already berserk.")
 	}
 	g.Player.Statuses[StatusBerserk] = 1
-	end := ev.Rank() + 65 + RandInt(20)
+	end := ev.Rank() + 65 + g.RandInt(20)
 	g.PushEvent(&simpleEvent{ERank: end, EAction: BerserkEnd})
 	g.Player.Expire[StatusBerserk] = end
 	g.Printf("You quaff the %s. You feel a sudden urge to kill things.", BerserkPotion)
@@ -264,7 +264,7 @@ func (g *game) QuaffDescent(ev event) error {
 
 func (g *game) QuaffSwiftness(ev event) error {
 	g.Player.Statuses[StatusSwift]++
-	end := ev.Rank() + 85 + RandInt(20)
+	end := ev.Rank() + 85 + g.RandInt(20)
 	g.PushEvent(&simpleEvent{ERank: end, EAction: HasteEnd})
 	g.Player.Expire[StatusSwift] = end
 	g.Player.Statuses[StatusAgile]++
@@ -276,7 +276,7 @@ func (g *game) QuaffSwiftness(ev event) error {
 
 func (g *game) QuaffDigPotion(ev event) error {
 	g.Player.Statuses[StatusDig] = 1
-	end := ev.Rank() + 75 + RandInt(20)
+	end := ev.Rank() + 75 + g.RandInt(20)
 	g.PushEvent(&simpleEvent{ERank: end, EAction: DigEnd})
 	g.Player.Expire[StatusDig] = end
 	g.Printf("You quaff the %s. You feel like an earth dragon.", DigPotion)
@@ -288,7 +288,7 @@ func (g *game) QuaffSwapPotion(ev event) error {
 		return errors.New("You cannot drink this potion while lignified.")
 	}
 	g.Player.Statuses[StatusSwap] = 1
-	end := ev.Rank() + 130 + RandInt(41)
+	end := ev.Rank() + 130 + g.RandInt(41)
 	g.PushEvent(&simpleEvent{ERank: end, EAction: SwapEnd})
 	g.Player.Expire[StatusSwap] = end
 	g.Printf("You quaff the %s. You feel light-footed.", SwapPotion)
@@ -300,7 +300,7 @@ func (g *game) QuaffShadowsPotion(ev event) error {
 		return errors.New("You are already surrounded by shadows.")
 	}
 	g.Player.Statuses[StatusShadows] = 1
-	end := ev.Rank() + 130 + RandInt(41)
+	end := ev.Rank() + 130 + g.RandInt(41)
 	g.PushEvent(&simpleEvent{ERank: end, EAction: ShadowsEnd})
 	g.Player.Expire[StatusShadows] = end
 	g.Printf("You quaff the %s. You feel surrounded by shadows.", ShadowsPotion)
@@ -321,7 +321,7 @@ func (g *game) QuaffMagicMapping(ev event) error {
 	dp := &dungeonPath{dungeon: g.Dungeon}
 	g.AutoExploreDijkstra(dp, []int{g.Player.Pos.idx()})
 	cdists := make(map[int][]int)
-	for i, dist := range DijkstraMapCache {
+	for i, dist := range g.dijkstraMap {
 		cdists[dist] = append(cdists[dist], i)
 	}
 	var dists []int
@@ -329,7 +329,7 @@ func (g *game) QuaffMagicMapping(ev event) error {
 		dists = append(dists, dist)
 	}
 	sort.Ints(dists)
-	g.ui.DrawDungeonView(NormalMode)
+	g.Animations().DrawDungeonView(NormalMode)
 	for _, d := range dists {
 		draw := false
 		for _, i := range cdists[d] {
@@ -341,7 +341,7 @@ func (g *game) QuaffMagicMapping(ev event) error {
 			}
 		}
 		if draw {
-			g.ui.MagicMappingAnimation(cdists[d])
+			g.Animations().MagicMappingAnimation(cdists[d])
 		}
 	}
 	g.Printf("You quaff the %s. You feel aware of your surroundings..", MagicMappingPotion)
@@ -353,11 +353,11 @@ func (g *game) QuaffTormentPotion(ev event) error {
 	damage := g.Player.HP / 2
 	g.Player.HP = g.Player.HP - damage
 	g.Stats.Damage += damage
-	g.ui.WoundedAnimation()
+	g.Animations().WoundedAnimation()
 	g.MakeNoise(ExplosionNoise+10, g.Player.Pos)
-	g.ui.TormentExplosionAnimation()
-	for pos, b := range g.Player.LOS {
-		if !b {
+	g.Animations().TormentExplosionAnimation()
+	for _, pos := range SortedPositions(g.Player.LOS) {
+		if !g.Player.LOS[pos] {
 			continue
 		}
 		g.ExplosionAt(ev, pos)
@@ -367,7 +367,7 @@ func (g *game) QuaffTormentPotion(ev event) error {
 
 func (g *game) QuaffAccuracyPotion(ev event) error {
 	g.Player.Statuses[StatusAccurate]++
-	end := ev.Rank() + 85 + RandInt(20)
+	end := ev.Rank() + 85 + g.RandInt(20)
 	g.PushEvent(&simpleEvent{ERank: end, EAction: AccurateEnd})
 	g.Player.Expire[StatusAccurate] = end
 	g.Printf("You quaff the %s. You feel accurate.", SwiftnessPotion)
@@ -402,7 +402,7 @@ func (g *game) QuaffCBlinkPotion(ev event) error {
 	if g.Player.HasStatus(StatusLignification) {
 		return errors.New("You cannot blink while lignified.")
 	}
-	if err := g.ui.ChooseTarget(&chooser{free: true}); err != nil {
+	if err := g.ChooseTarget(&chooser{free: true}); err != nil {
 		return err
 	}
 	g.Printf("You quaff the %s. You blink.", CBlinkPotion)
@@ -515,13 +515,13 @@ func (p projectile) Use(g *game, ev event) error {
 }
 
 func (g *game) ThrowConfusingDart(ev event) error {
-	if err := g.ui.ChooseTarget(&chooser{needsFreeWay: true}); err != nil {
+	if err := g.ChooseTarget(&chooser{needsFreeWay: true}); err != nil {
 		return err
 	}
 	mons := g.MonsterAt(g.Player.Target)
 	bonus := 0
 	if g.Player.HasStatus(StatusBerserk) {
-		bonus += RandInt(5)
+		bonus += g.RandInt(5)
 	}
 	if g.Player.Aptitudes[AptStrong] {
 		bonus += 2
@@ -531,11 +531,11 @@ func (g *game) ThrowConfusingDart(ev event) error {
 	if mons.HP > 0 {
 		mons.EnterConfusion(g, ev)
 		g.PrintfStyled("Your %s hits the %s (%d dmg), who appears confused.", logPlayerHit, ConfusingDart, mons.Kind, attack)
-		g.ui.ThrowAnimation(g.Ray(mons.Pos), true)
+		g.Animations().ThrowAnimation(g.Ray(mons.Pos), true)
 		mons.MakeHuntIfHurt(g)
 	} else {
 		g.PrintfStyled("Your %s kills the %s.", logPlayerHit, ConfusingDart, mons.Kind)
-		g.ui.ThrowAnimation(g.Ray(mons.Pos), true)
+		g.Animations().ThrowAnimation(g.Ray(mons.Pos), true)
 		g.HandleKill(mons, ev)
 	}
 	g.HandleStone(mons)
@@ -554,13 +554,13 @@ func (g *game) ExplosionAt(ev event, pos position) {
 		g.MakeNoise(ExplosionHitNoise, mons.Pos)
 		g.HandleStone(mons)
 		mons.MakeHuntIfHurt(g)
-	} else if g.Dungeon.Cell(pos).T == WallCell && RandInt(2) == 0 {
+	} else if g.Dungeon.Cell(pos).T == WallCell && g.RandInt(2) == 0 {
 		g.Dungeon.SetCell(pos, FreeCell)
 		g.Stats.Digs++
 		if !g.Player.LOS[pos] {
 			g.WrongWall[pos] = true
 		} else {
-			g.ui.WallExplosionAnimation(pos)
+			g.Animations().WallExplosionAnimation(pos)
 		}
 		g.MakeNoise(WallNoise, pos)
 		g.Fog(pos, 1, ev)
@@ -568,14 +568,14 @@ func (g *game) ExplosionAt(ev event, pos position) {
 }
 
 func (g *game) ThrowExplosiveMagara(ev event) error {
-	if err := g.ui.ChooseTarget(&chooser{area: true, minDist: true, flammable: true, wall: true}); err != nil {
+	if err := g.ChooseTarget(&chooser{area: true, minDist: true, flammable: true, wall: true}); err != nil {
 		return err
 	}
 	neighbors := g.Player.Target.ValidNeighbors()
 	g.Printf("You throw the explosive magara... %s", g.ExplosionSound())
 	g.MakeNoise(ExplosionNoise, g.Player.Target)
-	g.ui.ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgPlayer)
-	g.ui.ExplosionAnimation(FireExplosion, g.Player.Target)
+	g.Animations().ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgPlayer)
+	g.Animations().ExplosionAnimation(FireExplosion, g.Player.Target)
 	for _, pos := range append(neighbors, g.Player.Target) {
 		g.ExplosionAt(ev, pos)
 	}
@@ -585,12 +585,12 @@ func (g *game) ThrowExplosiveMagara(ev event) error {
 }
 
 func (g *game) ThrowTeleportMagara(ev event) error {
-	if err := g.ui.ChooseTarget(&chooser{area: true, minDist: true}); err != nil {
+	if err := g.ChooseTarget(&chooser{area: true, minDist: true}); err != nil {
 		return err
 	}
 	neighbors := g.Player.Target.ValidNeighbors()
 	g.Print("You throw the teleport magara.")
-	g.ui.ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgPlayer)
+	g.Animations().ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgPlayer)
 	for _, pos := range append(neighbors, g.Player.Target) {
 		mons := g.MonsterAt(pos)
 		if mons.Exists() {
@@ -603,20 +603,20 @@ func (g *game) ThrowTeleportMagara(ev event) error {
 }
 
 func (g *game) ThrowSlowingMagara(ev event) error {
-	if err := g.ui.ChooseTarget(&chooser{}); err != nil {
+	if err := g.ChooseTarget(&chooser{}); err != nil {
 		return err
 	}
 	ray := g.Ray(g.Player.Target)
 	g.MakeNoise(MagicCastNoise, g.Player.Pos)
 	g.Print("Whoosh! A bolt of slowing emerges out of the magara.")
-	g.ui.SlowingMagaraAnimation(ray)
+	g.Animations().SlowingMagaraAnimation(ray)
 	for _, pos := range ray {
 		mons := g.MonsterAt(pos)
 		if !mons.Exists() {
 			continue
 		}
 		mons.Statuses[MonsSlow]++
-		g.PushEvent(&monsterEvent{ERank: g.Ev.Rank() + 130 + RandInt(40), NMons: mons.Index, EAction: MonsSlowEnd})
+		g.PushEvent(&monsterEvent{ERank: g.Ev.Rank() + 130 + g.RandInt(40), NMons: mons.Index, EAction: MonsSlowEnd})
 	}
 
 	ev.Renew(g, 7)
@@ -625,8 +625,8 @@ func (g *game) ThrowSlowingMagara(ev event) error {
 
 func (g *game) ThrowConfuseMagara(ev event) error {
 	g.Printf("You activate the %s. A harmonic light confuses monsters.", ConfuseMagara)
-	for pos, b := range g.Player.LOS {
-		if !b {
+	for _, pos := range SortedPositions(g.Player.LOS) {
+		if !g.Player.LOS[pos] {
 			continue
 		}
 		mons := g.MonsterAt(pos)
@@ -642,7 +642,7 @@ func (g *game) ThrowConfuseMagara(ev event) error {
 func (g *game) NightFog(at position, radius int, ev event) {
 	dij := &normalPath{game: g}
 	nm := Dijkstra(dij, []position{at}, radius)
-	for pos := range nm {
+	for _, pos := range nm.SortedPositions() {
 		_, ok := g.Clouds[pos]
 		if !ok {
 			g.Clouds[pos] = CloudNight
@@ -654,11 +654,11 @@ func (g *game) NightFog(at position, radius int, ev event) {
 }
 
 func (g *game) ThrowNightMagara(ev event) error {
-	if err := g.ui.ChooseTarget(&chooser{needsFreeWay: true}); err != nil {
+	if err := g.ChooseTarget(&chooser{needsFreeWay: true}); err != nil {
 		return err
 	}
 	g.Print("You throw the night magara… Clouds come out of it.")
-	g.ui.ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgSleepingMonster)
+	g.Animations().ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgSleepingMonster)
 	g.NightFog(g.Player.Target, 2, ev)
 
 	ev.Renew(g, 7)
@@ -700,6 +700,19 @@ var ConsumablesCollectData = map[consumable]collectData{
 	AccuracyPotion:      {rarity: 18, quantity: 1},
 }
 
+// ConsumablesCollectOrder lists the consumables of ConsumablesCollectData in
+// a fixed order, so that generation does not depend on map iteration order.
+var ConsumablesCollectOrder []consumable
+
+func init() {
+	for c := range ConsumablesCollectData {
+		ConsumablesCollectOrder = append(ConsumablesCollectOrder, c)
+	}
+	sort.Slice(ConsumablesCollectOrder, func(i, j int) bool {
+		return ConsumablesCollectOrder[i].String() < ConsumablesCollectOrder[j].String()
+	})
+}
+
 type equipable interface {
 	Equip(g *game)
 	String() string
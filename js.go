@@ -9,7 +9,6 @@ import (
 	"log"
 	"runtime"
 	"time"
-	"unicode/utf8"
 
 	"syscall/js"
 )
@@ -51,15 +50,25 @@ func newGame(ui *gameui) {
 	}
 	ApplyConfig()
 	ui.PostConfig()
+	var action startAction
 	if runtime.GOARCH != "wasm" {
-		ui.DrawWelcome()
+		action = ui.DrawWelcome()
 	} else {
-		again := ui.HandleStartMenu()
+		var again bool
+		action, again = ui.HandleStartMenu()
 		if again {
 			return
 		}
 	}
 	load, err = g.Load()
+	var dailyerr string
+	if action == StartDaily {
+		if load && err == nil {
+			dailyerr = "You have a saved game in progress: finish it before attempting the daily challenge."
+		} else if errd := g.StartDaily(time.Now()); errd != nil {
+			dailyerr = errd.Error()
+		}
+	}
 	if !load {
 		g.InitLevel()
 	} else if err != nil {
@@ -68,6 +77,9 @@ func newGame(ui *gameui) {
 	} else {
 		ui.DrawBufferInit()
 	}
+	if dailyerr != "" {
+		g.PrintStyled(dailyerr, logError)
+	}
 	g.ui = ui
 	g.EventLoop()
 	ui.Clear()
@@ -77,7 +89,7 @@ func newGame(ui *gameui) {
 	ui.PressAnyKey()
 }
 
-func (ui *gameui) HandleStartMenu() (again bool) {
+func (ui *gameui) HandleStartMenu() (action startAction, again bool) {
 	l := ui.DrawWelcomeCommon()
 	g := ui.g
 	for {
@@ -90,7 +102,7 @@ func (ui *gameui) HandleStartMenu() (again bool) {
 				ui.Flush()
 				time.Sleep(25 * time.Millisecond)
 				log.Printf("Load replay: %v", err)
-				return true
+				return a, true
 			}
 			small := GameConfig.Small
 			GameConfig.Small = true
@@ -101,9 +113,12 @@ func (ui *gameui) HandleStartMenu() (again bool) {
 				GameConfig.Small = false
 				ui.ApplyToggleLayoutWithClear(false)
 			}
-			return true
+			return a, true
+		case StartHallOfFame:
+			ui.HallOfFame()
+			return a, true
 		default:
-			return false
+			return a, false
 		}
 	}
 }
@@ -249,6 +264,51 @@ func (g *game) RemoveDataFile(file string) error {
 	return nil
 }
 
+func (g *game) ReadDataFile(file string) ([]byte, error) {
+	storage := js.Global().Get("localStorage")
+	if storage.Type() != js.TypeObject {
+		return nil, errors.New("localStorage not found")
+	}
+	s := storage.Call("getItem", "boohu"+file)
+	if s.Type() != js.TypeString || runtime.GOARCH != "wasm" {
+		return nil, errors.New("invalid storage")
+	}
+	return base64.StdEncoding.DecodeString(s.String())
+}
+
+func (g *game) WriteDataFile(file string, data []byte) error {
+	if runtime.GOARCH != "wasm" {
+		return nil
+	}
+	storage := js.Global().Get("localStorage")
+	if storage.Type() != js.TypeObject {
+		SaveError = "localStorage not found"
+		return errors.New("localStorage not found")
+	}
+	s := base64.StdEncoding.EncodeToString(data)
+	storage.Call("setItem", "boohu"+file, s)
+	SaveError = ""
+	return nil
+}
+
+// AppendDataFile appends data to a data file. There is no append operation
+// for localStorage items, so the whole item is written again.
+func (g *game) AppendDataFile(file string, data []byte) error {
+	storage := js.Global().Get("localStorage")
+	if storage.Type() != js.TypeObject {
+		return errors.New("localStorage not found")
+	}
+	s := storage.Call("getItem", "boohu"+file)
+	if s.Type() == js.TypeString {
+		old, err := base64.StdEncoding.DecodeString(s.String())
+		if err != nil {
+			return err
+		}
+		data = append(old, data...)
+	}
+	return g.WriteDataFile(file, data)
+}
+
 func (g *game) Load() (bool, error) {
 	storage := js.Global().Get("localStorage")
 	if storage.Type() != js.TypeObject {
@@ -328,6 +388,10 @@ func (g *game) WriteDump() error {
 	return nil
 }
 
+func (g *game) WriteMorgue() error {
+	return g.WriteDump()
+}
+
 // End of io compatibility functions
 
 func (ui *gameui) Init() error {
@@ -384,6 +448,7 @@ func (ui *gameui) Init() error {
 		}))
 	ui.menuHover = -1
 	ui.InitElements()
+	ForcedPalette = PaletteSolarized
 	SolarizedPalette()
 	ui.HideCursor()
 	settingsActions = append(settingsActions, toggleTiles)
@@ -459,33 +524,6 @@ func (ui *gameui) PollEvent() (in uiInput) {
 	case in = <-ch:
 	case in.interrupt = <-interrupt:
 	}
-	switch in.key {
-	case "Escape", "Space":
-		in.key = "\x1b"
-	case "Enter", "\r", "\n":
-		in.key = "."
-	case "ArrowLeft":
-		in.key = "4"
-	case "ArrowRight":
-		in.key = "6"
-	case "ArrowUp", "BackSpace":
-		in.key = "8"
-	case "ArrowDown":
-		in.key = "2"
-	case "Home":
-		in.key = "7"
-	case "End":
-		in.key = "1"
-	case "PageUp":
-		in.key = "9"
-	case "PageDown":
-		in.key = "3"
-	case "Numpad5", "Delete":
-		in.key = "5"
-	default:
-		if utf8.RuneCountInString(in.key) != 1 {
-			in.key = ""
-		}
-	}
+	in.key = BrowserKey(in.key)
 	return in
 }
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+type keyPreset int
+
+const (
+	PresetViKeys keyPreset = iota
+	PresetNumpad
+	PresetWASD
+	PresetAzerty
+)
+
+var keyPresets = []keyPreset{PresetViKeys, PresetNumpad, PresetWASD, PresetAzerty}
+
+func (p keyPreset) String() (text string) {
+	switch p {
+	case PresetViKeys:
+		text = "vi-keys and numpad (default)"
+	case PresetNumpad:
+		text = "numpad and arrows only for movement"
+	case PresetWASD:
+		text = "arrows and WASD"
+	case PresetAzerty:
+		text = "AZERTY-friendly vi-keys"
+	}
+	return text
+}
+
+// movement keys in order W, S, N, E, NW, NE, SW, SE
+var viMoveKeys = []rune("hjklyubn")
+var viRunKeys = []rune("HJKLYUBN")
+
+// bindMoves replaces vi movement keys with the given ones in both modes.
+// Movement keys also are used in targeting mode.
+func bindMoves(normal, target map[rune]keyAction, moves, runs []rune) {
+	for i := range viMoveKeys {
+		delete(normal, viMoveKeys[i])
+		delete(normal, viRunKeys[i])
+		delete(target, viMoveKeys[i])
+		delete(target, viRunKeys[i])
+	}
+	for i, r := range moves {
+		normal[r] = KeyW + keyAction(i)
+		target[r] = KeyW + keyAction(i)
+	}
+	for i, r := range runs {
+		normal[r] = KeyRunW + keyAction(i)
+		target[r] = KeyRunW + keyAction(i)
+	}
+}
+
+// KeyPresetBindings returns the key maps of a preset. Presets are
+// variations on the default key bindings.
+func KeyPresetBindings(p keyPreset) (normal, target map[rune]keyAction) {
+	normal, target = DefaultKeyBindings()
+	switch p {
+	case PresetNumpad:
+		// numeric keys (and arrows, that are translated to them) are
+		// bound in every preset: keep vi-keys only for travel.
+		bindMoves(normal, target, nil, viRunKeys)
+	case PresetWASD:
+		bindMoves(normal, target, []rune("adwsqezc"), []rune("ADWSQEZC"))
+		normal['p'] = KeyDrink
+		normal['P'] = KeySave
+		normal['K'] = KeyQuit
+		normal['&'] = KeyWizard
+		target['r'] = KeyExclude
+	case PresetAzerty:
+		// unshifted number row keys on AZERTY keyboards
+		for r, k := range map[rune]keyAction{'\'': KeyW, 'é': KeyS, '_': KeyN, '-': KeyE,
+			'è': KeyNW, 'ç': KeyNE, '&': KeySW, '"': KeySE} {
+			normal[r] = k
+			target[r] = k
+		}
+		normal['('] = KeyWaitTurn
+		normal['*'] = KeyDump
+		normal['ù'] = KeyCharacterInfo
+		normal[';'] = KeyWaitTurn
+		target['p'] = KeyPreviousMonster
+	}
+	return normal, target
+}
+
+func ApplyKeyPreset(p keyPreset) {
+	GameConfig.RuneNormalModeKeys, GameConfig.RuneTargetModeKeys = KeyPresetBindings(p)
+	CustomKeys = p != PresetViKeys
+}
+
+func (k keyAction) Description() string {
+	if k.NormalModeKey() {
+		return k.NormalModeDescription()
+	}
+	return k.TargetingModeDescription()
+}
+
+// KeyBindingConflicts reports configurable actions that cannot be
+// performed anymore because none of their keys are left, as well as keys
+// used for movement in one mode but for another action in the other mode.
+func KeyBindingConflicts(normal, target map[rune]keyAction) []string {
+	bound := map[keyAction]bool{}
+	tbound := map[keyAction]bool{}
+	for _, k := range normal {
+		bound[k] = true
+	}
+	for _, k := range target {
+		tbound[k] = true
+	}
+	conflicts := []string{}
+	for _, k := range configurableKeyActions {
+		if k.NormalModeKey() && !bound[k] {
+			conflicts = append(conflicts, fmt.Sprintf("No key for “%s”.", k.Description()))
+		} else if !k.NormalModeKey() && !tbound[k] {
+			conflicts = append(conflicts, fmt.Sprintf("No key for “%s” in targeting mode.", k.Description()))
+		}
+	}
+	for _, r := range SortedRunes(normal) {
+		k := normal[r]
+		if k < KeyW || k > KeyRunSE {
+			continue
+		}
+		if tk, ok := target[r]; ok && tk != k {
+			conflicts = append(conflicts, fmt.Sprintf("Key “%s” is “%s” but “%s” in targeting mode.",
+				RuneKeyName(r), k.Description(), tk.Description()))
+		}
+	}
+	return conflicts
+}
+
+func SortedRunes(keys map[rune]keyAction) []rune {
+	runes := []rune{}
+	for r := range keys {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
+
+// KeyMapText returns the text of the key map sections of the
+// configuration file.
+func KeyMapText(normal, target map[rune]keyAction) []byte {
+	buf := &bytes.Buffer{}
+	WriteKeyMap(buf, "normal-keys", normal)
+	buf.WriteString("\n")
+	WriteKeyMap(buf, "target-keys", target)
+	return buf.Bytes()
+}
+
+// ExportKeys writes the current key bindings to the “keys.ini” data file,
+// in the same format as the configuration file.
+func (g *game) ExportKeys() error {
+	return g.WriteDataFile("keys.ini", KeyMapText(GameConfig.RuneNormalModeKeys, GameConfig.RuneTargetModeKeys))
+}
+
+// ImportKeys loads the key bindings from the “keys.ini” data file. Other
+// settings in the file, if any, are ignored. It returns messages about
+// invalid lines and conflicts.
+func (g *game) ImportKeys() ([]string, error) {
+	data, err := g.ReadDataFile("keys.ini")
+	if err != nil {
+		return nil, err
+	}
+	msgs := []string{}
+	c, err := ParseConfig(data, "keys.ini")
+	if serr, ok := err.(configSyntaxError); ok {
+		msgs = append(msgs, serr...)
+	}
+	GameConfig.RuneNormalModeKeys = c.RuneNormalModeKeys
+	GameConfig.RuneTargetModeKeys = c.RuneTargetModeKeys
+	CustomKeys = true
+	msgs = append(msgs, KeyBindingConflicts(c.RuneNormalModeKeys, c.RuneTargetModeKeys)...)
+	return msgs, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestKeyPresets(t *testing.T) {
+	for _, p := range keyPresets {
+		normal, target := KeyPresetBindings(p)
+		if cs := KeyBindingConflicts(normal, target); len(cs) > 0 {
+			t.Errorf("conflicts in preset %v: %v", p, cs)
+		}
+		c := &config{RuneNormalModeKeys: normal, RuneTargetModeKeys: target}
+		lc, err := ParseConfig(c.Text(), "config.ini")
+		if err != nil || len(lc.RuneNormalModeKeys) != len(normal) || len(lc.RuneTargetModeKeys) != len(target) {
+			t.Errorf("bad text round trip for preset %v: %v", p, err)
+		}
+	}
+}
+
+func TestKeyBindingConflicts(t *testing.T) {
+	normal, target := DefaultKeyBindings()
+	delete(normal, 'o')
+	target['h'] = KeyExclude
+	cs := KeyBindingConflicts(normal, target)
+	if len(cs) != 2 {
+		t.Fatalf("bad conflicts: %v", cs)
+	}
+	_, err := ParseConfig([]byte("[normal-keys]\nexplore = o\nrest = o\n"), "keys.ini")
+	if err == nil || err.Error() != "keys.ini:3: key “o” bound to both explore and rest" {
+		t.Errorf("duplicate key not reported: %v", err)
+	}
+}
@@ -77,7 +77,7 @@ func (g *game) StoryPrintf(format string, a ...interface{}) {
 }
 
 func (g *game) CrackSound() (text string) {
-	switch RandInt(4) {
+	switch g.RandInt(4) {
 	case 0:
 		text = "Crack!"
 	case 1:
@@ -91,7 +91,7 @@ func (g *game) CrackSound() (text string) {
 }
 
 func (g *game) ExplosionSound() (text string) {
-	switch RandInt(3) {
+	switch g.RandInt(3) {
 	case 0:
 		text = "Bang!"
 	case 1:
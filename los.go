@@ -6,6 +6,16 @@ type raynode struct {
 
 type rayMap map[position]raynode
 
+// SortedPositions returns the positions of the ray map in a fixed order.
+func (rm rayMap) SortedPositions() []position {
+	ps := make([]position, 0, len(rm))
+	for pos := range rm {
+		ps = append(ps, pos)
+	}
+	sortPositions(ps)
+	return ps
+}
+
 func (g *game) bestParent(rm rayMap, from, pos position) (position, int) {
 	p := pos.Parents(from)
 	b := p[0]
@@ -111,8 +121,8 @@ func (g *game) ComputeLOS() {
 	m := map[position]bool{}
 	losRange := g.LosRange()
 	g.Player.Rays = g.buildRayMap(g.Player.Pos, losRange)
-	for pos, n := range g.Player.Rays {
-		if n.Cost < g.LosRange() {
+	for _, pos := range g.Player.Rays.SortedPositions() {
+		if g.Player.Rays[pos].Cost < g.LosRange() {
 			m[pos] = true
 			g.SeePosition(pos)
 		}
@@ -248,12 +258,12 @@ func (g *game) ComputeNoise() {
 	if g.Player.Aptitudes[AptHear] {
 		rmax--
 	}
-	for pos := range nm {
+	for _, pos := range nm.SortedPositions() {
 		if g.Player.LOS[pos] {
 			continue
 		}
 		mons := g.MonsterAt(pos)
-		if mons.Exists() && mons.State != Resting && RandInt(rmax) == 0 {
+		if mons.Exists() && mons.State != Resting && g.RandInt(rmax) == 0 {
 			switch mons.Kind {
 			case MonsMirrorSpecter, MonsSatowalgaPlant:
 				// no footsteps
@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"time"
 )
 
 func main() {
@@ -21,18 +22,102 @@ func main() {
 	opt8colors := flag.Bool("o", color8, "use only 8-color palette")
 	opt256colors := flag.Bool("x", !color8, "use xterm 256-color palette (solarized approximation)")
 	optNoAnim := flag.Bool("n", false, "no animations")
-	optReplay := flag.String("r", "", "path to replay file")
+	optReplay := flag.String("r", "", "path to replay file, or name of an archived game")
+	optSeed := flag.Int64("seed", 0, "seed for a reproducible new game (0 means random)")
+	optReplayInput := flag.String("replay-input", "", "path to input replay file, or name of an archived game")
+	optProfile := flag.String("profile", "", "name of the player profile")
+	optAsciicast := flag.String("export-asciicast", "", "export the replay given by -r (or the last one) to an asciicast file")
+	optGIF := flag.String("export-gif", "", "export the replay given by -r (or the last one) to an animated GIF file")
+	optGIFFPS := flag.Int("gif-fps", 10, "maximum number of frames per second of exported GIFs (0 for no limit)")
+	optGIFStart := flag.Int("gif-start", 0, "first replay frame of exported GIFs")
+	optGIFEnd := flag.Int("gif-end", 0, "last replay frame of exported GIFs (0 for the last one)")
+	optGIFTiles := flag.Bool("gif-tiles", false, "use tiles for the map in exported GIFs")
+	optBroadcast := flag.String("broadcast", "", "stream the game to spectators connecting to the given address (e.g. :8080)")
+	optWatch := flag.String("watch", "", "watch the game broadcast at the given address (e.g. localhost:8080)")
+	optTelnet := flag.String("telnet", "", "serve games to telnet clients connecting to the given address (e.g. :2323)")
+	optSmall := flag.Bool("small", false, "use the small 80x24 layout")
+	optStats := flag.Bool("stats", false, "print statistics aggregated over finished runs")
 	flag.Parse()
-	if *optSolarized {
-		SolarizedPalette()
-	} else if color8 && !*opt256colors || !color8 && *opt8colors {
-		SolarizedPalette()
-		Simple8ColorPalette()
+	if *optSmall {
+		ForcedSmall = true
+	}
+	if err := SetProfile(*optProfile); err != nil {
+		log.Printf("boohu: %v\n", err)
+		os.Exit(1)
+	}
+	if color8 {
+		DefaultPalette = Palette8
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "s", "o", "x":
+			if *optSolarized {
+				ForcedPalette = PaletteSolarized
+			} else if color8 && !*opt256colors || !color8 && *opt8colors {
+				ForcedPalette = Palette8
+			} else {
+				ForcedPalette = Palette256
+			}
+		}
+	})
+	if ForcedPalette != "" {
+		ApplyPalette(ForcedPalette)
+	} else {
+		ApplyPalette(DefaultPalette)
 	}
 	if *optVersion {
 		fmt.Println(Version)
 		os.Exit(0)
 	}
+	if *optAsciicast != "" {
+		file := *optReplay
+		if file == "" {
+			file = "_"
+		}
+		err := ExportAsciicast(file, *optAsciicast)
+		if err != nil {
+			log.Printf("boohu: asciicast export: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *optGIF != "" {
+		file := *optReplay
+		if file == "" {
+			file = "_"
+		}
+		opts := gifOptions{MaxFPS: *optGIFFPS, Start: *optGIFStart, End: *optGIFEnd, Tiles: *optGIFTiles}
+		err := ExportGIF(file, *optGIF, opts)
+		if err != nil {
+			log.Printf("boohu: GIF export: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *optStats {
+		err := PrintStats(os.Stdout)
+		if err != nil {
+			log.Printf("boohu: stats: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *optTelnet != "" {
+		err := ServeTelnet(*optTelnet)
+		if err != nil {
+			log.Printf("boohu: telnet server: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *optWatch != "" {
+		err := Watch(*optWatch)
+		if err != nil {
+			log.Printf("boohu: watch: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 	if *optReplay != "" {
 		err := Replay(*optReplay)
 		if err != nil {
@@ -41,15 +126,37 @@ func main() {
 		}
 		os.Exit(0)
 	}
+	if *optReplayInput != "" {
+		if *optCenteredCamera {
+			CenteredCamera = true
+		}
+		err := ReplayInput(*optReplayInput)
+		if err != nil {
+			log.Printf("boohu: input replay: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 	if *optCenteredCamera {
 		CenteredCamera = true
 	}
 	if *optNoAnim {
+		ForcedNoAnimations = true
 		DisableAnimations = true
 	}
 
+	if *optBroadcast != "" {
+		b, err := StartBroadcast(*optBroadcast)
+		if err != nil {
+			log.Printf("boohu: broadcast: %v\n", err)
+			os.Exit(1)
+		}
+		defer b.Close()
+		Broadcast = b
+	}
+
 	ui := &gameui{}
-	g := &game{}
+	g := &game{Seed: *optSeed}
 	ui.g = g
 	err := ui.Init()
 	if err != nil {
@@ -57,26 +164,47 @@ func main() {
 		os.Exit(1)
 	}
 	defer ui.Close()
+	ui.PlayGame()
+}
 
-	LinkColors()
-	GameConfig.DarkLOS = true
+// PlayGame runs a game from the welcome screen to its end.
+func (ui *gameui) PlayGame() {
+	g := ui.g
+	defer func() {
+		if r := recover(); r != nil {
+			// keep the inputs that led to the crash
+			g.SaveInputRecord()
+			panic(r)
+		}
+	}()
 
-	load, err := g.LoadConfig()
-	var cfgerrstr string
-	var cfgreseterr string
-	if load && err != nil {
-		cfgerrstr = fmt.Sprintf("Error loading config: %s", err.Error())
-		err = g.SaveConfig()
-		if err != nil {
-			cfgreseterr = fmt.Sprintf("Error resetting config: %s", err.Error())
+	LinkColors()
+	cfgerrs := ui.LoadProfileConfig()
+	action := ui.DrawWelcome()
+	for action == StartProfile || action == StartHallOfFame {
+		switch action {
+		case StartProfile:
+			if ui.ChooseProfile() {
+				cfgerrs = ui.LoadProfileConfig()
+			}
+		case StartHallOfFame:
+			ui.HallOfFame()
+		}
+		action = ui.DrawWelcome()
+	}
+	if g.hangup {
+		return
+	}
+	load, err := g.Load()
+	resumed := load && err == nil
+	var dailyerr string
+	if action == StartDaily {
+		if load && err == nil {
+			dailyerr = "You have a saved game in progress: finish it before attempting the daily challenge."
+		} else if errd := g.StartDaily(time.Now()); errd != nil {
+			dailyerr = errd.Error()
 		}
-	} else if load {
-		CustomKeys = true
 	}
-	ApplyConfig()
-	ui.PostConfig()
-	ui.DrawWelcome()
-	load, err = g.Load()
 	if !load {
 		g.InitLevel()
 	} else if err != nil {
@@ -86,12 +214,16 @@ func main() {
 	} else {
 		ui.DrawBufferInit()
 	}
-	if cfgerrstr != "" {
-		g.PrintStyled(cfgerrstr, logError)
+	for _, s := range cfgerrs {
+		g.PrintStyled(s, logError)
 	}
-	if cfgreseterr != "" {
-		g.PrintStyled(cfgreseterr, logError)
+	if dailyerr != "" {
+		g.PrintStyled(dailyerr, logError)
 	}
 	g.ui = ui
+	if err := g.StartReplayRecord(resumed); err != nil {
+		g.PrintfStyled("Error recording replay: %v", logError, err)
+	}
+	defer g.StopReplayRecord()
 	g.EventLoop()
 }
@@ -1,6 +1,9 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 type monsterState int
 
@@ -380,9 +383,15 @@ func (g *game) GenBand(mbd monsterBandData, band monsterBand) []monsterKind {
 	if !mbd.Band {
 		return []monsterKind{mbd.Monster}
 	}
+	kinds := []monsterKind{}
+	for m := range mbd.Distribution {
+		kinds = append(kinds, m)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
 	bandMonsters := []monsterKind{}
-	for m, interval := range mbd.Distribution {
-		for i := 0; i < interval.Min+RandInt(interval.Max-interval.Min+1); i++ {
+	for _, m := range kinds {
+		interval := mbd.Distribution[m]
+		for i := 0; i < interval.Min+g.RandInt(interval.Max-interval.Min+1); i++ {
 			bandMonsters = append(bandMonsters, m)
 		}
 	}
@@ -1677,8 +1686,8 @@ type monster struct {
 	Seen        bool
 }
 
-func (m *monster) Init() {
-	m.HPmax = MonsData[m.Kind].maxHP - 1 + RandInt(3)
+func (m *monster) Init(g *game) {
+	m.HPmax = MonsData[m.Kind].maxHP - 1 + g.RandInt(3)
 	m.Attack = MonsData[m.Kind].baseAttack
 	m.HP = m.HPmax
 	m.Accuracy = MonsData[m.Kind].accuracy
@@ -1779,13 +1788,13 @@ func (m *monster) SafePlacement(g *game) *position {
 }
 
 func (m *monster) TeleportPlayer(g *game, ev event) {
-	evasion := RandInt(g.Player.Evasion())
-	acc := RandInt(m.Accuracy)
+	evasion := g.RandInt(g.Player.Evasion())
+	acc := g.RandInt(m.Accuracy)
 	if acc > evasion {
 		g.Print("Marevor pushes you through a monolith.")
 		g.StoryPrint("Pushed by Marevor through a monolith.")
 		g.Teleportation(ev)
-	} else if RandInt(2) == 0 {
+	} else if g.RandInt(2) == 0 {
 		g.Print("Marevor inadvertently goes into a monolith.")
 		m.TeleportAway(g)
 	}
@@ -1822,7 +1831,7 @@ func (m *monster) TeleportAway(g *game) {
 	opos := m.Pos
 	m.MoveTo(g, pos)
 	if g.Player.LOS[opos] {
-		g.ui.TeleportAnimation(opos, pos, false)
+		g.Animations().TeleportAnimation(opos, pos, false)
 	}
 }
 
@@ -1850,7 +1859,7 @@ func (m *monster) PlaceAt(g *game, pos position) {
 func (m *monster) TeleportMonsterAway(g *game) bool {
 	neighbors := g.Dungeon.FreeNeighbors(m.Pos)
 	for _, pos := range neighbors {
-		if pos == m.Pos || RandInt(3) != 0 {
+		if pos == m.Pos || g.RandInt(3) != 0 {
 			continue
 		}
 		mons := g.MonsterAt(pos)
@@ -1905,9 +1914,9 @@ func (m *monster) HandleTurn(g *game, ev event) {
 	mpos := m.Pos
 	m.MakeAware(g)
 	if !g.Player.LOS[m.Pos] && m.State == Hunting {
-		if g.Player.Armour == HarmonistRobe && RandInt(2) == 0 ||
-			g.Player.Aptitudes[AptStealthyMovement] && RandInt(4) == 0 ||
-			RandInt(10) == 0 {
+		if g.Player.Armour == HarmonistRobe && g.RandInt(2) == 0 ||
+			g.Player.Aptitudes[AptStealthyMovement] && g.RandInt(4) == 0 ||
+			g.RandInt(10) == 0 {
 			m.State = Wandering
 		}
 	}
@@ -1916,7 +1925,7 @@ func (m *monster) HandleTurn(g *game, ev event) {
 		movedelay += 3
 	}
 	if m.State == Resting {
-		wander := RandInt(100 + 6*Max(800-(g.DepthPlayerTurn+1), 0))
+		wander := g.RandInt(100 + 6*Max(800-(g.DepthPlayerTurn+1), 0))
 		if wander == 0 {
 			m.NaturalAwake(g)
 		}
@@ -1995,7 +2004,7 @@ func (m *monster) HandleTurn(g *game, ev event) {
 	if len(m.Path) < 2 {
 		switch m.State {
 		case Wandering:
-			keepWandering := RandInt(100)
+			keepWandering := g.RandInt(100)
 			if keepWandering > 75 && g.BandData[g.Bands[m.Band]].Band {
 				for _, mons := range g.Monsters {
 					m.Target = mons.Pos
@@ -2007,7 +2016,7 @@ func (m *monster) HandleTurn(g *game, ev event) {
 		case Hunting:
 			// pick a random cell: more escape strategies for the player
 			if m.Kind == MonsHound && m.Pos.Distance(g.Player.Pos) <= 6 &&
-				!(g.Player.Aptitudes[AptStealthyMovement] && RandInt(2) == 0) {
+				!(g.Player.Aptitudes[AptStealthyMovement] && g.RandInt(2) == 0) {
 				m.Target = g.Player.Pos
 			} else {
 				m.Target = g.FreeCell()
@@ -2048,7 +2057,7 @@ func (m *monster) HandleTurn(g *game, ev event) {
 			m.Path = m.Path[:len(m.Path)-1]
 		}
 	case m.State == Hunting && mons.State != Hunting:
-		r := RandInt(5)
+		r := g.RandInt(5)
 		if r == 0 {
 			mons.Target = m.Target
 			mons.State = Wandering
@@ -2061,7 +2070,7 @@ func (m *monster) HandleTurn(g *game, ev event) {
 			m.Path = m.APath(g, mpos, m.Target)
 		}
 	case !g.Player.LOS[mons.Pos] && g.Player.Pos.Distance(mons.Target) > 2 && mons.State != Hunting:
-		r := RandInt(5)
+		r := g.RandInt(5)
 		if r == 0 {
 			m.Target = g.FreeCell()
 			m.GatherBand(g)
@@ -2078,7 +2087,7 @@ func (m *monster) HandleTurn(g *game, ev event) {
 			mons.Obstructing = true
 		}
 	case mons.State == Hunting && m.State == Hunting || !g.Player.LOS[m.Target]:
-		if RandInt(4) == 0 {
+		if g.RandInt(4) == 0 {
 			m.Target = mons.Target
 			m.Path = m.APath(g, mpos, m.Target)
 		} else {
@@ -2114,11 +2123,11 @@ func (m *monster) InvertFoliage(g *game) {
 func (m *monster) DramaticAdjustment(g *game, baseAttack, attack, evasion, acc int, clang bool) (int, int, bool) {
 	if attack >= g.Player.HP {
 		// a little dramatic effect
-		if RandInt(2) == 0 {
+		if g.RandInt(2) == 0 {
 			attack, clang = g.HitDamage(DmgPhysical, baseAttack, g.Player.Armor())
 		}
 		if attack >= g.Player.HP {
-			n := RandInt(g.Player.Evasion())
+			n := g.RandInt(g.Player.Evasion())
 			if n > evasion {
 				evasion = n
 			}
@@ -2131,7 +2140,7 @@ func (m *monster) DramaticAdjustment(g *game, baseAttack, attack, evasion, acc i
 }
 
 func (m *monster) Exhaust(g *game) {
-	m.ExhaustTime(g, 100+RandInt(50))
+	m.ExhaustTime(g, 100+g.RandInt(50))
 }
 
 func (m *monster) ExhaustTime(g *game, t int) {
@@ -2143,8 +2152,8 @@ func (m *monster) HitPlayer(g *game, ev event) {
 	if g.Player.HP <= 0 || g.Player.Pos.Distance(m.Pos) > 1 {
 		return
 	}
-	evasion := RandInt(g.Player.Evasion())
-	acc := RandInt(m.Accuracy)
+	evasion := g.RandInt(g.Player.Evasion())
+	acc := g.RandInt(m.Accuracy)
 	attack, clang := g.HitDamage(DmgPhysical, m.Attack, g.Player.Armor())
 	attack, evasion, clang = m.DramaticAdjustment(g, m.Attack, attack, evasion, acc, clang)
 	if acc > evasion {
@@ -2165,7 +2174,7 @@ func (m *monster) HitPlayer(g *game, ev event) {
 			sclang = g.ArmourClang()
 		}
 		g.PrintfStyled("%s hits you (%d dmg).%s", logMonsterHit, m.Kind.Definite(true), attack, sclang)
-		m.InflictDamage(g, attack, m.Attack)
+		m.InflictDamage(g, attack, m.Attack, m.DamageSource(""))
 		if m.Kind == MonsVampire {
 			healing := attack
 			if healing > 2*m.Attack/3 {
@@ -2181,14 +2190,14 @@ func (m *monster) HitPlayer(g *game, ev event) {
 		}
 		m.HitSideEffects(g, ev)
 		const HeavyWoundHP = 18
-		if g.Player.Aptitudes[AptConfusingGas] && g.Player.HP < HeavyWoundHP && RandInt(2) == 0 {
+		if g.Player.Aptitudes[AptConfusingGas] && g.Player.HP < HeavyWoundHP && g.RandInt(2) == 0 {
 			m.EnterConfusion(g, ev)
 			g.Printf("You release some confusing gas against the %s.", m.Kind)
 		}
-		if g.Player.Aptitudes[AptSmoke] && g.Player.HP < HeavyWoundHP && RandInt(2) == 0 {
+		if g.Player.Aptitudes[AptSmoke] && g.Player.HP < HeavyWoundHP && g.RandInt(2) == 0 {
 			g.Smoke(ev)
 		}
-		if g.Player.Aptitudes[AptObstruction] && g.Player.HP <= HeavyWoundHP && RandInt(2) == 0 {
+		if g.Player.Aptitudes[AptObstruction] && g.Player.HP <= HeavyWoundHP && g.RandInt(2) == 0 {
 			opos := m.Pos
 			m.Blink(g)
 			if opos != m.Pos {
@@ -2197,10 +2206,10 @@ func (m *monster) HitPlayer(g *game, ev event) {
 				m.Exhaust(g)
 			}
 		}
-		if g.Player.Aptitudes[AptTeleport] && g.Player.HP < HeavyWoundHP && RandInt(2) == 0 {
+		if g.Player.Aptitudes[AptTeleport] && g.Player.HP < HeavyWoundHP && g.RandInt(2) == 0 {
 			m.TeleportAway(g)
 		}
-		if g.Player.Aptitudes[AptLignification] && g.Player.HP < HeavyWoundHP && RandInt(2) == 0 {
+		if g.Player.Aptitudes[AptLignification] && g.Player.HP < HeavyWoundHP && g.RandInt(2) == 0 {
 			m.EnterLignification(g, ev)
 		}
 	} else {
@@ -2214,7 +2223,7 @@ func (m *monster) EnterConfusion(g *game, ev event) {
 		m.Statuses[MonsConfused] = 1
 		m.Path = m.Path[:0]
 		g.PushEvent(&monsterEvent{
-			ERank: ev.Rank() + 50 + RandInt(100), NMons: m.Index, EAction: MonsConfusionEnd})
+			ERank: ev.Rank() + 50 + g.RandInt(100), NMons: m.Index, EAction: MonsConfusionEnd})
 	}
 }
 
@@ -2223,7 +2232,7 @@ func (m *monster) EnterLignification(g *game, ev event) {
 		m.Statuses[MonsLignified] = 1
 		m.Path = m.Path[:0]
 		g.PushEvent(&monsterEvent{
-			ERank: ev.Rank() + 150 + RandInt(100), NMons: m.Index, EAction: MonsLignificationEnd})
+			ERank: ev.Rank() + 150 + g.RandInt(100), NMons: m.Index, EAction: MonsLignificationEnd})
 		if g.Player.LOS[m.Pos] {
 			g.Printf("%s is rooted to the ground.", m.Kind.Definite(true))
 		}
@@ -2233,26 +2242,26 @@ func (m *monster) EnterLignification(g *game, ev event) {
 func (m *monster) HitSideEffects(g *game, ev event) {
 	switch m.Kind {
 	case MonsSpider:
-		if RandInt(2) == 0 {
+		if g.RandInt(2) == 0 {
 			g.Confusion(ev)
 		}
 	case MonsGiantBee:
-		if RandInt(5) == 0 && !g.Player.HasStatus(StatusBerserk) && !g.Player.HasStatus(StatusExhausted) {
+		if g.RandInt(5) == 0 && !g.Player.HasStatus(StatusBerserk) && !g.Player.HasStatus(StatusExhausted) {
 			g.Player.Statuses[StatusBerserk] = 1
 			g.Player.HP += 10
-			end := ev.Rank() + 25 + RandInt(30)
+			end := ev.Rank() + 25 + g.RandInt(30)
 			g.PushEvent(&simpleEvent{ERank: end, EAction: BerserkEnd})
 			g.Player.Expire[StatusBerserk] = end
 			g.Print("You feel a sudden urge to kill things.")
 		}
 	case MonsBlinkingFrog:
-		if RandInt(2) == 0 {
+		if g.RandInt(2) == 0 {
 			g.Blink(ev)
 		}
 	case MonsAcidMound:
 		g.Corrosion(ev)
 	case MonsYack:
-		if RandInt(2) == 0 && m.PushPlayer(g) {
+		if g.RandInt(2) == 0 && m.PushPlayer(g) {
 			g.Print("The yack pushes you.")
 		}
 	case MonsWingedMilfid:
@@ -2263,7 +2272,7 @@ func (m *monster) HitSideEffects(g *game, ev event) {
 		m.MoveTo(g, g.Player.Pos)
 		g.PlacePlayerAt(ompos)
 		g.Print("The flying milfid makes you swap positions.")
-		m.ExhaustTime(g, 50+RandInt(50))
+		m.ExhaustTime(g, 50+g.RandInt(50))
 	}
 }
 
@@ -2348,12 +2357,12 @@ func (m *monster) TormentBolt(g *game, ev event) bool {
 		g.MakeNoise(MagicHitNoise, g.Player.Pos)
 		damage := g.Player.HP - g.Player.HP/2
 		g.PrintfStyled("%s throws a bolt of torment at you.", logMonsterHit, m.Kind.Definite(true))
-		g.ui.MonsterProjectileAnimation(g.Ray(m.Pos), '*', ColorCyan)
-		m.InflictDamage(g, damage, 15)
+		g.Animations().MonsterProjectileAnimation(g.Ray(m.Pos), '*', ColorCyan)
+		m.InflictDamage(g, damage, 15, m.DamageSource("bolt of torment"))
 	} else {
 		g.Printf("You block the %s's bolt of torment.", m.Kind)
 		g.BlockEffects(m)
-		g.ui.MonsterProjectileAnimation(g.Ray(m.Pos), '*', ColorCyan)
+		g.Animations().MonsterProjectileAnimation(g.Ray(m.Pos), '*', ColorCyan)
 	}
 	m.Exhaust(g)
 	ev.Renew(g, m.Kind.AttackDelay())
@@ -2363,8 +2372,8 @@ func (m *monster) TormentBolt(g *game, ev event) bool {
 func (m *monster) Blocked(g *game) bool {
 	blocked := false
 	if g.Player.Shield != NoShield && !g.Player.Weapon.TwoHanded() && !g.Player.Blocked {
-		block := RandInt(g.Player.Block())
-		acc := RandInt(m.Accuracy)
+		block := g.RandInt(g.Player.Block())
+		acc := g.RandInt(m.Accuracy)
 		if block >= acc {
 			blocked = true
 		}
@@ -2379,8 +2388,8 @@ func (m *monster) ThrowRock(g *game, ev event) bool {
 	}
 	block := false
 	hit := true
-	evasion := RandInt(g.Player.Evasion())
-	acc := RandInt(m.Accuracy)
+	evasion := g.RandInt(g.Player.Evasion())
+	acc := g.RandInt(m.Accuracy)
 	const rockdmg = 15
 	attack, clang := g.HitDamage(DmgPhysical, rockdmg, g.Player.Armor())
 	attack, evasion, clang = m.DramaticAdjustment(g, rockdmg, attack, evasion, acc, clang)
@@ -2399,7 +2408,7 @@ func (m *monster) ThrowRock(g *game, ev event) bool {
 			sclang = g.ArmourClang()
 		}
 		g.PrintfStyled("%s throws a rock at you (%d dmg).%s", logMonsterHit, m.Kind.Definite(true), attack, sclang)
-		g.ui.MonsterProjectileAnimation(g.Ray(m.Pos), '●', ColorMagenta)
+		g.Animations().MonsterProjectileAnimation(g.Ray(m.Pos), '●', ColorMagenta)
 		oppos := g.Player.Pos
 		if m.PushPlayer(g) {
 			g.TemporalWallAt(oppos, ev)
@@ -2409,12 +2418,12 @@ func (m *monster) ThrowRock(g *game, ev event) bool {
 				g.TemporalWallAt(ray[len(ray)-1], ev)
 			}
 		}
-		m.InflictDamage(g, attack, rockdmg)
+		m.InflictDamage(g, attack, rockdmg, m.DamageSource("rock"))
 	} else if block {
 		g.Printf("You block %s's rock. Clang!", m.Kind.Indefinite(false))
 		g.MakeNoise(ShieldBlockNoise, g.Player.Pos)
 		g.BlockEffects(m)
-		g.ui.MonsterProjectileAnimation(g.Ray(m.Pos), '●', ColorMagenta)
+		g.Animations().MonsterProjectileAnimation(g.Ray(m.Pos), '●', ColorMagenta)
 		ray := g.Ray(m.Pos)
 		if len(ray) > 0 {
 			g.TemporalWallAt(ray[len(ray)-1], ev)
@@ -2422,13 +2431,13 @@ func (m *monster) ThrowRock(g *game, ev event) bool {
 	} else {
 		g.Stats.Dodges++
 		g.Printf("You dodge %s's rock.", m.Kind.Indefinite(false))
-		g.ui.MonsterProjectileAnimation(g.Ray(m.Pos), '●', ColorMagenta)
+		g.Animations().MonsterProjectileAnimation(g.Ray(m.Pos), '●', ColorMagenta)
 		dir := g.Player.Pos.Dir(m.Pos)
 		pos := g.Player.Pos.To(dir)
 		if pos.valid() {
 			mons := g.MonsterAt(pos)
 			if mons.Exists() {
-				mons.HP -= RandInt(15)
+				mons.HP -= g.RandInt(15)
 				if mons.HP <= 0 {
 					g.HandleKill(mons, ev)
 				} else {
@@ -2452,7 +2461,7 @@ func (m *monster) VampireSpit(g *game, ev event) bool {
 		return false
 	}
 	g.Player.Statuses[StatusNausea]++
-	g.PushEvent(&simpleEvent{ERank: ev.Rank() + 30 + RandInt(20), EAction: NauseaEnd})
+	g.PushEvent(&simpleEvent{ERank: ev.Rank() + 30 + g.RandInt(20), EAction: NauseaEnd})
 	g.Print("The vampire spits at you. You feel sick.")
 	m.Exhaust(g)
 	ev.Renew(g, m.Kind.AttackDelay())
@@ -2478,8 +2487,8 @@ func (m *monster) ThrowJavelin(g *game, ev event) bool {
 	}
 	block := false
 	hit := true
-	evasion := RandInt(g.Player.Evasion())
-	acc := RandInt(m.Accuracy)
+	evasion := g.RandInt(g.Player.Evasion())
+	acc := g.RandInt(m.Accuracy)
 	const jdmg = 11
 	attack, clang := g.HitDamage(DmgPhysical, jdmg, g.Player.Armor())
 	attack, evasion, clang = m.DramaticAdjustment(g, jdmg, attack, evasion, acc, clang)
@@ -2497,27 +2506,27 @@ func (m *monster) ThrowJavelin(g *game, ev event) bool {
 			sclang = g.ArmourClang()
 		}
 		g.Printf("%s throws %s at you (%d dmg).%s", m.Kind.Definite(true), Indefinite("javelin", false), attack, sclang)
-		g.ui.MonsterJavelinAnimation(g.Ray(m.Pos), true)
-		m.InflictDamage(g, attack, jdmg)
+		g.Animations().MonsterJavelinAnimation(g.Ray(m.Pos), true)
+		m.InflictDamage(g, attack, jdmg, m.DamageSource("javelin"))
 	} else if block {
-		if RandInt(3) == 0 {
+		if g.RandInt(3) == 0 {
 			g.Printf("You block %s's %s. Clang!", m.Kind.Indefinite(false), "javelin")
 			g.MakeNoise(ShieldBlockNoise, g.Player.Pos)
 			g.BlockEffects(m)
-			g.ui.MonsterJavelinAnimation(g.Ray(m.Pos), false)
+			g.Animations().MonsterJavelinAnimation(g.Ray(m.Pos), false)
 		} else if !g.Player.HasStatus(StatusDisabledShield) {
 			g.Player.Statuses[StatusDisabledShield] = 1
-			g.PushEvent(&simpleEvent{ERank: ev.Rank() + 100 + RandInt(100), EAction: DisabledShieldEnd})
+			g.PushEvent(&simpleEvent{ERank: ev.Rank() + 100 + g.RandInt(100), EAction: DisabledShieldEnd})
 			g.Printf("%s's %s gets embedded in your shield.", m.Kind.Indefinite(true), "javelin")
 			g.MakeNoise(ShieldBlockNoise, g.Player.Pos)
-			g.ui.MonsterJavelinAnimation(g.Ray(m.Pos), false)
+			g.Animations().MonsterJavelinAnimation(g.Ray(m.Pos), false)
 		}
 	} else {
 		g.Stats.Dodges++
 		g.Printf("You dodge %s's %s.", m.Kind.Indefinite(false), "javelin")
-		g.ui.MonsterJavelinAnimation(g.Ray(m.Pos), false)
+		g.Animations().MonsterJavelinAnimation(g.Ray(m.Pos), false)
 	}
-	m.ExhaustTime(g, 50+RandInt(50))
+	m.ExhaustTime(g, 50+g.RandInt(50))
 	ev.Renew(g, m.Kind.AttackDelay())
 	return true
 }
@@ -2529,8 +2538,8 @@ func (m *monster) ThrowAcid(g *game, ev event) bool {
 	}
 	block := false
 	hit := true
-	evasion := RandInt(g.Player.Evasion())
-	acc := RandInt(m.Accuracy)
+	evasion := g.RandInt(g.Player.Evasion())
+	acc := g.RandInt(m.Accuracy)
 	acdmg := 12
 	attack, clang := g.HitDamage(DmgPhysical, acdmg, g.Player.Armor())
 	attack, evasion, clang = m.DramaticAdjustment(g, acdmg, attack, evasion, acc, clang)
@@ -2544,25 +2553,25 @@ func (m *monster) ThrowAcid(g *game, ev event) bool {
 		noise := g.HitNoise(false) // no clang with acid projectiles
 		g.MakeNoise(noise, g.Player.Pos)
 		g.Printf("%s throws acid at you (%d dmg).", m.Kind.Definite(true), attack)
-		g.ui.MonsterProjectileAnimation(g.Ray(m.Pos), '*', ColorGreen)
-		m.InflictDamage(g, attack, acdmg)
-		if RandInt(2) == 0 {
+		g.Animations().MonsterProjectileAnimation(g.Ray(m.Pos), '*', ColorGreen)
+		m.InflictDamage(g, attack, acdmg, m.DamageSource("acid"))
+		if g.RandInt(2) == 0 {
 			g.Corrosion(ev)
-			if RandInt(2) == 0 {
+			if g.RandInt(2) == 0 {
 				g.Confusion(ev)
 			}
 		}
 	} else if block {
 		g.Printf("You block %s's acid projectile.", m.Kind.Indefinite(false))
 		g.MakeNoise(BaseHitNoise, g.Player.Pos) // no real clang
-		g.ui.MonsterProjectileAnimation(g.Ray(m.Pos), '*', ColorGreen)
-		if RandInt(2) == 0 {
+		g.Animations().MonsterProjectileAnimation(g.Ray(m.Pos), '*', ColorGreen)
+		if g.RandInt(2) == 0 {
 			g.Corrosion(ev)
 		}
 	} else {
 		g.Stats.Dodges++
 		g.Printf("You dodge %s's acid projectile.", m.Kind.Indefinite(false))
-		g.ui.MonsterProjectileAnimation(g.Ray(m.Pos), '*', ColorGreen)
+		g.Animations().MonsterProjectileAnimation(g.Ray(m.Pos), '*', ColorGreen)
 	}
 	ev.Renew(g, m.Kind.AttackDelay())
 	return true
@@ -2576,10 +2585,10 @@ func (m *monster) NixeAttraction(g *game, ev event) bool {
 	g.MakeNoise(9, m.Pos)
 	g.PrintfStyled("%s lures you to her.", logMonsterHit, m.Kind.Definite(true))
 	ray := g.Ray(m.Pos)
-	g.ui.MonsterProjectileAnimation(ray, 'θ', ColorCyan) // TODO: improve
+	g.Animations().MonsterProjectileAnimation(ray, 'θ', ColorCyan) // TODO: improve
 	if len(ray) > 1 {
 		// should always be the case
-		g.ui.TeleportAnimation(g.Player.Pos, ray[1], true)
+		g.Animations().TeleportAnimation(g.Player.Pos, ray[1], true)
 		g.PlacePlayerAt(ray[1])
 	}
 	m.Exhaust(g)
@@ -2622,27 +2631,27 @@ func (m *monster) AbsorbMana(g *game, ev event) bool {
 	}
 	g.Player.MP -= 1
 	g.Printf("%s absorbs your mana.", m.Kind.Definite(true))
-	m.ExhaustTime(g, 10+RandInt(10))
+	m.ExhaustTime(g, 10+g.RandInt(10))
 	ev.Renew(g, m.Kind.AttackDelay())
 	return true
 }
 
 func (m *monster) MindAttack(g *game, ev event) bool {
-	if g.Player.Pos.Distance(m.Pos) == 1 && (m.HP < m.HPmax || RandInt(2) == 0) {
+	if g.Player.Pos.Distance(m.Pos) == 1 && (m.HP < m.HPmax || g.RandInt(2) == 0) {
 		// try to avoid melee
 		safepos := m.SafePlacement(g)
 		if safepos != nil {
 			return false
 		}
 	}
-	dmg := 3 + RandInt(m.Attack) + RandInt(m.Attack) + RandInt(m.Attack)
+	dmg := 3 + g.RandInt(m.Attack) + g.RandInt(m.Attack) + g.RandInt(m.Attack)
 	dmg /= 3
-	m.InflictDamage(g, dmg, m.Attack)
+	m.InflictDamage(g, dmg, m.Attack, m.DamageSource("mind attack"))
 	g.Printf("The celmist mage hurts your mind (%d dmg).", dmg)
-	if RandInt(2) == 0 {
-		if RandInt(2) == 0 {
+	if g.RandInt(2) == 0 {
+		if g.RandInt(2) == 0 {
 			g.Player.Statuses[StatusSlow]++
-			g.PushEvent(&simpleEvent{ERank: ev.Rank() + 30 + RandInt(10), EAction: SlowEnd})
+			g.PushEvent(&simpleEvent{ERank: ev.Rank() + 30 + g.RandInt(10), EAction: SlowEnd})
 		} else {
 			g.Confusion(ev)
 		}
@@ -2655,7 +2664,7 @@ func (m *monster) Explode(g *game, ev event) {
 	neighbors := m.Pos.ValidNeighbors()
 	g.MakeNoise(WallNoise, m.Pos)
 	g.Printf("%s %s explodes with a loud boom.", g.ExplosionSound(), m.Kind.Definite(true))
-	g.ui.ExplosionAnimation(FireExplosion, m.Pos)
+	g.Animations().ExplosionAnimation(FireExplosion, m.Pos)
 	for _, pos := range append(neighbors, m.Pos) {
 		c := g.Dungeon.Cell(pos)
 		if c.T == FreeCell {
@@ -2672,14 +2681,14 @@ func (m *monster) Explode(g *game, ev event) {
 			mons.MakeHuntIfHurt(g)
 		} else if g.Player.Pos == pos {
 			dmg := g.Player.HP / 2
-			m.InflictDamage(g, dmg, 15)
-		} else if c.T == WallCell && RandInt(2) == 0 {
+			m.InflictDamage(g, dmg, 15, m.DamageSource("explosion"))
+		} else if c.T == WallCell && g.RandInt(2) == 0 {
 			g.Dungeon.SetCell(pos, FreeCell)
 			g.Stats.Digs++
 			if !g.Player.LOS[pos] {
 				g.WrongWall[pos] = true
 			} else {
-				g.ui.WallExplosionAnimation(pos)
+				g.Animations().WallExplosionAnimation(pos)
 			}
 			g.MakeNoise(WallNoise, pos)
 			g.Fog(pos, 1, ev)
@@ -2694,7 +2703,7 @@ func (m *monster) Blink(g *game) {
 	}
 	opos := m.Pos
 	g.Printf("The %s blinks away.", m.Kind)
-	g.ui.TeleportAnimation(opos, npos, true)
+	g.Animations().TeleportAnimation(opos, npos, true)
 	m.MoveTo(g, npos)
 }
 
@@ -2733,7 +2742,7 @@ func (m *monster) MakeAware(g *game) {
 		return
 	}
 	if m.State == Resting {
-		if m.Status(MonsExhausted) && (m.Pos.Distance(g.Player.Pos) > 1 || RandInt(3) > 0) {
+		if m.Status(MonsExhausted) && (m.Pos.Distance(g.Player.Pos) > 1 || g.RandInt(3) > 0) {
 			return
 		}
 		adjust := g.LosRange() - m.Pos.Distance(g.Player.Pos)
@@ -2751,7 +2760,7 @@ func (m *monster) MakeAware(g *game) {
 		} else if stealth > 15 {
 			stealth = 15
 		}
-		r := RandInt(stealth)
+		r := g.RandInt(stealth)
 		if g.Player.Aptitudes[AptStealthyMovement] {
 			r *= fact
 		}
@@ -2772,7 +2781,7 @@ func (m *monster) MakeAware(g *game) {
 			max += 10
 		}
 		stealth := max - 4*adjust
-		r := RandInt(stealth)
+		r := g.RandInt(stealth)
 		if g.Player.Aptitudes[AptStealthyMovement] {
 			r *= 2
 		}
@@ -2816,10 +2825,10 @@ func (m *monster) GatherBand(g *game) {
 				continue
 			}
 			n, ok := nm[mons.Pos]
-			if !ok || n.Cost > 4 || mons.State == Resting && mons.Status(MonsExhausted) && RandInt(2) == 0 {
+			if !ok || n.Cost > 4 || mons.State == Resting && mons.Status(MonsExhausted) && g.RandInt(2) == 0 {
 				continue
 			}
-			r := RandInt(100)
+			r := g.RandInt(100)
 			if r > 50 || mons.State == Wandering && r > 10 {
 				mons.Target = m.Target
 				if mons.State == Resting {
@@ -2952,7 +2961,7 @@ func (g *game) GenMonsters() {
 loop:
 	for danger > 0 && nmons > 0 {
 		for band, data := range g.BandData {
-			if RandInt(data.Rarity*50) != 0 {
+			if g.RandInt(data.Rarity*50) != 0 {
 				continue
 			}
 			monsters := g.GenBand(data, monsterBand(band))
@@ -2981,7 +2990,7 @@ loop:
 				danger -= mk.Dangerousness()
 				nmons--
 				mons := &monster{Kind: mk}
-				mons.Init()
+				mons.Init(g)
 				mons.Index = i
 				mons.Band = nband
 				mons.PlaceAt(g, pos)
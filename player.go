@@ -172,6 +172,27 @@ func (g *game) GoToDir(dir direction, ev event) error {
 	return nil
 }
 
+func (g *game) GoToStairs(ev event) error {
+	stairs := g.StairsSlice()
+	sortedStairs := g.SortedNearestTo(stairs, g.Player.Pos)
+	if len(sortedStairs) == 0 {
+		return errors.New("You cannot go to any stairs.")
+	}
+	stair := sortedStairs[0]
+	if g.Player.Pos == stair {
+		return errors.New("You are already on the stairs.")
+	}
+	ex := &examiner{stairs: true}
+	err := ex.Action(g, stair)
+	if err == nil && !g.MoveToTarget(ev) {
+		err = errors.New("You could not move toward stairs.")
+	}
+	if ex.Done() {
+		g.Targeting = InvalidPos
+	}
+	return err
+}
+
 func (g *game) MoveToTarget(ev event) bool {
 	if !g.AutoTarget.valid() {
 		return false
@@ -324,7 +345,7 @@ func (g *game) Teleportation(ev event) {
 		// should always happen
 		opos := g.Player.Pos
 		g.Print("You teleport away.")
-		g.ui.TeleportAnimation(opos, pos, true)
+		g.Animations().TeleportAnimation(opos, pos, true)
 		g.PlacePlayerAt(pos)
 	} else {
 		// should not happen
@@ -418,7 +439,7 @@ func (g *game) MovePlayer(pos position, ev event) error {
 			_, ok := g.Clouds[g.Player.Pos]
 			if !ok {
 				g.Clouds[g.Player.Pos] = CloudFog
-				g.PushEvent(&cloudEvent{ERank: ev.Rank() + 15 + RandInt(10), EAction: CloudEnd, Pos: g.Player.Pos})
+				g.PushEvent(&cloudEvent{ERank: ev.Rank() + 15 + g.RandInt(10), EAction: CloudEnd, Pos: g.Player.Pos})
 			}
 		}
 		if g.Player.HasStatus(StatusSwift) {
@@ -469,15 +490,15 @@ func (g *game) MPRegen(ev event) {
 func (g *game) Smoke(ev event) {
 	dij := &normalPath{game: g}
 	nm := Dijkstra(dij, []position{g.Player.Pos}, 2)
-	for pos := range nm {
+	for _, pos := range nm.SortedPositions() {
 		_, ok := g.Clouds[pos]
 		if !ok {
 			g.Clouds[pos] = CloudFog
-			g.PushEvent(&cloudEvent{ERank: ev.Rank() + 100 + RandInt(100), EAction: CloudEnd, Pos: pos})
+			g.PushEvent(&cloudEvent{ERank: ev.Rank() + 100 + g.RandInt(100), EAction: CloudEnd, Pos: pos})
 		}
 	}
 	g.Player.Statuses[StatusSwift]++
-	end := ev.Rank() + 20 + RandInt(10)
+	end := ev.Rank() + 20 + g.RandInt(10)
 	g.PushEvent(&simpleEvent{ERank: end, EAction: HasteEnd})
 	g.Player.Expire[StatusSwift] = end
 	g.ComputeLOS()
@@ -486,14 +507,14 @@ func (g *game) Smoke(ev event) {
 
 func (g *game) Corrosion(ev event) {
 	g.Player.Statuses[StatusCorrosion]++
-	g.PushEvent(&simpleEvent{ERank: ev.Rank() + 80 + RandInt(40), EAction: CorrosionEnd})
+	g.PushEvent(&simpleEvent{ERank: ev.Rank() + 80 + g.RandInt(40), EAction: CorrosionEnd})
 	g.Print("Your equipment gets corroded.")
 }
 
 func (g *game) Confusion(ev event) {
 	if !g.Player.HasStatus(StatusConfusion) {
 		g.Player.Statuses[StatusConfusion]++
-		g.PushEvent(&simpleEvent{ERank: ev.Rank() + 100 + RandInt(100), EAction: ConfusionEnd})
+		g.PushEvent(&simpleEvent{ERank: ev.Rank() + 100 + g.RandInt(100), EAction: ConfusionEnd})
 		g.Print("You feel confused.")
 	}
 }
@@ -507,6 +528,6 @@ func (g *game) PlacePlayerAt(pos position) {
 
 func (g *game) EnterLignification(ev event) {
 	g.Player.Statuses[StatusLignification]++
-	g.PushEvent(&simpleEvent{ERank: ev.Rank() + 150 + RandInt(100), EAction: LignificationEnd})
+	g.PushEvent(&simpleEvent{ERank: ev.Rank() + 150 + g.RandInt(100), EAction: LignificationEnd})
 	g.Player.HP += 10
 }
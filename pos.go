@@ -1,6 +1,9 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 type position struct {
 	X int
@@ -102,6 +105,28 @@ func KeyToDir(k keyAction) (dir direction) {
 	return dir
 }
 
+func DirToKey(dir direction) (k keyAction) {
+	switch dir {
+	case W:
+		k = KeyW
+	case E:
+		k = KeyE
+	case S:
+		k = KeyS
+	case N:
+		k = KeyN
+	case NW:
+		k = KeyNW
+	case SW:
+		k = KeySW
+	case NE:
+		k = KeyNE
+	case SE:
+		k = KeySE
+	}
+	return k
+}
+
 func (pos position) To(dir direction) position {
 	to := pos
 	switch dir {
@@ -217,37 +242,37 @@ func (pos position) Parents(from position) []position {
 	return p
 }
 
-func (pos position) RandomNeighbor(diag bool) position {
+func (d *dungeon) RandomNeighbor(pos position, diag bool) position {
 	if diag {
-		return pos.RandomNeighborDiagonals()
+		return d.RandomNeighborDiagonals(pos)
 	}
-	return pos.RandomNeighborCardinal()
+	return d.RandomNeighborCardinal(pos)
 }
 
-func (pos position) RandomNeighborDiagonals() position {
+func (d *dungeon) RandomNeighborDiagonals(pos position) position {
 	neighbors := [8]position{pos.E(), pos.W(), pos.N(), pos.S(), pos.NE(), pos.NW(), pos.SE(), pos.SW()}
 	var r int
-	switch RandInt(8) {
+	switch d.RandInt(8) {
 	case 0:
-		r = RandInt(len(neighbors[0:4]))
+		r = d.RandInt(len(neighbors[0:4]))
 	case 1:
-		r = RandInt(len(neighbors[0:2]))
+		r = d.RandInt(len(neighbors[0:2]))
 	default:
-		r = RandInt(len(neighbors[4:]))
+		r = d.RandInt(len(neighbors[4:]))
 	}
 	return neighbors[r]
 }
 
-func (pos position) RandomNeighborCardinal() position {
+func (d *dungeon) RandomNeighborCardinal(pos position) position {
 	neighbors := [8]position{pos.E(), pos.W(), pos.N(), pos.S(), pos.NE(), pos.NW(), pos.SE(), pos.SW()}
 	var r int
-	switch RandInt(6) {
+	switch d.RandInt(6) {
 	case 0:
-		r = RandInt(len(neighbors[0:4]))
+		r = d.RandInt(len(neighbors[0:4]))
 	case 1:
-		r = RandInt(len(neighbors))
+		r = d.RandInt(len(neighbors))
 	default:
-		r = RandInt(len(neighbors[0:2]))
+		r = d.RandInt(len(neighbors[0:2]))
 	}
 	return neighbors[r]
 }
@@ -260,6 +285,21 @@ func (pos position) idx() int {
 	return pos.Y*DungeonWidth + pos.X
 }
 
+// SortedPositions returns the positions of a set in a fixed order, so that
+// random generation does not depend on map iteration order.
+func SortedPositions(set map[position]bool) []position {
+	ps := make([]position, 0, len(set))
+	for pos := range set {
+		ps = append(ps, pos)
+	}
+	sortPositions(ps)
+	return ps
+}
+
+func sortPositions(ps []position) {
+	sort.Slice(ps, func(i, j int) bool { return ps[i].idx() < ps[j].idx() })
+}
+
 func (pos position) valid() bool {
 	return pos.Y >= 0 && pos.Y < DungeonHeight && pos.X >= 0 && pos.X < DungeonWidth
 }
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Profile is the name of the current player profile. Each profile has its
+// own subdirectory in the data directory, with independent saves, config,
+// dumps and history. The default profile (empty name) uses the data
+// directory itself.
+var Profile string
+
+// ProfileLocked forbids changing profile, as in telnet sessions, where the
+// profile is the login name.
+var ProfileLocked bool
+
+const maxProfileLength = 20
+
+func ValidProfileName(name string) bool {
+	if name == "" || len(name) > maxProfileLength {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func SetProfile(name string) error {
+	if name != "" && !ValidProfileName(name) {
+		return fmt.Errorf("invalid profile name “%s” (use at most %d letters, digits, “-” or “_”)", name, maxProfileLength)
+	}
+	Profile = name
+	return nil
+}
+
+// Profiles returns the names of existing profiles, without the default
+// one.
+func Profiles() []string {
+	names := []string{}
+	files, err := ioutil.ReadDir(filepath.Join(BaseDataDir(), "profiles"))
+	if err != nil {
+		return names
+	}
+	for _, fi := range files {
+		if fi.IsDir() && ValidProfileName(fi.Name()) {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadProfileConfig loads the configuration of the current profile. It
+// returns messages about errors, if any.
+func (ui *gameui) LoadProfileConfig() []string {
+	g := ui.g
+	if GameConfig.Small {
+		ui.ApplyToggleLayout()
+	}
+	GameConfig = config{DarkLOS: true}
+	CustomKeys = false
+	errs := []string{}
+	load, err := g.LoadConfig()
+	if serr, ok := err.(configSyntaxError); ok {
+		for _, s := range serr {
+			errs = append(errs, fmt.Sprintf("Invalid config: %s", s))
+		}
+		CustomKeys = true
+	} else if load && err != nil {
+		errs = append(errs, fmt.Sprintf("Error loading config: %s", err.Error()))
+		err = g.SaveConfig()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("Error resetting config: %s", err.Error()))
+		}
+	} else if load {
+		CustomKeys = true
+	}
+	ApplyConfig()
+	ui.PostConfig()
+	return errs
+}
+
+func ProfileString(name string) string {
+	if name == "" {
+		return "default"
+	}
+	return name
+}
+
+// ChooseProfile shows the profile menu. It returns true if the current
+// profile changed.
+func (ui *gameui) ChooseProfile() bool {
+	profiles := append([]string{""}, Profiles()...)
+	if len(profiles) > 20 {
+		profiles = profiles[:20]
+	}
+	for {
+		ui.DrawBufferInit()
+		ui.Clear()
+		ui.DrawColoredText("Choose a profile:", 1, 1, ColorCyan)
+		for i, p := range profiles {
+			fg := ColorFg
+			if p == Profile {
+				fg = ColorYellow
+			}
+			ui.DrawColoredText(fmt.Sprintf("(%c) %s", 'a'+i, ProfileString(p)), 3, 3+i, fg)
+		}
+		ui.DrawColoredText("(+) new profile", 3, 4+len(profiles), ColorFg)
+		ui.DrawColoredText("(esc) back", 3, 5+len(profiles), ColorFg)
+		ui.Flush()
+		in := ui.PollEvent()
+		switch in.key {
+		case "\x1b", " ":
+			return false
+		case "+":
+			name, err := ui.ReadProfileName(7 + len(profiles))
+			if err != nil {
+				continue
+			}
+			changed := name != Profile
+			Profile = name
+			return changed
+		}
+		if len(in.key) == 1 && in.key[0] >= 'a' && int(in.key[0]-'a') < len(profiles) {
+			p := profiles[in.key[0]-'a']
+			changed := p != Profile
+			Profile = p
+			return changed
+		}
+	}
+}
+
+func (ui *gameui) ReadProfileName(line int) (string, error) {
+	name := ""
+	for {
+		ui.DrawColoredText(fmt.Sprintf("New profile name: %-*s", maxProfileLength, name), 1, line, ColorFg)
+		ui.Flush()
+		in := ui.PollEvent()
+		switch in.key {
+		case "\x1b", " ":
+			return "", errors.New("cancelled")
+		case "\r", "\n", ".":
+			if ValidProfileName(name) {
+				return name, nil
+			}
+		case "\x7f", "\b":
+			if len(name) > 0 {
+				name = name[:len(name)-1]
+			}
+		default:
+			if ValidProfileName(name + in.key) {
+				name += in.key
+			}
+		}
+	}
+}
+
+// BaseDataDir returns the data directory shared by all profiles.
+func BaseDataDir() string {
+	var xdg string
+	if os.Getenv("GOOS") == "windows" {
+		xdg = os.Getenv("LOCALAPPDATA")
+	} else {
+		xdg = os.Getenv("XDG_DATA_HOME")
+	}
+	if xdg == "" {
+		xdg = filepath.Join(os.Getenv("HOME"), ".local", "share")
+	}
+	return filepath.Join(xdg, "boohu")
+}
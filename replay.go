@@ -1,7 +1,10 @@
 package main
 
 import (
+	"fmt"
+	"strconv"
 	"time"
+	"unicode/utf8"
 )
 
 func (ui *gameui) Replay() {
@@ -21,12 +24,17 @@ func (ui *gameui) Replay() {
 type replay struct {
 	ui       *gameui
 	frames   []drawFrame
-	undo     [][]cellDraw
+	index    *replayIndex
+	screen   *replayScreen
 	frame    int
 	auto     bool
 	speed    time.Duration
 	evch     chan repEvent
+	resume   chan bool
 	color256 bool
+	search   string
+	msg      string
+	live     string // address of the watched broadcast, if any
 }
 
 type repEvent int
@@ -38,13 +46,20 @@ const (
 	ReplayQuit
 	ReplaySpeedMore
 	ReplaySpeedLess
+	ReplayNextMark
+	ReplayPreviousMark
+	ReplaySeekPercent
+	ReplayGotoFrame
+	ReplaySearch
 )
 
 func (rep *replay) Run() {
 	rep.auto = true
 	rep.speed = 1
 	rep.evch = make(chan repEvent, 100)
-	rep.undo = [][]cellDraw{}
+	rep.resume = make(chan bool)
+	rep.index = NewReplayIndex(rep.frames, UIWidth, UIHeight)
+	rep.screen = newReplayScreen(UIWidth, UIHeight)
 	go func(r *replay) {
 		r.PollKeyboardEvents()
 	}(rep)
@@ -57,67 +72,196 @@ func (rep *replay) Run() {
 			} else if rep.frame < 0 {
 				rep.frame = 0
 			}
-			rep.DrawFrame()
+			rep.screen.Apply(rep.frames[rep.frame])
 			rep.frame++
+			rep.msg = ""
+			rep.Draw()
 		case ReplayPrevious:
 			if rep.frame <= 1 {
 				break
-			} else if rep.frame >= len(rep.frames) {
-				rep.frame = len(rep.frames)
 			}
-			rep.frame--
-			rep.UndoFrame()
+			rep.Seek(rep.frame - 1)
 		case ReplayQuit:
 			return
 		case ReplayTogglePause:
 			rep.auto = !rep.auto
+			rep.Draw()
 		case ReplaySpeedMore:
 			rep.speed *= 2
 			if rep.speed > 16 {
 				rep.speed = 16
 			}
+			rep.Draw()
 		case ReplaySpeedLess:
 			rep.speed /= 2
 			if rep.speed < 1 {
 				rep.speed = 1
 			}
+			rep.Draw()
+		case ReplayNextMark:
+			if m, ok := rep.index.NextMark(rep.frame); ok {
+				rep.Seek(m.Frame + 1)
+			}
+		case ReplayPreviousMark:
+			if m, ok := rep.index.PreviousMark(rep.frame); ok {
+				rep.Seek(m.Frame + 1)
+			} else {
+				rep.Seek(1)
+			}
+		case ReplaySeekPercent, ReplayGotoFrame, ReplaySearch:
+			rep.HandlePrompt(e)
+			rep.resume <- true
 		}
 	}
 }
 
-func (rep *replay) DrawFrame() {
-	ui := rep.ui
-	df := rep.frames[rep.frame]
-	rep.undo = append(rep.undo, []cellDraw{})
-	j := len(rep.undo) - 1
-	for _, dr := range df.Draws {
-		i := ui.GetIndex(dr.X, dr.Y)
-		c := ui.g.DrawBuffer[i]
-		if rep.color256 {
-			dr.Cell.Fg = rep.ui.Map16ColorTo256(dr.Cell.Fg)
-			dr.Cell.Bg = rep.ui.Map16ColorTo256(dr.Cell.Bg)
+// Seek shows the screen after pos frames have been drawn.
+func (rep *replay) Seek(pos int) {
+	if pos < 1 {
+		pos = 1
+	}
+	if pos > len(rep.frames) {
+		pos = len(rep.frames)
+	}
+	rep.screen = rep.index.Screen(pos)
+	rep.frame = pos
+	rep.msg = ""
+	rep.Draw()
+}
+
+func (rep *replay) HandlePrompt(e repEvent) {
+	switch e {
+	case ReplaySeekPercent:
+		text, ok := rep.Prompt("Seek to %")
+		if !ok {
+			break
+		}
+		p, err := strconv.Atoi(text)
+		if err != nil || p < 0 || p > 100 {
+			rep.msg = "Invalid percentage"
+			break
+		}
+		rep.Seek(p * len(rep.frames) / 100)
+	case ReplayGotoFrame:
+		text, ok := rep.Prompt("Go to frame")
+		if !ok {
+			break
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			rep.msg = "Invalid frame number"
+			break
+		}
+		rep.Seek(n)
+	case ReplaySearch:
+		text, ok := rep.Prompt("Search")
+		if !ok {
+			break
+		}
+		if text == "" {
+			text = rep.search
+		}
+		if text == "" {
+			break
+		}
+		rep.search = text
+		if f, ok := rep.index.Search(text, rep.frame); ok {
+			rep.Seek(f + 1)
 		} else {
-			dr.Cell.Bg = ui.Map256ColorTo16(dr.Cell.Bg)
-			dr.Cell.Fg = ui.Map256ColorTo16(dr.Cell.Fg)
+			rep.msg = fmt.Sprintf("“%s” not found", text)
+		}
+	}
+	rep.Draw()
+}
+
+// Prompt reads a line of text in the status line. It returns false if the
+// user cancelled.
+func (rep *replay) Prompt(label string) (string, bool) {
+	ui := rep.ui
+	text := ""
+	for {
+		rep.msg = fmt.Sprintf("%s: %s_", label, text)
+		rep.Draw()
+		in := ui.PollEvent()
+		switch in.key {
+		case "\x1b":
+			rep.msg = ""
+			return "", false
+		case "\r", "\n":
+			rep.msg = ""
+			return text, true
+		case "\x7f", "\b":
+			if len(text) > 0 {
+				_, size := utf8.DecodeLastRuneInString(text)
+				text = text[:len(text)-size]
+			}
+		default:
+			if utf8.RuneCountInString(in.key) == 1 {
+				text += in.key
+			}
 		}
-		rep.undo[j] = append(rep.undo[j], cellDraw{Cell: c, X: dr.X, Y: dr.Y})
-		ui.SetGenCell(dr.X, dr.Y, dr.Cell.R, dr.Cell.Fg, dr.Cell.Bg, dr.Cell.InMap)
 	}
-	ui.Flush()
-	ui.g.DrawLog = nil
 }
 
-func (rep *replay) UndoFrame() {
+// Draw draws the current screen, with a status line on the last row.
+func (rep *replay) Draw() {
 	ui := rep.ui
-	df := rep.undo[len(rep.undo)-1]
-	for _, dr := range df {
-		ui.SetGenCell(dr.X, dr.Y, dr.Cell.R, dr.Cell.Fg, dr.Cell.Bg, dr.Cell.InMap)
+	s := rep.screen
+	for y := 0; y < s.h; y++ {
+		for x := 0; x < s.w; x++ {
+			c := s.cells[y*s.w+x]
+			if c.R == 0 {
+				ui.SetCell(x, y, ' ', ColorFg, ColorBg)
+				continue
+			}
+			if rep.color256 {
+				c.Fg = Map16ColorTo256(c.Fg)
+				c.Bg = Map16ColorTo256(c.Bg)
+			} else {
+				c.Bg = Map256ColorTo16(c.Bg)
+				c.Fg = Map256ColorTo16(c.Fg)
+			}
+			ui.SetGenCell(x, y, c.R, c.Fg, c.Bg, c.InMap)
+		}
 	}
-	rep.undo = rep.undo[:len(rep.undo)-1]
+	status := rep.Status()
+	ui.DrawColoredTextOnBG(status, UIWidth-utf8.RuneCountInString(status), UIHeight-1, ColorBg, ColorFgDark)
 	ui.Flush()
 	ui.g.DrawLog = nil
 }
 
+func (rep *replay) Status() string {
+	if rep.msg != "" {
+		return " " + rep.msg + " "
+	}
+	if rep.live != "" {
+		return " Watching " + rep.live + " "
+	}
+	var elapsed, total time.Duration
+	if rep.frame > 0 {
+		elapsed = rep.frames[rep.frame-1].Time.Sub(rep.frames[0].Time)
+	}
+	total = rep.frames[len(rep.frames)-1].Time.Sub(rep.frames[0].Time)
+	depth := "-"
+	switch d := rep.index.Depth(rep.frame); {
+	case d == -1:
+		depth = "Out!"
+	case d > 0:
+		depth = strconv.Itoa(d)
+	}
+	status := fmt.Sprintf(" %d/%d %s/%s D:%s ×%d ", rep.frame, len(rep.frames),
+		replayDuration(elapsed), replayDuration(total), depth, rep.speed)
+	if !rep.auto {
+		status += "paused "
+	}
+	return status
+}
+
+func replayDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
 func (rep *replay) PollEvent() (in repEvent) {
 	if rep.auto && rep.frame <= len(rep.frames)-1 && rep.frame >= 0 {
 		var d time.Duration
@@ -149,36 +293,82 @@ func (rep *replay) PollEvent() (in repEvent) {
 func (rep *replay) PollKeyboardEvents() {
 	for {
 		e := rep.ui.PollEvent()
-		if e.interrupt {
-			rep.evch <- ReplayNext
-			continue
-		}
+		var ev repEvent
 		switch e.key {
-		case "Q", "q", "\x1b":
-			rep.evch <- ReplayQuit
-			return
-		case "p", "P", " ":
-			rep.evch <- ReplayTogglePause
-		case "+", ">":
-			rep.evch <- ReplaySpeedMore
-		case "-", "<":
-			rep.evch <- ReplaySpeedLess
-		case ".", "6", "j", "n", "f":
-			rep.evch <- ReplayNext
-		case "4", "k", "N", "b":
-			rep.evch <- ReplayPrevious
+		case "]":
+			rep.evch <- ReplayNextMark
+			continue
+		case "[":
+			rep.evch <- ReplayPreviousMark
+			continue
+		case "%":
+			ev = ReplaySeekPercent
+		case "g", ":":
+			ev = ReplayGotoFrame
+		case "/":
+			ev = ReplaySearch
 		default:
-			if !e.mouse {
-				break
+			ev, ok := ReplayKeyEvent(e)
+			if !ok {
+				continue
 			}
-			switch e.button {
-			case 0:
-				rep.evch <- ReplayNext
-			case 1:
-				rep.evch <- ReplayTogglePause
-			case 2:
-				rep.evch <- ReplayPrevious
+			rep.evch <- ev
+			if ev == ReplayQuit {
+				return
 			}
+			continue
 		}
+		rep.evch <- ev
+		// the prompt reads the keys until it is done
+		<-rep.resume
+	}
+}
+
+// PollReplayKeyboardEvents sends replay control events corresponding to
+// user input, until the user quits.
+func PollReplayKeyboardEvents(ui *gameui, evch chan<- repEvent) {
+	for {
+		ev, ok := ReplayKeyEvent(ui.PollEvent())
+		if !ok {
+			continue
+		}
+		evch <- ev
+		if ev == ReplayQuit {
+			return
+		}
+	}
+}
+
+// ReplayKeyEvent returns the replay control event common to all replays
+// corresponding to user input, if any.
+func ReplayKeyEvent(e uiInput) (repEvent, bool) {
+	if e.interrupt {
+		return ReplayNext, true
+	}
+	switch e.key {
+	case "Q", "q", "\x1b":
+		return ReplayQuit, true
+	case "p", "P", " ":
+		return ReplayTogglePause, true
+	case "+", ">":
+		return ReplaySpeedMore, true
+	case "-", "<":
+		return ReplaySpeedLess, true
+	case ".", "6", "j", "n", "f":
+		return ReplayNext, true
+	case "4", "k", "N", "b":
+		return ReplayPrevious, true
+	}
+	if !e.mouse {
+		return 0, false
+	}
+	switch e.button {
+	case 0:
+		return ReplayNext, true
+	case 1:
+		return ReplayTogglePause, true
+	case 2:
+		return ReplayPrevious, true
 	}
+	return 0, false
 }
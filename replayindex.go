@@ -0,0 +1,241 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// replayKeyframeInterval is the number of frames between two full screen
+// copies of the replay index.
+const replayKeyframeInterval = 200
+
+// replayScreen is the state of the screen at some point of a replay.
+type replayScreen struct {
+	w, h  int
+	cells []UICell
+}
+
+func newReplayScreen(w, h int) *replayScreen {
+	return &replayScreen{w: w, h: h, cells: make([]UICell, w*h)}
+}
+
+// Resize changes the size of the screen, keeping the cells that still fit.
+func (s *replayScreen) Resize(w, h int) {
+	cells := make([]UICell, w*h)
+	for y := 0; y < Min(h, s.h); y++ {
+		copy(cells[y*w:y*w+Min(w, s.w)], s.cells[y*s.w:y*s.w+Min(w, s.w)])
+	}
+	s.w, s.h, s.cells = w, h, cells
+}
+
+// Apply draws a frame on the screen, and returns which rows changed.
+func (s *replayScreen) Apply(df drawFrame) map[int]bool {
+	rows := map[int]bool{}
+	for _, dr := range df.Draws {
+		if dr.X < 0 || dr.X >= s.w || dr.Y < 0 || dr.Y >= s.h {
+			continue
+		}
+		s.cells[dr.Y*s.w+dr.X] = dr.Cell
+		rows[dr.Y] = true
+	}
+	return rows
+}
+
+func (s *replayScreen) Row(y int) string {
+	runes := make([]rune, s.w)
+	for x := 0; x < s.w; x++ {
+		r := s.cells[y*s.w+x].R
+		if r == 0 {
+			r = ' '
+		}
+		runes[x] = r
+	}
+	return string(runes)
+}
+
+// replayMark is a notable frame of a replay: a depth change or the death of
+// the player.
+type replayMark struct {
+	Frame int
+	Depth int
+	Death bool
+}
+
+type replayLogEntry struct {
+	Frame int
+	Text  string
+}
+
+// replayIndex is built from the frames of a replay for seeking. Frame
+// numbers are indexes in the frame list, and positions are numbers of
+// drawn frames.
+type replayIndex struct {
+	frames    []drawFrame
+	w, h      int
+	keyframes [][]UICell // screen at positions multiple of replayKeyframeInterval
+	Marks     []replayMark
+	Logs      []replayLogEntry
+}
+
+var (
+	replayDepthRe      = regexp.MustCompile(`^Depth: (\d+|Out!)`)
+	replaySmallDepthRe = regexp.MustCompile(`♣:\d+ D: ?(\d+|Out!) T:`)
+)
+
+// ReplayDepth returns the depth shown on a screen row, if any.
+func ReplayDepth(row string) (int, bool) {
+	var m []string
+	runes := []rune(row)
+	if len(runes) > BarCol {
+		m = replayDepthRe.FindStringSubmatch(string(runes[BarCol:]))
+	}
+	if m == nil {
+		m = replaySmallDepthRe.FindStringSubmatch(row)
+	}
+	if m == nil {
+		return 0, false
+	}
+	if m[1] == "Out!" {
+		return -1, true
+	}
+	depth, err := strconv.Atoi(m[1])
+	return depth, err == nil
+}
+
+// replayLogRows returns the number of message log rows below the map.
+func replayLogRows(small bool) int {
+	if small {
+		return 2
+	}
+	return 4
+}
+
+// NewReplayIndex builds the index of a replay. Depth and death marks are
+// taken from the frames. For replays of older versions, they are guessed
+// from the screen, once the layout is known from the depth display: until
+// then, depth, death and log messages are unknown.
+func NewReplayIndex(frames []drawFrame, w, h int) *replayIndex {
+	idx := &replayIndex{frames: frames, w: w, h: h}
+	s := newReplayScreen(w, h)
+	depth := 0
+	death := false
+	scrape := true
+	for _, df := range frames {
+		if df.Depth != 0 {
+			scrape = false
+			break
+		}
+	}
+	logRows := 0
+	for i, df := range frames {
+		if i%replayKeyframeInterval == 0 {
+			idx.keyframes = append(idx.keyframes, append([]UICell{}, s.cells...))
+		}
+		if !scrape {
+			logRows = replayLogRows(df.Small)
+		}
+		if df.Depth != 0 && df.Depth != depth {
+			depth = df.Depth
+			idx.Marks = append(idx.Marks, replayMark{Frame: i, Depth: depth})
+		}
+		if !death && df.Dead {
+			death = true
+			idx.Marks = append(idx.Marks, replayMark{Frame: i, Depth: depth, Death: true})
+		}
+		logs := map[string]bool{}
+		for y := DungeonHeight + 1; y <= DungeonHeight+logRows && y < h; y++ {
+			logs[strings.TrimSpace(s.Row(y))] = true
+		}
+		rows := s.Apply(df)
+		for y := 0; y < h; y++ {
+			if !rows[y] {
+				continue
+			}
+			row := s.Row(y)
+			if d, ok := ReplayDepth(row); scrape && ok && y <= DungeonHeight {
+				// the small layout shows the depth in the status line
+				logRows = replayLogRows(y == DungeonHeight)
+				if d != depth {
+					depth = d
+					idx.Marks = append(idx.Marks, replayMark{Frame: i, Depth: d})
+				}
+			}
+			if y <= DungeonHeight || y > DungeonHeight+logRows {
+				continue
+			}
+			text := strings.TrimSpace(row)
+			if text == "" || logs[text] {
+				continue
+			}
+			idx.Logs = append(idx.Logs, replayLogEntry{Frame: i, Text: text})
+			if scrape && !death && strings.Contains(text, "You die...") {
+				death = true
+				idx.Marks = append(idx.Marks, replayMark{Frame: i, Depth: depth, Death: true})
+			}
+		}
+	}
+	return idx
+}
+
+// Screen returns the screen after pos frames have been drawn.
+func (idx *replayIndex) Screen(pos int) *replayScreen {
+	s := newReplayScreen(idx.w, idx.h)
+	k := pos / replayKeyframeInterval
+	if k >= len(idx.keyframes) {
+		k = len(idx.keyframes) - 1
+	}
+	if k < 0 {
+		return s
+	}
+	copy(s.cells, idx.keyframes[k])
+	for i := k * replayKeyframeInterval; i < pos && i < len(idx.frames); i++ {
+		s.Apply(idx.frames[i])
+	}
+	return s
+}
+
+// NextMark returns the first mark drawn after position pos.
+func (idx *replayIndex) NextMark(pos int) (replayMark, bool) {
+	for _, m := range idx.Marks {
+		if m.Frame >= pos {
+			return m, true
+		}
+	}
+	return replayMark{}, false
+}
+
+// PreviousMark returns the last mark drawn before position pos, excluding
+// the frame just before pos.
+func (idx *replayIndex) PreviousMark(pos int) (replayMark, bool) {
+	for i := len(idx.Marks) - 1; i >= 0; i-- {
+		if idx.Marks[i].Frame < pos-1 {
+			return idx.Marks[i], true
+		}
+	}
+	return replayMark{}, false
+}
+
+// Search returns the first frame drawn after position pos that shows a new
+// log message containing text, ignoring case.
+func (idx *replayIndex) Search(text string, pos int) (int, bool) {
+	text = strings.ToLower(text)
+	for _, e := range idx.Logs {
+		if e.Frame >= pos && strings.Contains(strings.ToLower(e.Text), text) {
+			return e.Frame, true
+		}
+	}
+	return 0, false
+}
+
+// Depth returns the depth shown after position pos.
+func (idx *replayIndex) Depth(pos int) int {
+	depth := 0
+	for _, m := range idx.Marks {
+		if m.Frame >= pos {
+			break
+		}
+		depth = m.Depth
+	}
+	return depth
+}
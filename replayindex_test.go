@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func textDraws(text string, x, y int) []cellDraw {
+	draws := []cellDraw{}
+	for i, r := range []rune(text) {
+		draws = append(draws, cellDraw{Cell: UICell{R: r, Fg: ColorFg, Bg: ColorBg}, X: x + i, Y: y})
+	}
+	return draws
+}
+
+func TestReplayIndex(t *testing.T) {
+	frames := []drawFrame{}
+	for i := 0; i < 2*replayKeyframeInterval+50; i++ {
+		frames = append(frames, drawFrame{Draws: textDraws("@", i%DungeonWidth, i%DungeonHeight)})
+	}
+	frames[3].Draws = append(frames[3].Draws, textDraws("Depth: 1", BarCol, 8)...)
+	frames[10].Draws = append(frames[10].Draws, textDraws("You see a goblin.", 0, DungeonHeight+4)...)
+	frames[11].Draws = append(frames[11].Draws, textDraws("You see a goblin.", 0, DungeonHeight+3)...)
+	frames[250].Draws = append(frames[250].Draws, textDraws("Depth: 2", BarCol, 8)...)
+	frames[300].Draws = append(frames[300].Draws, textDraws("You die... [(x) to continue]", 0, DungeonHeight+4)...)
+	idx := NewReplayIndex(frames, 100, 26)
+	marks := []replayMark{{Frame: 3, Depth: 1}, {Frame: 250, Depth: 2}, {Frame: 300, Depth: 2, Death: true}}
+	if len(idx.Marks) != len(marks) {
+		t.Fatalf("bad marks: %+v", idx.Marks)
+	}
+	for i, m := range marks {
+		if idx.Marks[i] != m {
+			t.Errorf("bad mark %d: %+v", i, idx.Marks[i])
+		}
+	}
+	if m, ok := idx.NextMark(4); !ok || m.Frame != 250 {
+		t.Errorf("bad next mark: %+v", m)
+	}
+	if m, ok := idx.PreviousMark(251); !ok || m.Frame != 3 {
+		t.Errorf("bad previous mark: %+v", m)
+	}
+	if f, ok := idx.Search("GOBLIN", 0); !ok || f != 10 {
+		t.Errorf("bad search result: %d", f)
+	}
+	if _, ok := idx.Search("goblin", 11); ok {
+		t.Errorf("scrolled message found again")
+	}
+	if d := idx.Depth(251); d != 2 {
+		t.Errorf("bad depth: %d", d)
+	}
+	s := newReplayScreen(100, 26)
+	for pos := 0; pos <= len(frames); pos++ {
+		seeked := idx.Screen(pos)
+		for i := range s.cells {
+			if seeked.cells[i] != s.cells[i] {
+				t.Fatalf("bad screen at position %d", pos)
+			}
+		}
+		if pos < len(frames) {
+			s.Apply(frames[pos])
+		}
+	}
+}
+
+func TestReplayIndexFrameMarks(t *testing.T) {
+	frames := []drawFrame{{}, {Depth: 1}, {Depth: 1}, {Depth: 2}, {Depth: 2, Dead: true}, {Depth: 2, Dead: true}}
+	frames[2].Draws = textDraws("Depth: 9", BarCol, 8)
+	frames[2].Draws = append(frames[2].Draws, textDraws("You die... [(x) to continue]", 0, DungeonHeight+4)...)
+	idx := NewReplayIndex(frames, 100, 26)
+	marks := []replayMark{{Frame: 1, Depth: 1}, {Frame: 3, Depth: 2}, {Frame: 4, Depth: 2, Death: true}}
+	if len(idx.Marks) != len(marks) {
+		t.Fatalf("bad marks: %+v", idx.Marks)
+	}
+	for i, m := range marks {
+		if idx.Marks[i] != m {
+			t.Errorf("bad mark %d: %+v", i, idx.Marks[i])
+		}
+	}
+}
+
+func TestReplayIndexSmallLayout(t *testing.T) {
+	frames := make([]drawFrame, 5)
+	frames[0].Draws = textDraws("You see a goblin.", 0, DungeonHeight+1)
+	frames[1].Draws = textDraws("[ ]:plate ♣:12 D:3 T:120.5 HP:20 MP: 5", 0, DungeonHeight)
+	frames[2].Draws = textDraws("You see an orc.", 0, DungeonHeight+1)
+	frames[3].Draws = textDraws("Some menu text", 0, DungeonHeight+3)
+	frames[4].Draws = textDraws("You die... [(x) to continue]", 0, DungeonHeight+2)
+	idx := NewReplayIndex(frames, 100, 26)
+	marks := []replayMark{{Frame: 1, Depth: 3}, {Frame: 4, Depth: 3, Death: true}}
+	if len(idx.Marks) != len(marks) || idx.Marks[0] != marks[0] || idx.Marks[1] != marks[1] {
+		t.Errorf("bad marks: %+v", idx.Marks)
+	}
+	// the layout is unknown before the depth is shown
+	if _, ok := idx.Search("goblin", 0); ok {
+		t.Errorf("log message found in unknown layout")
+	}
+	if _, ok := idx.Search("orc", 0); !ok {
+		t.Errorf("log message not found")
+	}
+	if _, ok := idx.Search("menu", 0); ok {
+		t.Errorf("text below the small layout log found")
+	}
+}
+
+func TestReplayDepth(t *testing.T) {
+	bar := strings.Repeat(" ", BarCol)
+	rows := map[string]int{
+		bar + "Depth: 7":                         7,
+		bar + "Depth: Out!":                      -1,
+		"[ ]:plate ♣:12 D:4 T:120.5 HP:20 MP: 5": 4,
+	}
+	for row, depth := range rows {
+		if d, ok := ReplayDepth(row); !ok || d != depth {
+			t.Errorf("bad depth for %q: %d", row, d)
+		}
+	}
+	if _, ok := ReplayDepth("You explored 4 levels. D:3"); ok {
+		t.Errorf("depth found in log message")
+	}
+}
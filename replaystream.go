@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"io"
+	"time"
+)
+
+// Replays are written as the game progresses into an append-only stream:
+// a magic line followed by independently compressed chunks of frames, each
+// prefixed by its length. A chunk starts with a keyframe, the full screen
+// after its first frame, so that reading can recover from lost chunks.
+
+const replayStreamMagic = "boohu replay stream\n"
+
+const (
+	replayChunkFrames = 100              // maximum number of frames in a chunk
+	replayChunkDelay  = 10 * time.Second // maximum time before writing a chunk
+)
+
+type replayChunk struct {
+	Start    int // number of the first frame
+	Keyframe []cellDraw
+	Frames   []drawFrame
+}
+
+func WriteReplayChunk(w io.Writer, c *replayChunk) error {
+	data := bytes.Buffer{}
+	zw := zlib.NewWriter(&data)
+	err := gob.NewEncoder(zw).Encode(c)
+	if err != nil {
+		return err
+	}
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+data.Len())
+	n := binary.PutUvarint(buf, uint64(data.Len()))
+	_, err = w.Write(append(buf[:n], data.Bytes()...))
+	return err
+}
+
+// IsReplayStream reports whether data is a replay stream, instead of a
+// replay file of older versions.
+func IsReplayStream(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(replayStreamMagic))
+}
+
+// ReadReplayStream returns the frames of a replay stream. A truncated or
+// corrupted last chunk, as left by a crash, is ignored.
+func ReadReplayStream(data []byte) ([]drawFrame, error) {
+	if !IsReplayStream(data) {
+		return nil, errors.New("not a replay stream")
+	}
+	r := bufio.NewReader(bytes.NewReader(data[len(replayStreamMagic):]))
+	frames := []drawFrame{}
+	next := 0
+	for {
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(len(data)) {
+			break
+		}
+		cdata := make([]byte, n)
+		_, err = io.ReadFull(r, cdata)
+		if err != nil {
+			break
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(cdata))
+		if err != nil {
+			break
+		}
+		c := &replayChunk{}
+		err = gob.NewDecoder(zr).Decode(c)
+		if err != nil {
+			break
+		}
+		if len(c.Frames) == 0 {
+			continue
+		}
+		if c.Start != next && c.Keyframe != nil {
+			// frames are missing: draw the whole screen
+			c.Frames[0].Draws = c.Keyframe
+		}
+		frames = append(frames, c.Frames...)
+		next = c.Start + len(c.Frames)
+	}
+	if len(frames) == 0 {
+		return nil, errors.New("empty replay")
+	}
+	return frames, nil
+}
+
+// WriteReplayStream writes a whole replay as a stream.
+func WriteReplayStream(w io.Writer, frames []drawFrame, width, height int) error {
+	_, err := io.WriteString(w, replayStreamMagic)
+	if err != nil {
+		return err
+	}
+	s := newReplayScreen(width, height)
+	for i := 0; i < len(frames); i += replayChunkFrames {
+		c := &replayChunk{Start: i, Frames: frames[i:Min(i+replayChunkFrames, len(frames))]}
+		s.Apply(c.Frames[0])
+		c.Keyframe = s.Draws()
+		for _, df := range c.Frames[1:] {
+			s.Apply(df)
+		}
+		err = WriteReplayChunk(w, c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Draws returns the draws of every non-empty cell of the screen.
+func (s *replayScreen) Draws() []cellDraw {
+	draws := []cellDraw{}
+	for i, c := range s.cells {
+		if c.R != 0 {
+			draws = append(draws, cellDraw{Cell: c, X: i % s.w, Y: i / s.w})
+		}
+	}
+	return draws
+}
+
+// replayRecorder writes frames of the current game to a replay stream.
+type replayRecorder struct {
+	w      io.Writer
+	closer io.Closer
+	chunk  replayChunk
+	start  time.Time
+}
+
+func (rec *replayRecorder) NeedKeyframe() bool {
+	return len(rec.chunk.Frames) == 0
+}
+
+// Record adds frame number n to the stream. The keyframe is needed for the
+// first frame of a chunk.
+func (rec *replayRecorder) Record(df drawFrame, n int, keyframe []cellDraw) error {
+	if len(rec.chunk.Frames) == 0 {
+		rec.chunk.Start = n
+		rec.chunk.Keyframe = keyframe
+		rec.start = time.Now()
+	}
+	rec.chunk.Frames = append(rec.chunk.Frames, df)
+	if len(rec.chunk.Frames) >= replayChunkFrames || time.Since(rec.start) >= replayChunkDelay {
+		return rec.Flush()
+	}
+	return nil
+}
+
+func (rec *replayRecorder) Flush() error {
+	if len(rec.chunk.Frames) == 0 {
+		return nil
+	}
+	err := WriteReplayChunk(rec.w, &rec.chunk)
+	rec.chunk = replayChunk{}
+	return err
+}
+
+func (rec *replayRecorder) Close() error {
+	err := rec.Flush()
+	if rec.closer != nil {
+		if errc := rec.closer.Close(); err == nil {
+			err = errc
+		}
+	}
+	return err
+}
+
+// RecordReplayFrame writes the last drawn frame to the replay stream, and
+// forgets older frames.
+func (ui *gameui) RecordReplayFrame() {
+	g := ui.g
+	last := len(g.DrawLog) - 1
+	var keyframe []cellDraw
+	if g.replay.NeedKeyframe() {
+		keyframe = []cellDraw{}
+		for i, c := range g.drawBackBuffer {
+			x, y := ui.GetPos(i)
+			keyframe = append(keyframe, cellDraw{Cell: c, X: x, Y: y})
+		}
+	}
+	err := g.replay.Record(g.DrawLog[last], g.ReplayFrames, keyframe)
+	g.ReplayFrames++
+	g.DrawLog = g.DrawLog[last:]
+	if err != nil {
+		g.replay.Close()
+		g.replay = nil
+		g.PrintfStyled("Error writing replay: %v", logError, err)
+	}
+}
+
+// FlushReplay writes the recorded frames not yet written to the replay
+// stream.
+func (g *game) FlushReplay() error {
+	if g.replay == nil {
+		return nil
+	}
+	return g.replay.Flush()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestReplayStream(t *testing.T) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(replayStreamMagic)
+	rec := &replayRecorder{w: buf}
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	n := 2*replayChunkFrames + 50
+	for i := 0; i < n; i++ {
+		df := drawFrame{Time: start.Add(time.Duration(i) * time.Second), Draws: textDraws("@", i%DungeonWidth, 0), Depth: 1 + i/100}
+		if err := rec.Record(df, i, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	full := buf.Len()
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	frames, err := ReadReplayStream(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != n {
+		t.Fatalf("bad number of frames: %d", len(frames))
+	}
+	if !frames[n-1].Time.Equal(start.Add(time.Duration(n-1)*time.Second)) || frames[n-1].Draws[0].X != (n-1)%DungeonWidth || frames[n-1].Depth != 3 {
+		t.Errorf("bad last frame: %+v", frames[n-1])
+	}
+	// crash while writing the last chunk
+	frames, err = ReadReplayStream(buf.Bytes()[:full+10])
+	if err != nil || len(frames) != 2*replayChunkFrames {
+		t.Errorf("truncated stream not read: %d frames (%v)", len(frames), err)
+	}
+}
+
+func TestReplayStreamKeyframe(t *testing.T) {
+	frames := []drawFrame{}
+	for i := 0; i < replayChunkFrames+10; i++ {
+		frames = append(frames, drawFrame{Draws: textDraws("@", i%DungeonWidth, 1)})
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteReplayStream(buf, frames, 100, 26); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	rframes, err := ReadReplayStream(data)
+	if err != nil || len(rframes) != len(frames) {
+		t.Fatalf("stream not read: %d frames (%v)", len(rframes), err)
+	}
+	if len(rframes[replayChunkFrames].Draws) != 1 {
+		t.Errorf("keyframe used without missing frames")
+	}
+	// lose the first chunk
+	gap := &bytes.Buffer{}
+	gap.WriteString(replayStreamMagic)
+	if err := WriteReplayChunk(gap, &replayChunk{Start: 5, Keyframe: textDraws("@@@", 0, 1), Frames: frames[5:7]}); err != nil {
+		t.Fatal(err)
+	}
+	rframes, err = ReadReplayStream(gap.Bytes())
+	if err != nil || len(rframes) != 2 || len(rframes[0].Draws) != 3 {
+		t.Errorf("keyframe not used: %+v (%v)", rframes, err)
+	}
+}
+
+func TestReplayStreamOldFormat(t *testing.T) {
+	g := &game{DrawLog: []drawFrame{{Draws: textDraws("@", 0, 0)}}}
+	data, err := g.EncodeDrawLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsReplayStream(data) {
+		t.Error("old replay format detected as stream")
+	}
+}
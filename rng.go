@@ -0,0 +1,34 @@
+package main
+
+// rng is a small pseudo-random number generator (splitmix64). Unlike
+// math/rand sources, its whole state is a single exported integer, which
+// makes it easy to reproduce a game from a seed.
+type rng struct {
+	State uint64
+}
+
+func newRng(seed int64) *rng {
+	return &rng{State: uint64(seed)}
+}
+
+func (r *rng) Uint64() uint64 {
+	r.State += 0x9e3779b97f4a7c15
+	z := r.State
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (r *rng) Intn(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	// rejection sampling to avoid modulo bias
+	max := ^uint64(0) - ^uint64(0)%uint64(n)
+	for {
+		x := r.Uint64()
+		if x < max {
+			return int(x % uint64(n))
+		}
+	}
+}
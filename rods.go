@@ -202,8 +202,8 @@ func (g *game) EvokeRodBlink(ev event) error {
 
 func (g *game) BlinkPos() position {
 	losPos := []position{}
-	for pos, b := range g.Player.LOS {
-		if !b {
+	for _, pos := range SortedPositions(g.Player.LOS) {
+		if !g.Player.LOS[pos] {
 			continue
 		}
 		if g.Dungeon.Cell(pos).T != FreeCell {
@@ -218,9 +218,9 @@ func (g *game) BlinkPos() position {
 	if len(losPos) == 0 {
 		return InvalidPos
 	}
-	npos := losPos[RandInt(len(losPos))]
+	npos := losPos[g.RandInt(len(losPos))]
 	for i := 0; i < 4; i++ {
-		pos := losPos[RandInt(len(losPos))]
+		pos := losPos[g.RandInt(len(losPos))]
 		if npos.Distance(g.Player.Pos) < pos.Distance(g.Player.Pos) {
 			npos = pos
 		}
@@ -240,12 +240,12 @@ func (g *game) Blink(ev event) {
 	}
 	opos := g.Player.Pos
 	g.Print("You blink away.")
-	g.ui.TeleportAnimation(opos, npos, true)
+	g.Animations().TeleportAnimation(opos, npos, true)
 	g.PlacePlayerAt(npos)
 }
 
 func (g *game) EvokeRodTeleportOther(ev event) error {
-	if err := g.ui.ChooseTarget(&chooser{}); err != nil {
+	if err := g.ChooseTarget(&chooser{}); err != nil {
 		return err
 	}
 	mons := g.MonsterAt(g.Player.Target)
@@ -255,12 +255,12 @@ func (g *game) EvokeRodTeleportOther(ev event) error {
 }
 
 func (g *game) EvokeRodSleeping(ev event) error {
-	if err := g.ui.ChooseTarget(&chooser{area: true, minDist: true}); err != nil {
+	if err := g.ChooseTarget(&chooser{area: true, minDist: true}); err != nil {
 		return err
 	}
 	neighbors := g.Dungeon.FreeNeighbors(g.Player.Target)
 	g.Print("A sleeping ball emerges straight out of the rod.")
-	g.ui.ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgSleepingMonster)
+	g.Animations().ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgSleepingMonster)
 	for _, pos := range append(neighbors, g.Player.Target) {
 		mons := g.MonsterAt(pos)
 		if !mons.Exists() {
@@ -270,19 +270,19 @@ func (g *game) EvokeRodSleeping(ev event) error {
 			g.Printf("%s falls asleep.", mons.Kind.Definite(true))
 		}
 		mons.State = Resting
-		mons.ExhaustTime(g, 40+RandInt(10))
+		mons.ExhaustTime(g, 40+g.RandInt(10))
 	}
 	return nil
 }
 
 func (g *game) EvokeRodFireBolt(ev event) error {
-	if err := g.ui.ChooseTarget(&chooser{flammable: true}); err != nil {
+	if err := g.ChooseTarget(&chooser{flammable: true}); err != nil {
 		return err
 	}
 	ray := g.Ray(g.Player.Target)
 	g.MakeNoise(MagicCastNoise, g.Player.Pos)
 	g.Print("Whoosh! A fire bolt emerges straight out of the rod.")
-	g.ui.FireBoltAnimation(ray)
+	g.Animations().FireBoltAnimation(ray)
 	for _, pos := range ray {
 		g.Burn(pos, ev)
 		mons := g.MonsterAt(pos)
@@ -291,7 +291,7 @@ func (g *game) EvokeRodFireBolt(ev event) error {
 		}
 		dmg := 0
 		for i := 0; i < 2; i++ {
-			dmg += RandInt(21)
+			dmg += g.RandInt(21)
 		}
 		dmg /= 2
 		mons.HP -= dmg
@@ -307,14 +307,14 @@ func (g *game) EvokeRodFireBolt(ev event) error {
 }
 
 func (g *game) EvokeRodFireball(ev event) error {
-	if err := g.ui.ChooseTarget(&chooser{area: true, minDist: true, flammable: true}); err != nil {
+	if err := g.ChooseTarget(&chooser{area: true, minDist: true, flammable: true}); err != nil {
 		return err
 	}
 	neighbors := g.Dungeon.FreeNeighbors(g.Player.Target)
 	g.MakeNoise(MagicExplosionNoise, g.Player.Target)
 	g.Printf("A fireball emerges straight out of the rod... %s", g.ExplosionSound())
-	g.ui.ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgExplosionStart)
-	g.ui.ExplosionAnimation(FireExplosion, g.Player.Target)
+	g.Animations().ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgExplosionStart)
+	g.Animations().ExplosionAnimation(FireExplosion, g.Player.Target)
 	for _, pos := range append(neighbors, g.Player.Target) {
 		g.Burn(pos, ev)
 		mons := g.MonsterAt(pos)
@@ -323,7 +323,7 @@ func (g *game) EvokeRodFireball(ev event) error {
 		}
 		dmg := 0
 		for i := 0; i < 2; i++ {
-			dmg += RandInt(24)
+			dmg += g.RandInt(24)
 		}
 		dmg /= 2
 		mons.HP -= dmg
@@ -372,7 +372,7 @@ func (g *game) EvokeRodLightning(ev event) error {
 		targets = append(targets, pos)
 		dmg := 0
 		for i := 0; i < 2; i++ {
-			dmg += RandInt(17)
+			dmg += g.RandInt(17)
 		}
 		dmg /= 2
 		mons.HP -= dmg
@@ -393,7 +393,7 @@ func (g *game) EvokeRodLightning(ev event) error {
 			}
 		}
 	}
-	g.ui.LightningHitAnimation(targets)
+	g.Animations().LightningHitAnimation(targets)
 
 	return nil
 }
@@ -415,18 +415,18 @@ func (g *game) EvokeRodFog(ev event) error {
 func (g *game) Fog(at position, radius int, ev event) {
 	dij := &normalPath{game: g}
 	nm := Dijkstra(dij, []position{at}, radius)
-	for pos := range nm {
+	for _, pos := range nm.SortedPositions() {
 		_, ok := g.Clouds[pos]
 		if !ok {
 			g.Clouds[pos] = CloudFog
-			g.PushEvent(&cloudEvent{ERank: ev.Rank() + 100 + RandInt(100), EAction: CloudEnd, Pos: pos})
+			g.PushEvent(&cloudEvent{ERank: ev.Rank() + 100 + g.RandInt(100), EAction: CloudEnd, Pos: pos})
 		}
 	}
 	g.ComputeLOS()
 }
 
 func (g *game) EvokeRodDigging(ev event) error {
-	if err := g.ui.ChooseTarget(&wallChooser{}); err != nil {
+	if err := g.ChooseTarget(&wallChooser{}); err != nil {
 		return err
 	}
 	pos := g.Player.Target
@@ -450,7 +450,7 @@ func (g *game) EvokeRodDigging(ev event) error {
 }
 
 func (g *game) EvokeRodShatter(ev event) error {
-	if err := g.ui.ChooseTarget(&wallChooser{minDist: true}); err != nil {
+	if err := g.ChooseTarget(&wallChooser{minDist: true}); err != nil {
 		return err
 	}
 	neighbors := g.Dungeon.FreeNeighbors(g.Player.Target)
@@ -460,8 +460,8 @@ func (g *game) EvokeRodShatter(ev event) error {
 	g.MakeMonstersAware()
 	g.MakeNoise(WallNoise, g.Player.Target)
 	g.Printf("%s The wall disappeared.", g.CrackSound())
-	g.ui.ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgExplosionWallStart)
-	g.ui.ExplosionAnimation(WallExplosion, g.Player.Target)
+	g.Animations().ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgExplosionWallStart)
+	g.Animations().ExplosionAnimation(WallExplosion, g.Player.Target)
 	g.Fog(g.Player.Target, 2, ev)
 	for _, pos := range neighbors {
 		mons := g.MonsterAt(pos)
@@ -470,7 +470,7 @@ func (g *game) EvokeRodShatter(ev event) error {
 		}
 		dmg := 0
 		for i := 0; i < 3; i++ {
-			dmg += RandInt(30)
+			dmg += g.RandInt(30)
 		}
 		dmg /= 3
 		mons.HP -= dmg
@@ -486,7 +486,7 @@ func (g *game) EvokeRodShatter(ev event) error {
 }
 
 func (g *game) EvokeRodObstruction(ev event) error {
-	if err := g.ui.ChooseTarget(&chooser{free: true}); err != nil {
+	if err := g.ChooseTarget(&chooser{free: true}); err != nil {
 		return err
 	}
 	g.TemporalWallAt(g.Player.Target, ev)
@@ -495,7 +495,7 @@ func (g *game) EvokeRodObstruction(ev event) error {
 }
 
 func (g *game) EvokeRodLignification(ev event) error {
-	if err := g.ui.ChooseTarget(&chooser{}); err != nil {
+	if err := g.ChooseTarget(&chooser{}); err != nil {
 		return err
 	}
 	mons := g.MonsterAt(g.Player.Target)
@@ -522,15 +522,15 @@ func (g *game) CreateTemporalWallAt(pos position, ev event) {
 	g.Dungeon.SetCell(pos, WallCell)
 	delete(g.Clouds, pos)
 	g.TemporalWalls[pos] = true
-	g.PushEvent(&cloudEvent{ERank: ev.Rank() + 200 + RandInt(50), Pos: pos, EAction: ObstructionEnd})
+	g.PushEvent(&cloudEvent{ERank: ev.Rank() + 200 + g.RandInt(50), Pos: pos, EAction: ObstructionEnd})
 }
 
 func (g *game) EvokeRodHope(ev event) error {
-	if err := g.ui.ChooseTarget(&chooser{needsFreeWay: true}); err != nil {
+	if err := g.ChooseTarget(&chooser{needsFreeWay: true}); err != nil {
 		return err
 	}
 	g.MakeNoise(MagicCastNoise, g.Player.Pos)
-	g.ui.ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgExplosionStart)
+	g.Animations().ProjectileTrajectoryAnimation(g.Ray(g.Player.Target), ColorFgExplosionStart)
 	mons := g.MonsterAt(g.Player.Target)
 	// mons not nil (check done in the targeter)
 	attack := -20 + 30*DefaultHealth/g.Player.HP
@@ -539,7 +539,7 @@ func (g *game) EvokeRodHope(ev event) error {
 	}
 	dmg := 0
 	for i := 0; i < 5; i++ {
-		dmg += RandInt(attack)
+		dmg += g.RandInt(attack)
 	}
 	dmg /= 5
 	if dmg < 0 {
@@ -548,7 +548,7 @@ func (g *game) EvokeRodHope(ev event) error {
 	}
 	mons.HP -= dmg
 	g.Burn(g.Player.Target, ev)
-	g.ui.HitAnimation(g.Player.Target, true)
+	g.Animations().HitAnimation(g.Player.Target, true)
 	g.Printf("An energy channel hits %s (%d dmg).", mons.Kind.Definite(false), dmg)
 	if mons.HP <= 0 {
 		g.Printf("%s dies.", mons.Kind.Indefinite(true))
@@ -561,7 +561,7 @@ func (g *game) EvokeRodSwapping(ev event) error {
 	if g.Player.HasStatus(StatusLignification) {
 		return errors.New("You cannot use this rod while lignified.")
 	}
-	if err := g.ui.ChooseTarget(&chooser{}); err != nil {
+	if err := g.ChooseTarget(&chooser{}); err != nil {
 		return err
 	}
 	mons := g.MonsterAt(g.Player.Target)
@@ -576,7 +576,7 @@ func (g *game) EvokeRodSwapping(ev event) error {
 func (g *game) SwapWithMonster(mons *monster) {
 	ompos := mons.Pos
 	g.Printf("You swap positions with the %s.", mons.Kind)
-	g.ui.SwappingAnimation(mons.Pos, g.Player.Pos)
+	g.Animations().SwappingAnimation(mons.Pos, g.Player.Pos)
 	mons.MoveTo(g, g.Player.Pos)
 	g.PlacePlayerAt(ompos)
 	mons.MakeAware(g)
@@ -593,7 +593,7 @@ func (g *game) GeneratedRodsCount() int {
 }
 
 func (g *game) RandomRod() rod {
-	r := rod(RandInt(NumRods))
+	r := rod(g.RandInt(NumRods))
 	return r
 }
 
@@ -615,21 +615,22 @@ func (g *game) GenerateRod() {
 }
 
 func (g *game) RechargeRods() {
-	for r, props := range g.Player.Rods {
+	for _, r := range g.SortedRods() {
+		props := g.Player.Rods[r]
 		max := r.MaxCharge()
 		if g.Player.Armour == CelmistRobe {
 			max += 2
 		}
 		if props.Charge < max {
-			rchg := RandInt(1 + r.Rate())
-			if rchg == 0 && RandInt(2) == 0 {
+			rchg := g.RandInt(1 + r.Rate())
+			if rchg == 0 && g.RandInt(2) == 0 {
 				rchg++
 			}
 			if g.Player.Armour == CelmistRobe {
-				if RandInt(10) > 0 {
+				if g.RandInt(10) > 0 {
 					rchg++
 				}
-				if RandInt(3) == 0 {
+				if g.RandInt(3) == 0 {
 					rchg++
 				}
 			}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// runStats contains statistics aggregated over finished runs. Outcomes,
+// depths and killers come from the run history, and detailed statistics
+// from the JSON dumps of archived games. Wizard mode runs are ignored.
+type runStats struct {
+	Runs        int
+	Outcomes    map[string]int
+	Deaths      int
+	DeathDepths int // sum of depths of death
+	Killers     map[string]int
+
+	Dumps        int            // number of archived JSON dumps
+	LayoutVisits map[string]int // levels explored, by layout
+	LayoutDeaths map[string]int // deaths, by layout of the level
+	RodRuns      map[string]int // runs using each rod
+	RodUses      map[string]int // total uses of each rod
+	AptRuns      map[string]int // runs with each aptitude
+	AptWins      map[string]int // escaped runs with each aptitude
+	Wins         int            // escaped runs among dumps
+}
+
+func AggregateStats(runs []runRecord, dumps []jsonDump) *runStats {
+	s := &runStats{
+		Outcomes:     map[string]int{},
+		Killers:      map[string]int{},
+		LayoutVisits: map[string]int{},
+		LayoutDeaths: map[string]int{},
+		RodRuns:      map[string]int{},
+		RodUses:      map[string]int{},
+		AptRuns:      map[string]int{},
+		AptWins:      map[string]int{},
+	}
+	for _, r := range runs {
+		if r.Wizard {
+			continue
+		}
+		s.Runs++
+		s.Outcomes[r.Outcome]++
+		if r.Outcome != "died" {
+			continue
+		}
+		s.Deaths++
+		s.DeathDepths += r.Depth
+		killer := r.Killer
+		if killer == "" {
+			killer = "unknown"
+		}
+		s.Killers[killer]++
+	}
+	for _, d := range dumps {
+		if d.Wizard || d.Outcome == "playing" {
+			continue
+		}
+		s.Dumps++
+		escaped := d.Outcome == "escaped"
+		if escaped {
+			s.Wins++
+		}
+		for _, l := range d.Stats.DLayout {
+			if l != "" {
+				s.LayoutVisits[l]++
+			}
+		}
+		if d.Outcome == "died" && d.Depth >= 1 && d.Depth <= len(d.Stats.DLayout) {
+			s.LayoutDeaths[d.Stats.DLayout[d.Depth-1]]++
+		}
+		for r, n := range d.Stats.UsedRod {
+			if n > 0 {
+				s.RodRuns[r]++
+				s.RodUses[r] += n
+			}
+		}
+		for _, apt := range d.Aptitudes {
+			s.AptRuns[apt]++
+			if escaped {
+				s.AptWins[apt]++
+			}
+		}
+	}
+	return s
+}
+
+// sortedCounts returns the keys of m by decreasing count.
+func sortedCounts(m map[string]int) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(total)
+}
+
+func (s *runStats) Write(w io.Writer) {
+	fmt.Fprintf(w, "Runs: %d\n", s.Runs)
+	if s.Runs == 0 {
+		return
+	}
+	for _, o := range sortedCounts(s.Outcomes) {
+		fmt.Fprintf(w, "  %-8s %5d (%.1f%%)\n", o, s.Outcomes[o], percent(s.Outcomes[o], s.Runs))
+	}
+	fmt.Fprintf(w, "Win rate: %.1f%%\n", percent(s.Outcomes["escaped"], s.Runs))
+	if s.Deaths > 0 {
+		fmt.Fprintf(w, "Average depth of death: %.1f\n", float64(s.DeathDepths)/float64(s.Deaths))
+		fmt.Fprintf(w, "\nMost common killers:\n")
+		for i, k := range sortedCounts(s.Killers) {
+			if i >= 10 {
+				break
+			}
+			fmt.Fprintf(w, "  %-24s %5d (%.1f%%)\n", k, s.Killers[k], percent(s.Killers[k], s.Deaths))
+		}
+	}
+	fmt.Fprintf(w, "\nArchived character dumps: %d\n", s.Dumps)
+	if s.Dumps == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nDeath rate by level layout (deaths / levels explored):\n")
+	for _, l := range sortedCounts(s.LayoutVisits) {
+		fmt.Fprintf(w, "  %-24s %5d / %-5d (%.1f%%)\n", l, s.LayoutDeaths[l], s.LayoutVisits[l], percent(s.LayoutDeaths[l], s.LayoutVisits[l]))
+	}
+	fmt.Fprintf(w, "\nRod usage (runs using the rod, total uses):\n")
+	for _, r := range sortedCounts(s.RodRuns) {
+		fmt.Fprintf(w, "  %-24s %5d (%.1f%%) %6d\n", r, s.RodRuns[r], percent(s.RodRuns[r], s.Dumps), s.RodUses[r])
+	}
+	fmt.Fprintf(w, "\nAptitudes (runs with the aptitude, win rate with and without it):\n")
+	for _, apt := range sortedCounts(s.AptRuns) {
+		n := s.AptRuns[apt]
+		fmt.Fprintf(w, "  %-40s %5d (%.1f%%) %5.1f%% %5.1f%%\n", apt, n, percent(n, s.Dumps),
+			percent(s.AptWins[apt], n), percent(s.Wins-s.AptWins[apt], s.Dumps-n))
+	}
+}
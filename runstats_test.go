@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAggregateStats(t *testing.T) {
+	runs := []runRecord{
+		{Outcome: "died", Depth: 2, Killer: "an orc"},
+		{Outcome: "died", Depth: 4, Killer: "an orc"},
+		{Outcome: "died", Depth: 6, Killer: "a cyclops"},
+		{Outcome: "escaped", Depth: 8},
+		{Outcome: "died", Depth: 1, Killer: "a rat", Wizard: true},
+	}
+	dumps := []jsonDump{
+		{Outcome: "died", Depth: 2, Aptitudes: []string{"You are agile."},
+			Stats: jsonStats{DLayout: []string{"CaveGen", "RuinsGen"}, UsedRod: map[string]int{"rod of blinking": 3}}},
+		{Outcome: "escaped", Depth: 2, Aptitudes: []string{"You are strong."},
+			Stats: jsonStats{DLayout: []string{"CaveGen", "CaveGen"}, UsedRod: map[string]int{"rod of blinking": 1, "rod of fog": 0}}},
+		{Outcome: "playing", Depth: 1, Stats: jsonStats{DLayout: []string{"CaveGen"}}},
+	}
+	s := AggregateStats(runs, dumps)
+	if s.Runs != 4 || s.Deaths != 3 || s.DeathDepths != 12 || s.Killers["an orc"] != 2 {
+		t.Errorf("bad run statistics: %+v", s)
+	}
+	if s.Dumps != 2 || s.Wins != 1 {
+		t.Errorf("bad dump count: %+v", s)
+	}
+	if s.LayoutVisits["CaveGen"] != 3 || s.LayoutDeaths["RuinsGen"] != 1 || s.LayoutDeaths["CaveGen"] != 0 {
+		t.Errorf("bad layout statistics: %v %v", s.LayoutVisits, s.LayoutDeaths)
+	}
+	if s.RodRuns["rod of blinking"] != 2 || s.RodUses["rod of blinking"] != 4 || s.RodRuns["rod of fog"] != 0 {
+		t.Errorf("bad rod statistics: %v %v", s.RodRuns, s.RodUses)
+	}
+	if s.AptRuns["You are strong."] != 1 || s.AptWins["You are strong."] != 1 || s.AptWins["You are agile."] != 0 {
+		t.Errorf("bad aptitude statistics: %v %v", s.AptRuns, s.AptWins)
+	}
+	buf := &bytes.Buffer{}
+	s.Write(buf)
+	out := buf.String()
+	for _, want := range []string{"Win rate: 25.0%", "Average depth of death: 4.0", "an orc", "RuinsGen", "rod of blinking"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in output:\n%s", want, out)
+		}
+	}
+}
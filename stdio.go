@@ -0,0 +1,270 @@
+// +build stdio
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// The stdio backend does not draw anything: it is meant for external
+// programs driving the game through a JSON-lines protocol. Each time the
+// game needs some input, it writes a JSON message on a single line of the
+// standard output, and then reads a single line JSON answer on the
+// standard input.
+//
+// At each player turn, the message is an observation (type "turn"), and the
+// answer should contain an action, like {"action":"explore"}. Item actions
+// ("drink", "throw" and "evoke") need the name of the item, as in the
+// observation inventory, and targeted items need a target position, like
+// {"action":"throw","item":"dart of confusion","target":{"x":10,"y":5}}.
+// An invalid action is reported in the error field of the next
+// observation, without any turn passing. Otherwise
+// (menus, confirmations, welcome screen), the message is a prompt (type
+// "prompt") containing the screen text, and the answer should contain a
+// key, like {"key":"x"}.
+
+type gameui struct {
+	g       *game
+	cursor  position
+	stdin   *bufio.Scanner
+	stdout  *json.Encoder
+	logNext int
+	// below unused for this backend
+	menuHover menu
+	itemHover int
+}
+
+type stdioObservation struct {
+	Type        string         `json:"type"`
+	Turn        int            `json:"turn,omitempty"`
+	Depth       int            `json:"depth,omitempty"`
+	X           int            `json:"x"`
+	Y           int            `json:"y"`
+	HP          int            `json:"hp,omitempty"`
+	HPMax       int            `json:"hpmax,omitempty"`
+	MP          int            `json:"mp,omitempty"`
+	MPMax       int            `json:"mpmax,omitempty"`
+	Simellas    int            `json:"simellas,omitempty"`
+	Statuses    map[string]int `json:"statuses,omitempty"`
+	Map         []string       `json:"map,omitempty"`
+	Screen      []string       `json:"screen,omitempty"`
+	Log         []string       `json:"log,omitempty"`
+	Monsters    []stdioMonster `json:"monsters,omitempty"`
+	Armour      string         `json:"armour,omitempty"`
+	Weapon      string         `json:"weapon,omitempty"`
+	Shield      string         `json:"shield,omitempty"`
+	Consumables map[string]int `json:"consumables,omitempty"`
+	Rods        map[string]int `json:"rods,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
+
+type stdioMonster struct {
+	Kind  string `json:"kind"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	HP    int    `json:"hp"`
+	State string `json:"state"`
+}
+
+type stdioAction struct {
+	Action string    `json:"action"`
+	Key    string    `json:"key"`
+	Item   string    `json:"item"`
+	Target *stdioPos `json:"target"`
+}
+
+type stdioPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+var stdioActions = map[string]keyAction{
+	"W":            KeyW,
+	"S":            KeyS,
+	"N":            KeyN,
+	"E":            KeyE,
+	"NW":           KeyNW,
+	"NE":           KeyNE,
+	"SW":           KeySW,
+	"SE":           KeySE,
+	"run-W":        KeyRunW,
+	"run-S":        KeyRunS,
+	"run-N":        KeyRunN,
+	"run-E":        KeyRunE,
+	"run-NW":       KeyRunNW,
+	"run-NE":       KeyRunNE,
+	"run-SW":       KeyRunSW,
+	"run-SE":       KeyRunSE,
+	"rest":         KeyRest,
+	"wait":         KeyWaitTurn,
+	"descend":      KeyDescend,
+	"go-to-stairs": KeyGoToStairs,
+	"explore":      KeyExplore,
+	"equip":        KeyEquip,
+	"drink":        KeyDrink,
+	"throw":        KeyThrow,
+	"evoke":        KeyEvoke,
+	"quit":         KeyQuit,
+}
+
+func (ui *gameui) Init() error {
+	ui.stdin = bufio.NewScanner(os.Stdin)
+	ui.stdout = json.NewEncoder(os.Stdout)
+	ui.menuHover = -1
+	ui.g.SetController(ui)
+	return nil
+}
+
+func (ui *gameui) Close() {
+}
+
+func (ui *gameui) Flush() {
+	ui.DrawLogFrame()
+}
+
+func (ui *gameui) ApplyToggleLayout() {
+	GameConfig.Small = !GameConfig.Small
+	if GameConfig.Small {
+		UIHeight = 24
+		UIWidth = 80
+	} else {
+		UIHeight = 26
+		UIWidth = 100
+	}
+	ui.g.DrawBuffer = make([]UICell, UIWidth*UIHeight)
+	ui.Clear()
+}
+
+func (ui *gameui) Small() bool {
+	return GameConfig.Small
+}
+
+func (ui *gameui) Interrupt() {
+}
+
+func (ui *gameui) ReadAction() (stdioAction, bool) {
+	var a stdioAction
+	for ui.stdin.Scan() {
+		err := json.Unmarshal(ui.stdin.Bytes(), &a)
+		if err == nil {
+			return a, true
+		}
+		ui.stdout.Encode(stdioObservation{Type: "error", Error: err.Error()})
+	}
+	return a, false
+}
+
+func (ui *gameui) NewLogLines() []string {
+	g := ui.g
+	lines := []string{}
+	for _, e := range g.Log {
+		if e.Index >= ui.logNext {
+			lines = append(lines, e.String())
+		}
+	}
+	ui.logNext = g.LogIndex
+	return lines
+}
+
+func (ui *gameui) PollEvent() (in uiInput) {
+	g := ui.g
+	screen := []string{}
+	for y := 0; y < UIHeight; y++ {
+		var sb strings.Builder
+		for x := 0; x < UIWidth; x++ {
+			sb.WriteRune(g.DrawBuffer[ui.GetIndex(x, y)].R)
+		}
+		screen = append(screen, strings.TrimRight(sb.String(), " "))
+	}
+	ui.stdout.Encode(stdioObservation{Type: "prompt", Screen: screen, Log: ui.NewLogLines()})
+	a, ok := ui.ReadAction()
+	if !ok || a.Key == "" {
+		in.key = "\x1b"
+		return in
+	}
+	in.key = a.Key
+	return in
+}
+
+// NextAction makes the stdio backend a player controller.
+func (ui *gameui) NextAction(v *playerView) playerAction {
+	obs := stdioObservation{
+		Type:        "turn",
+		Turn:        v.Turn / 10,
+		Depth:       v.Depth,
+		X:           v.Pos.X,
+		Y:           v.Pos.Y,
+		HP:          v.HP,
+		HPMax:       v.HPMax,
+		MP:          v.MP,
+		MPMax:       v.MPMax,
+		Simellas:    v.Simellas,
+		Statuses:    map[string]int{},
+		Log:         ui.NewLogLines(),
+		Armour:      v.Armour.String(),
+		Weapon:      v.Weapon.String(),
+		Shield:      v.Shield.String(),
+		Consumables: map[string]int{},
+		Rods:        map[string]int{},
+		Error:       v.Error,
+	}
+	for y := 0; y < DungeonHeight; y++ {
+		var sb strings.Builder
+		for x := 0; x < DungeonWidth; x++ {
+			r, _, _ := ui.PositionDrawing(position{x, y})
+			sb.WriteRune(r)
+		}
+		obs.Map = append(obs.Map, sb.String())
+	}
+	for _, m := range v.Monsters {
+		obs.Monsters = append(obs.Monsters, stdioMonster{Kind: m.Kind.String(), X: m.Pos.X, Y: m.Pos.Y, HP: m.HP, State: m.State.String()})
+	}
+	for st, n := range v.Statuses {
+		obs.Statuses[st.String()] = n
+	}
+	for c, n := range v.Consumables {
+		obs.Consumables[c.String()] = n
+	}
+	for r, n := range v.Rods {
+		obs.Rods[r.String()] = n
+	}
+	ui.stdout.Encode(obs)
+	a, ok := ui.ReadAction()
+	if !ok {
+		return playerAction{Key: KeyQuit}
+	}
+	if k, ok := stdioActions[a.Action]; ok {
+		return stdioPlayerAction(v, k, a)
+	}
+	if utf8.RuneCountInString(a.Key) == 1 {
+		r, _ := utf8.DecodeRuneInString(a.Key)
+		if k, ok := GameConfig.RuneNormalModeKeys[r]; ok {
+			return playerAction{Key: k}
+		}
+	}
+	return playerAction{Key: KeyNothing}
+}
+
+// stdioPlayerAction returns the player action for an action name, looking
+// for the item in the player's inventory.
+func stdioPlayerAction(v *playerView, k keyAction, a stdioAction) playerAction {
+	pa := playerAction{Key: k, Rod: -1, Target: InvalidPos}
+	if a.Target != nil {
+		pa.Target = position{a.Target.X, a.Target.Y}
+	}
+	for c := range v.Consumables {
+		if c.String() == a.Item {
+			pa.Consumable = c
+		}
+	}
+	for r := range v.Rods {
+		if r.String() == a.Item {
+			pa.Rod = r
+		}
+	}
+	return pa
+}
@@ -0,0 +1,60 @@
+// +build stdio
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStdioItemActions(t *testing.T) {
+	g := &game{Seed: 1, noIO: true}
+	ui := &gameui{g: g, menuHover: -1}
+	g.ui = ui
+	g.SetController(ui)
+	g.InitLevel()
+	ui.DrawBufferInit()
+	wall := InvalidPos
+	for _, pos := range g.Player.Pos.ValidNeighbors() {
+		if !g.MonsterAt(pos).Exists() {
+			wall = pos
+			break
+		}
+	}
+	g.Dungeon.SetCell(wall, WallCell)
+	g.ComputeLOS()
+	g.Player.Consumables[ConfusingDart] = 1
+	g.Player.Rods[RodDigging] = rodProps{Charge: 2}
+	g.Player.MP = g.Player.MPMax()
+	input := fmt.Sprintf(`{"action":"drink","item":"%s"}
+{"action":"evoke","item":"%s"}
+{"action":"evoke","item":"%s","target":{"x":%d,"y":%d}}
+`, ConfusingDart, RodDigging, RodDigging, wall.X, wall.Y)
+	ui.stdin = bufio.NewScanner(strings.NewReader(input))
+	out := &bytes.Buffer{}
+	ui.stdout = json.NewEncoder(out)
+	g.EventLoop()
+
+	errs := []string{}
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var obs stdioObservation
+		if err := dec.Decode(&obs); err != nil {
+			t.Fatal(err)
+		}
+		if obs.Type == "turn" {
+			errs = append(errs, obs.Error)
+		}
+	}
+	want := []string{"", "You cannot drink this item.", "You cannot target that place.", ""}
+	if strings.Join(errs, "|") != strings.Join(want, "|") {
+		t.Errorf("bad observation errors: %q", errs)
+	}
+	if g.Dungeon.Cell(wall).T != FreeCell || g.Player.Rods[RodDigging].Charge != 1 {
+		t.Errorf("rod of digging not evoked")
+	}
+}
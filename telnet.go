@@ -0,0 +1,410 @@
+// +build ansi
+
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The telnet server runs a game per connection, with the ansi backend
+// reading from and writing to the connection. The login name is used as
+// profile, so that each user has its own saves and dumps, and a password
+// stored in the profile directory protects it.
+
+const maxTelnetPasswordLength = 64
+
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWILL = 251
+	telnetWONT = 252
+	telnetDO   = 253
+	telnetDONT = 254
+	telnetIAC  = 255
+
+	telnetOptEcho = 1
+	telnetOptSGA  = 3
+	telnetOptNAWS = 31
+)
+
+// telnetConn filters telnet commands out of the input of a connection, and
+// remembers the terminal size sent by the client, if any.
+type telnetConn struct {
+	net.Conn
+	r      *bufio.Reader
+	width  int
+	height int
+	cr     bool // last byte was a carriage return
+}
+
+func newTelnetConn(conn net.Conn) *telnetConn {
+	return &telnetConn{Conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (tc *telnetConn) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && (n == 0 || tc.r.Buffered() > 0) {
+		b, err := tc.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if b == telnetIAC {
+			b, err = tc.Command()
+			if err != nil {
+				return n, err
+			}
+			if b != telnetIAC {
+				continue
+			}
+		} else if tc.cr && (b == 0 || b == '\n') {
+			// end of line sent as CR NUL or CR LF
+			tc.cr = false
+			continue
+		}
+		tc.cr = b == '\r'
+		p[n] = b
+		n++
+	}
+	return n, nil
+}
+
+// Command handles a command following an IAC byte. It returns IAC for an
+// escaped data byte.
+func (tc *telnetConn) Command() (byte, error) {
+	c, err := tc.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch c {
+	case telnetDO, telnetDONT, telnetWILL, telnetWONT:
+		_, err = tc.r.ReadByte()
+	case telnetSB:
+		var data []byte
+		for {
+			b, err := tc.r.ReadByte()
+			if err != nil {
+				return 0, err
+			}
+			if b == telnetIAC {
+				b, err = tc.r.ReadByte()
+				if err != nil {
+					return 0, err
+				}
+				if b == telnetSE {
+					break
+				}
+			}
+			data = append(data, b)
+		}
+		if len(data) == 5 && data[0] == telnetOptNAWS {
+			tc.width = int(data[1])<<8 | int(data[2])
+			tc.height = int(data[3])<<8 | int(data[4])
+		}
+	}
+	return c, err
+}
+
+// ReadLine reads a line typed by the user, echoing it if echo is true.
+func (tc *telnetConn) ReadLine(prompt string, max int, echo bool) (string, error) {
+	_, err := io.WriteString(tc, prompt)
+	if err != nil {
+		return "", err
+	}
+	line := []byte{}
+	buf := make([]byte, 1)
+	for {
+		_, err := tc.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		switch b := buf[0]; b {
+		case '\r', '\n':
+			_, err = io.WriteString(tc, "\r\n")
+			return string(line), err
+		case '\x7f', '\b':
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				if echo {
+					_, err = io.WriteString(tc, "\b \b")
+				}
+			}
+		default:
+			if b >= ' ' && b < '\x7f' && len(line) < max {
+				line = append(line, b)
+				if echo {
+					_, err = tc.Write(buf)
+				}
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// telnetWorld is held by the telnet session whose game is running. Games
+// use global state, like the configuration, the layout or the profile, so
+// sessions take turns: a session releases the
+// lock while waiting for input, saving its global state, and restores it
+// when the input comes, or during animations (see UISleep).
+var telnetWorld sync.Mutex
+
+// telnetCurrent is the session holding telnetWorld.
+var telnetCurrent *telnetSession
+
+// telnetSession is the global state of the game of a telnet session.
+type telnetSession struct {
+	config     config
+	customKeys bool
+	small      bool
+	width      int
+	height     int
+	profile    string
+}
+
+func (st *telnetSession) Acquire() {
+	telnetWorld.Lock()
+	GameConfig = st.config
+	CustomKeys = st.customKeys
+	ForcedSmall = st.small
+	UIWidth, UIHeight = st.width, st.height
+	Profile = st.profile
+	ApplyConfig()
+	telnetCurrent = st
+}
+
+func (st *telnetSession) Release() {
+	st.config = GameConfig
+	st.customKeys = CustomKeys
+	st.small = ForcedSmall
+	st.width, st.height = UIWidth, UIHeight
+	st.profile = Profile
+	telnetWorld.Unlock()
+}
+
+// telnetSleep lets other sessions run while the current one waits.
+func telnetSleep(d time.Duration) {
+	st := telnetCurrent
+	st.Release()
+	time.Sleep(d)
+	st.Acquire()
+}
+
+const telnetPasswordFile = "telnet-password"
+
+// telnetPasswordRounds is the number of SHA-256 rounds used to hash
+// passwords, to slow down guessing from a stolen password file.
+const telnetPasswordRounds = 1 << 16
+
+func HashTelnetPassword(salt []byte, password string) []byte {
+	h := sha256.Sum256(append(salt, password...))
+	for i := 1; i < telnetPasswordRounds; i++ {
+		h = sha256.Sum256(h[:])
+	}
+	return h[:]
+}
+
+// CheckTelnetPassword reports whether password is the one of the profile,
+// as stored in its directory by SetTelnetPassword. It returns an error if
+// the profile has no password yet.
+func CheckTelnetPassword(dir, password string) (bool, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, telnetPasswordFile))
+	if err != nil {
+		return false, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return false, errors.New("bad password file")
+	}
+	salt, err := hex.DecodeString(fields[0])
+	if err != nil {
+		return false, err
+	}
+	hash, err := hex.DecodeString(fields[1])
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(hash, HashTelnetPassword(salt, password)) == 1, nil
+}
+
+// SetTelnetPassword stores the password of a profile without one yet.
+func SetTelnetPassword(dir, password string) error {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, telnetPasswordFile), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "%x %x\n", salt, HashTelnetPassword(salt, password))
+	if errc := f.Close(); err == nil {
+		err = errc
+	}
+	return err
+}
+
+type telnetServer struct {
+	small   bool // small layout for every session
+	mu      sync.Mutex
+	players map[string]bool
+}
+
+// ServeTelnet accepts telnet connections on the given address.
+func ServeTelnet(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	log.Printf("boohu: telnet server listening on %s\n", ln.Addr())
+	ProfileLocked = true
+	UISleep = telnetSleep
+	s := &telnetServer{small: ForcedSmall, players: map[string]bool{}}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.Session(conn)
+	}
+}
+
+// Login marks a user as playing. It returns false if the user is already
+// playing from another connection.
+func (s *telnetServer) Login(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.players[name] {
+		return false
+	}
+	s.players[name] = true
+	return true
+}
+
+func (s *telnetServer) Logout(name string) {
+	s.mu.Lock()
+	delete(s.players, name)
+	s.mu.Unlock()
+}
+
+// Authenticate asks for the login name and password of the user. A user
+// logging in with a profile without password chooses one.
+func (s *telnetServer) Authenticate(tc *telnetConn) (string, error) {
+	for {
+		name, err := tc.ReadLine("Login name: ", maxProfileLength, true)
+		if err != nil {
+			return "", err
+		}
+		if !ValidProfileName(name) {
+			fmt.Fprintf(tc, "Invalid name: use up to %d letters, digits, - or _.\r\n", maxProfileLength)
+			continue
+		}
+		dir := filepath.Join(BaseDataDir(), "profiles", name)
+		password, err := tc.ReadLine("Password: ", maxTelnetPasswordLength, false)
+		if err != nil {
+			return "", err
+		}
+		ok, err := CheckTelnetPassword(dir, password)
+		if os.IsNotExist(err) {
+			fmt.Fprintf(tc, "%s has no password yet: type it again to set it.\r\n", name)
+			var again string
+			again, err = tc.ReadLine("Password: ", maxTelnetPasswordLength, false)
+			if err != nil {
+				return "", err
+			}
+			if again != password || password == "" {
+				fmt.Fprintf(tc, "Passwords differ or are empty.\r\n")
+				continue
+			}
+			err = SetTelnetPassword(dir, password)
+			ok = err == nil
+		}
+		if err != nil {
+			log.Printf("boohu: %s: password: %v\n", name, err)
+			fmt.Fprintf(tc, "Could not check password.\r\n")
+			continue
+		}
+		if !ok {
+			fmt.Fprintf(tc, "Wrong password.\r\n")
+			continue
+		}
+		return name, nil
+	}
+}
+
+func (s *telnetServer) Session(conn net.Conn) {
+	defer conn.Close()
+	tc := newTelnetConn(conn)
+	_, err := tc.Write([]byte{telnetIAC, telnetWILL, telnetOptEcho, telnetIAC, telnetWILL, telnetOptSGA, telnetIAC, telnetDO, telnetOptNAWS})
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(tc, "Welcome to Boohu %s!\r\n", Version)
+	var name string
+	for {
+		name, err = s.Authenticate(tc)
+		if err != nil {
+			return
+		}
+		if !s.Login(name) {
+			fmt.Fprintf(tc, "%s is already playing.\r\n", name)
+			continue
+		}
+		break
+	}
+	defer s.Logout(name)
+	log.Printf("boohu: %s logged in from %s\n", name, conn.RemoteAddr())
+	st := &telnetSession{width: 100, height: 26, small: s.small, profile: name}
+	if tc.width > 0 && tc.height > 0 {
+		if tc.width < 80 || tc.height < 24 {
+			fmt.Fprintf(tc, "Your terminal is %dx%d, but the game needs at least 80x24.\r\n", tc.width, tc.height)
+		}
+		if tc.width < 100 || tc.height < 26 {
+			st.small = true
+		}
+	}
+	ui := &gameui{in: tc, out: conn, session: st}
+	g := &game{}
+	ui.g = g
+	st.Acquire()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("boohu: %s: game crashed: %v\n%s", name, r, debug.Stack())
+		}
+		// the lock is always held outside of PollEvent
+		st.Release()
+		log.Printf("boohu: %s logged out\n", name)
+	}()
+	err = ui.Init()
+	if err != nil {
+		log.Printf("boohu: %s: %v\n", name, err)
+		return
+	}
+	defer ui.Close()
+	ui.PlayGame()
+}
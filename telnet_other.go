@@ -0,0 +1,9 @@
+// +build !ansi,!js
+
+package main
+
+import "errors"
+
+func ServeTelnet(addr string) error {
+	return errors.New("the telnet server needs a build with the ansi tag")
+}
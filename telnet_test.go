@@ -0,0 +1,147 @@
+// +build ansi
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTelnetConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		client.Write([]byte{'a', 'b', telnetIAC, telnetWILL, telnetOptNAWS,
+			telnetIAC, telnetSB, telnetOptNAWS, 0, 120, 0, 40, telnetIAC, telnetSE,
+			'c', '\r', 0, telnetIAC, telnetIAC, 'd', '\r', '\n'})
+		client.Close()
+	}()
+	tc := newTelnetConn(server)
+	data, err := ioutil.ReadAll(tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "abc\r\xffd\r" {
+		t.Errorf("bad data: %q", data)
+	}
+	if tc.width != 120 || tc.height != 40 {
+		t.Errorf("bad size: %dx%d", tc.width, tc.height)
+	}
+}
+
+func TestTelnetReadLine(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		buf := make([]byte, 100)
+		client.Read(buf) // prompt
+		for _, b := range []byte("bobx\x7f\r\n") {
+			client.Write([]byte{b})
+			client.Read(buf) // echo
+		}
+		io.Copy(ioutil.Discard, client)
+	}()
+	tc := newTelnetConn(server)
+	name, err := tc.ReadLine("Login name: ", maxProfileLength, true)
+	server.Close()
+	if err != nil || name != "bob" {
+		t.Errorf("bad line: %q (%v)", name, err)
+	}
+}
+
+func TestTelnetSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boohu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xdg := os.Getenv("XDG_DATA_HOME")
+	defer os.Setenv("XDG_DATA_HOME", xdg)
+	os.Setenv("XDG_DATA_HOME", dir)
+	// keep the global state of other tests out of the session
+	saved := &telnetSession{}
+	telnetWorld.Lock()
+	saved.Release()
+	defer func() {
+		saved.Acquire()
+		telnetWorld.Unlock()
+	}()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	s := &telnetServer{players: map[string]bool{}}
+	session := func(input string) string {
+		go func() {
+			conn, err := ln.Accept()
+			if err == nil {
+				s.Session(conn)
+			}
+		}()
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.Write([]byte(input))
+		// lost connection
+		conn.(*net.TCPConn).CloseWrite()
+		conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+		out, err := ioutil.ReadAll(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(out)
+	}
+	profile := filepath.Join(dir, "boohu", "profiles", "bob")
+	session("bob\r\nsecret\r\nsecret\r\np")
+	if _, err := os.Stat(filepath.Join(profile, "save")); err != nil {
+		t.Errorf("game not saved: %v", err)
+	}
+	if ok, err := CheckTelnetPassword(profile, "secret"); !ok || err != nil {
+		t.Errorf("password not set: %v", err)
+	}
+	out := session("bob\r\nsesame\r\n")
+	if !strings.Contains(out, "Wrong password.") || strings.Contains(out, "sesame") {
+		t.Errorf("bad output for a wrong password: %q", out)
+	}
+}
+
+func TestTelnetSleep(t *testing.T) {
+	saved := &telnetSession{}
+	telnetWorld.Lock()
+	saved.Release()
+	defer func() {
+		saved.Acquire()
+		telnetWorld.Unlock()
+	}()
+	a := &telnetSession{profile: "a"}
+	b := &telnetSession{profile: "b"}
+	a.Acquire()
+	done := make(chan bool, 1)
+	go func() {
+		b.Acquire()
+		Profile = "c"
+		b.Release()
+		done <- true
+	}()
+	// other sessions run during animations
+	telnetSleep(200 * time.Millisecond)
+	select {
+	case <-done:
+	default:
+		t.Errorf("other session blocked during sleep")
+	}
+	if Profile != "a" || b.profile != "c" {
+		t.Errorf("bad session state: %q %q", Profile, b.profile)
+	}
+	a.Release()
+}
@@ -1,4 +1,4 @@
-// +build !tcell,!ansi,!js,!tk
+// +build !tcell,!ansi,!js,!tk,!stdio,!web
 
 package main
 
@@ -1,4 +1,4 @@
-// +build !js,!tk
+// +build !js,!tk,!web
 
 package main
 
@@ -6,6 +6,9 @@ func (ui *gameui) ApplyToggleTiles() {
 }
 
 func (ui *gameui) PostConfig() {
+	if ForcedSmall {
+		GameConfig.Small = true
+	}
 	if GameConfig.Small {
 		UIHeight = 24
 		UIWidth = 80
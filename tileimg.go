@@ -0,0 +1,196 @@
+// +build js tk web gif
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+)
+
+func (c uicolor) Color() color.Color {
+	cl := color.RGBA{}
+	opaque := uint8(255)
+	switch c {
+	case 0:
+		cl = color.RGBA{7, 54, 66, opaque}
+	case 1:
+		cl = color.RGBA{220, 50, 47, opaque}
+	case 2:
+		cl = color.RGBA{133, 153, 0, opaque}
+	case 3:
+		cl = color.RGBA{181, 137, 0, opaque}
+	case 4:
+		cl = color.RGBA{38, 139, 210, opaque}
+	case 5:
+		cl = color.RGBA{211, 54, 130, opaque}
+	case 6:
+		cl = color.RGBA{42, 161, 152, opaque}
+	case 7:
+		cl = color.RGBA{238, 232, 213, opaque}
+	case 8:
+		cl = color.RGBA{0, 43, 54, opaque}
+	case 9:
+		cl = color.RGBA{203, 75, 22, opaque}
+	case 10:
+		cl = color.RGBA{88, 110, 117, opaque}
+	case 11:
+		cl = color.RGBA{101, 123, 131, opaque}
+	case 12:
+		cl = color.RGBA{131, 148, 150, opaque}
+	case 13:
+		cl = color.RGBA{108, 113, 196, opaque}
+	case 14:
+		cl = color.RGBA{147, 161, 161, opaque}
+	case 15:
+		cl = color.RGBA{253, 246, 227, opaque}
+	}
+	return cl
+}
+
+var TileImgs map[string][]byte
+
+var MapNames = map[rune]string{
+	'¤':  "frontier",
+	'√':  "hit",
+	'Φ':  "magic",
+	'☻':  "dreaming",
+	'♫':  "footsteps",
+	'#':  "wall",
+	'@':  "player",
+	'§':  "fog",
+	'♣':  "simella",
+	'+':  "door",
+	'.':  "ground",
+	'"':  "foliage",
+	'•':  "tick",
+	'●':  "rock",
+	'×':  "times",
+	',':  "comma",
+	'}':  "rbrace",
+	'%':  "percent",
+	':':  "colon",
+	'\\': "backslash",
+	'~':  "tilde",
+	'☼':  "sun",
+	'*':  "asterisc",
+	'—':  "hbar",
+	'/':  "slash",
+	'|':  "vbar",
+	'∞':  "kill",
+	' ':  "space",
+	'[':  "lbracket",
+	']':  "rbracket",
+	')':  "rparen",
+	'(':  "lparen",
+	'>':  "stairs",
+	'Δ':  "portal",
+	'!':  "potion",
+	';':  "semicolon",
+	'_':  "stone",
+}
+
+var LetterNames = map[rune]string{
+	'(':  "lparen",
+	')':  "rparen",
+	'@':  "player",
+	'{':  "lbrace",
+	'}':  "rbrace",
+	'[':  "lbracket",
+	']':  "rbracket",
+	'♪':  "music1",
+	'♫':  "music2",
+	'•':  "tick",
+	'♣':  "simella",
+	' ':  "space",
+	'!':  "exclamation",
+	'?':  "interrogation",
+	',':  "comma",
+	':':  "colon",
+	';':  "semicolon",
+	'\'': "quote",
+	'—':  "longhyphen",
+	'-':  "hyphen",
+	'|':  "pipe",
+	'/':  "slash",
+	'\\': "backslash",
+	'%':  "percent",
+	'┐':  "boxne",
+	'┤':  "boxe",
+	'│':  "vbar",
+	'┘':  "boxse",
+	'─':  "hbar",
+	'►':  "arrow",
+	'×':  "times",
+	'.':  "dot",
+	'#':  "hash",
+	'"':  "quotes",
+	'+':  "plus",
+	'“':  "lquotes",
+	'”':  "rquotes",
+	'=':  "equal",
+	'>':  "gt",
+	'Δ':  "portal",
+	'¤':  "frontier",
+	'√':  "hit",
+	'Φ':  "magic",
+	'§':  "fog",
+	'●':  "rock",
+	'~':  "tilde",
+	'☼':  "sun",
+	'*':  "asterisc",
+	'∞':  "kill",
+	'☻':  "dreaming",
+	'…':  "dots",
+	'_':  "stone",
+}
+
+// TileName returns the name in TileImgs of the image of a cell, using map
+// tiles for map cells if tiles is true, and letter tiles otherwise.
+func TileName(cell UICell, tiles bool) string {
+	prefix, names := "letter-", LetterNames
+	if cell.InMap && tiles {
+		prefix, names = "map-", MapNames
+	}
+	if _, ok := TileImgs[prefix+string(cell.R)]; ok {
+		return prefix + string(cell.R)
+	}
+	if _, ok := TileImgs[prefix+names[cell.R]]; ok {
+		return prefix + names[cell.R]
+	}
+	return "map-notile"
+}
+
+// TileImage returns the image of a cell, colored with its foreground and
+// background colors.
+func TileImage(cell UICell, tiles bool) *image.RGBA {
+	pngImg := TileImgs[TileName(cell, tiles)]
+	buf := make([]byte, len(pngImg))
+	base64.StdEncoding.Decode(buf, pngImg) // TODO: check error
+	br := bytes.NewReader(buf)
+	img, err := png.Decode(br)
+	if err != nil {
+		log.Printf("Could not decode png: %v", err)
+	}
+	rect := img.Bounds()
+	rgbaimg := image.NewRGBA(rect)
+	draw.Draw(rgbaimg, rect, img, rect.Min, draw.Src)
+	bgc := cell.Bg.Color()
+	fgc := cell.Fg.Color()
+	for y := 0; y < rect.Max.Y; y++ {
+		for x := 0; x < rect.Max.X; x++ {
+			c := rgbaimg.At(x, y)
+			r, _, _, _ := c.RGBA()
+			if r == 0 {
+				rgbaimg.Set(x, y, bgc)
+			} else {
+				rgbaimg.Set(x, y, fgc)
+			}
+		}
+	}
+	return rgbaimg
+}
@@ -83,6 +83,7 @@ bind .c <ButtonPress> {
 `)
 	ui.menuHover = -1
 
+	ForcedPalette = PaletteSolarized
 	SolarizedPalette()
 	ui.HideCursor()
 	settingsActions = append(settingsActions, toggleTiles)
@@ -3,7 +3,9 @@ package main
 import (
 	"errors"
 	"fmt"
+	"log"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 	"unicode"
@@ -44,7 +46,7 @@ func (ui *gameui) KeyToRuneKeyAction(in uiInput) rune {
 func (ui *gameui) WaitForContinue(line int) {
 loop:
 	for {
-		in := ui.PollEvent()
+		in := ui.PollGameEvent()
 		r := ui.KeyToRuneKeyAction(in)
 		switch r {
 		case '\x1b', ' ', 'x', 'X':
@@ -65,7 +67,7 @@ loop:
 
 func (ui *gameui) PromptConfirmation() bool {
 	for {
-		in := ui.PollEvent()
+		in := ui.PollGameEvent()
 		switch in.key {
 		case "Y", "y":
 			return true
@@ -92,31 +94,77 @@ type startAction int
 const (
 	StartPlay startAction = iota
 	StartWatchReplay
+	StartDaily
+	StartProfile
+	StartHallOfFame
 )
 
+func (a startAction) String() (text string) {
+	switch a {
+	case StartPlay:
+		text = "(P)lay"
+	case StartWatchReplay:
+		text = "(W)atch replay"
+	case StartDaily:
+		text = "(D)aily challenge"
+	case StartProfile:
+		text = fmt.Sprintf("(C)hange profile [%s]", ProfileString(Profile))
+	case StartHallOfFame:
+		text = "(H)all of fame"
+	}
+	return text
+}
+
+func (a startAction) Key() (key string) {
+	switch a {
+	case StartPlay:
+		key = "p"
+	case StartWatchReplay:
+		key = "w"
+	case StartDaily:
+		key = "d"
+	case StartProfile:
+		key = "c"
+	case StartHallOfFame:
+		key = "h"
+	}
+	return key
+}
+
+func StartActions() []startAction {
+	if runtime.GOARCH == "wasm" {
+		return []startAction{StartPlay, StartWatchReplay, StartDaily, StartHallOfFame}
+	}
+	if ProfileLocked {
+		return []startAction{StartPlay, StartDaily, StartHallOfFame}
+	}
+	return []startAction{StartPlay, StartDaily, StartProfile, StartHallOfFame}
+}
+
 func (ui *gameui) StartMenu(l int) startAction {
+	actions := StartActions()
 	for {
 		in := ui.PollEvent()
-		switch in.key {
-		case "P", "p":
-			ui.ColorLine(l, ColorYellow)
-			ui.Flush()
-			time.Sleep(10 * time.Millisecond)
-			return StartPlay
-		case "W", "w":
-			ui.ColorLine(l+1, ColorYellow)
-			ui.Flush()
-			time.Sleep(10 * time.Millisecond)
-			return StartWatchReplay
+		for i, a := range actions {
+			if strings.ToLower(in.key) == a.Key() {
+				ui.ColorLine(l+i, ColorYellow)
+				ui.Flush()
+				UISleep(10 * time.Millisecond)
+				return a
+			}
 		}
 		if in.key != "" && !in.mouse {
+			if runtime.GOARCH != "wasm" {
+				// any other key starts a normal game
+				return StartPlay
+			}
 			continue
 		}
 		y := in.mouseY
 		switch in.button {
 		case -1:
 			oih := ui.itemHover
-			if y < l || y >= l+2 {
+			if y < l || y >= l+len(actions) {
 				ui.itemHover = -1
 				if oih != -1 {
 					ui.ColorLine(oih, ColorFg)
@@ -134,83 +182,29 @@ func (ui *gameui) StartMenu(l int) startAction {
 			}
 			ui.Flush()
 		case 0:
-			if y < l || y >= l+2 {
+			if y < l || y >= l+len(actions) {
 				ui.itemHover = -1
 				break
 			}
 			ui.itemHover = -1
-			switch y - l {
-			case 0:
-				return StartPlay
-			case 1:
-				return StartWatchReplay
-			}
+			return actions[y-l]
 		}
 	}
 }
 
 func (ui *gameui) PlayerTurnEvent(ev event) (err error, again, quit bool) {
-	g := ui.g
 	again = true
-	in := ui.PollEvent()
-	switch in.key {
-	case "":
-		if in.mouse {
-			pos := position{X: in.mouseX, Y: in.mouseY}
-			switch in.button {
-			case -1:
-				if in.mouseY == DungeonHeight {
-					m, ok := ui.WhichButton(in.mouseX)
-					omh := ui.menuHover
-					if ok {
-						ui.menuHover = m
-					} else {
-						ui.menuHover = -1
-					}
-					if ui.menuHover != omh {
-						ui.DrawMenus()
-						ui.Flush()
-					}
-					break
-				}
-				ui.menuHover = -1
-				if in.mouseX >= DungeonWidth || in.mouseY >= DungeonHeight {
-					again = true
-					break
-				}
-				fallthrough
-			case 0:
-				if in.mouseY == DungeonHeight {
-					m, ok := ui.WhichButton(in.mouseX)
-					if !ok {
-						again = true
-						break
-					}
-					err, again, quit = ui.HandleKeyAction(runeKeyAction{k: m.Key(g)})
-					if err != nil {
-						again = true
-					}
-					return err, again, quit
-				} else if in.mouseX >= DungeonWidth || in.mouseY >= DungeonHeight {
-					again = true
-				} else {
-					err, again, quit = ui.ExaminePos(ev, pos)
-				}
-			case 2:
-				err, again, quit = ui.HandleKeyAction(runeKeyAction{k: KeyMenu})
-				if err != nil {
-					again = true
-				}
-				return err, again, quit
-			}
-		}
-	default:
-		r := ui.KeyToRuneKeyAction(in)
-		if r == 0 {
-			again = true
-		} else {
-			err, again, quit = ui.HandleKeyAction(runeKeyAction{r: r})
-		}
+	in, ok := ui.PollMapEvent(false)
+	if !ok {
+		return err, again, quit
+	}
+	switch {
+	case in.kind == InputKeyAction:
+		err, again, quit = ui.HandleKeyAction(in.rka)
+	case in.kind == InputMapClick && in.button == 2:
+		err, again, quit = ui.HandleKeyAction(runeKeyAction{k: KeyMenu})
+	case in.pos.valid() && (in.kind == InputMapPoint || in.button == 0):
+		err, again, quit = ui.ExaminePos(ev, in.pos)
 	}
 	if err != nil {
 		again = true
@@ -219,7 +213,7 @@ func (ui *gameui) PlayerTurnEvent(ev event) (err error, again, quit bool) {
 }
 
 func (ui *gameui) Scroll(n int) (m int, quit bool) {
-	in := ui.PollEvent()
+	in := ui.PollGameEvent()
 	switch in.key {
 	case "Escape", "\x1b", " ", "x", "X":
 		quit = true
@@ -267,7 +261,7 @@ func (ui *gameui) Select(l int) (index int, alternate bool, err error) {
 		ui.itemHover = -1
 	}
 	for {
-		in := ui.PollEvent()
+		in := ui.PollGameEvent()
 		r := ui.ReadKey(in.key)
 		switch {
 		case in.key == "\x1b" || in.key == "Escape" || in.key == " " || in.key == "x" || in.key == "X":
@@ -353,7 +347,7 @@ func (ui *gameui) Select(l int) (index int, alternate bool, err error) {
 }
 
 func (ui *gameui) KeyMenuAction(n int) (m int, action keyConfigAction) {
-	in := ui.PollEvent()
+	in := ui.PollGameEvent()
 	r := ui.KeyToRuneKeyAction(in)
 	switch string(r) {
 	case "a":
@@ -390,66 +384,43 @@ func (ui *gameui) KeyMenuAction(n int) (m int, action keyConfigAction) {
 func (ui *gameui) TargetModeEvent(targ Targeter, data *examineData) (err error, again, quit, notarg bool) {
 	g := ui.g
 	again = true
-	in := ui.PollEvent()
-	switch in.key {
-	case "\x1b", "Escape", " ", "x", "X":
-		g.Targeting = InvalidPos
-		notarg = true
-	case "":
-		if !in.mouse {
+	in, ok := ui.PollMapEvent(true)
+	if !ok {
+		return
+	}
+	switch in.kind {
+	case InputKeyAction:
+		switch in.rka.r {
+		case '\x1b', ' ', 'x', 'X':
+			g.Targeting = InvalidPos
+			notarg = true
 			return
 		}
-		switch in.button {
-		case -1:
-			if in.mouseY == DungeonHeight {
-				m, ok := ui.WhichButton(in.mouseX)
-				omh := ui.menuHover
-				if ok {
-					ui.menuHover = m
-				} else {
-					ui.menuHover = -1
-				}
-				if ui.menuHover != omh {
-					ui.DrawMenus()
-					ui.Flush()
-				}
-				g.Targeting = InvalidPos
-				notarg = true
-				err = errors.New(DoNothing)
-				break
-			}
-			ui.menuHover = -1
-			if in.mouseY >= DungeonHeight || in.mouseX >= DungeonWidth {
-				g.Targeting = InvalidPos
-				notarg = true
-				err = errors.New(DoNothing)
-				break
-			}
-			mpos := position{in.mouseX, in.mouseY}
-			if g.Targeting == mpos {
-				break
-			}
+		return ui.CursorKeyAction(targ, in.rka, data)
+	case InputMapPoint:
+		if !in.pos.valid() {
 			g.Targeting = InvalidPos
-			fallthrough
+			notarg = true
+			err = errors.New(DoNothing)
+			break
+		}
+		if g.Targeting == in.pos {
+			break
+		}
+		g.Targeting = InvalidPos
+		again, notarg = ui.CursorMouseLeft(targ, in.pos, data)
+	case InputMapClick:
+		switch in.button {
 		case 0:
-			if in.mouseY == DungeonHeight {
-				m, ok := ui.WhichButton(in.mouseX)
-				if !ok {
-					g.Targeting = InvalidPos
-					notarg = true
-					err = errors.New(DoNothing)
-					break
-				}
-				err, again, quit, notarg = ui.CursorKeyAction(targ, runeKeyAction{k: m.Key(g)}, data)
-			} else if in.mouseX >= DungeonWidth || in.mouseY >= DungeonHeight {
+			if !in.pos.valid() {
 				g.Targeting = InvalidPos
 				notarg = true
 				err = errors.New(DoNothing)
-			} else {
-				again, notarg = ui.CursorMouseLeft(targ, position{X: in.mouseX, Y: in.mouseY}, data)
+				break
 			}
+			again, notarg = ui.CursorMouseLeft(targ, in.pos, data)
 		case 2:
-			if in.mouseY >= DungeonHeight || in.mouseX >= DungeonWidth {
+			if !in.pos.valid() {
 				err, again, quit, notarg = ui.CursorKeyAction(targ, runeKeyAction{k: KeyMenu}, data)
 			} else {
 				err, again, quit, notarg = ui.CursorKeyAction(targ, runeKeyAction{k: KeyDescription}, data)
@@ -457,19 +428,28 @@ func (ui *gameui) TargetModeEvent(targ Targeter, data *examineData) (err error,
 		case 1:
 			err, again, quit, notarg = ui.CursorKeyAction(targ, runeKeyAction{k: KeyExclude}, data)
 		}
-	default:
-		r := ui.KeyToRuneKeyAction(in)
-		if r != 0 {
-			return ui.CursorKeyAction(targ, runeKeyAction{r: r}, data)
-		}
-		again = true
 	}
 	return
 }
 
+// HoverMenus highlights the menu button under the mouse, if any.
+func (ui *gameui) HoverMenus(x, y int) {
+	omh := ui.menuHover
+	ui.menuHover = -1
+	if y == DungeonHeight {
+		if m, ok := ui.WhichButton(x); ok {
+			ui.menuHover = m
+		}
+		if ui.menuHover != omh {
+			ui.DrawMenus()
+			ui.Flush()
+		}
+	}
+}
+
 func (ui *gameui) ReadRuneKey() rune {
 	for {
-		in := ui.PollEvent()
+		in := ui.PollGameEvent()
 		switch in.key {
 		case "\x1b", "Escape", " ", "x", "X":
 			return 0
@@ -824,7 +804,12 @@ func (k keyAction) TargetingModeKey() bool {
 var GameConfig config
 
 func ApplyDefaultKeyBindings() {
-	GameConfig.RuneNormalModeKeys = map[rune]keyAction{
+	GameConfig.RuneNormalModeKeys, GameConfig.RuneTargetModeKeys = DefaultKeyBindings()
+	CustomKeys = false
+}
+
+func DefaultKeyBindings() (normal, target map[rune]keyAction) {
+	normal = map[rune]keyAction{
 		'h': KeyW,
 		'j': KeyS,
 		'k': KeyN,
@@ -878,7 +863,7 @@ func ApplyDefaultKeyBindings() {
 		'@': KeyWizardInfo,
 		'=': KeyConfigure,
 	}
-	GameConfig.RuneTargetModeKeys = map[rune]keyAction{
+	target = map[rune]keyAction{
 		'h':    KeyW,
 		'j':    KeyS,
 		'k':    KeyN,
@@ -925,7 +910,7 @@ func ApplyDefaultKeyBindings() {
 		'X':    KeyEscape,
 		'?':    KeyHelp,
 	}
-	CustomKeys = false
+	return normal, target
 }
 
 type runeKeyAction struct {
@@ -935,18 +920,15 @@ type runeKeyAction struct {
 
 func (ui *gameui) HandleKeyAction(rka runeKeyAction) (err error, again bool, quit bool) {
 	g := ui.g
-	if rka.r != 0 {
-		var ok bool
-		rka.k, ok = GameConfig.RuneNormalModeKeys[rka.r]
-		if !ok {
-			switch rka.r {
-			case 's':
-				err = errors.New("Unknown key. Did you mean capital S for save and quit?")
-			default:
-				err = fmt.Errorf("Unknown key '%c'. Type ? for help.", rka.r)
-			}
-			return err, again, quit
+	if rka.k == KeyNothing {
+		// rune without key action (see PollMapEvent)
+		switch rka.r {
+		case 's':
+			err = errors.New("Unknown key. Did you mean capital S for save and quit?")
+		default:
+			err = fmt.Errorf("Unknown key '%c'. Type ? for help.", rka.r)
 		}
+		return err, again, quit
 	}
 	if rka.k == KeyMenu {
 		rka.k, err = ui.SelectAction(menuActions, g.Ev)
@@ -1001,25 +983,7 @@ func (ui *gameui) HandleKey(rka runeKeyAction) (err error, again bool, quit bool
 			err = errors.New("No stairs here.")
 		}
 	case KeyGoToStairs:
-		stairs := g.StairsSlice()
-		sortedStairs := g.SortedNearestTo(stairs, g.Player.Pos)
-		if len(sortedStairs) > 0 {
-			stair := sortedStairs[0]
-			if g.Player.Pos == stair {
-				err = errors.New("You are already on the stairs.")
-				break
-			}
-			ex := &examiner{stairs: true}
-			err = ex.Action(g, stair)
-			if err == nil && !g.MoveToTarget(g.Ev) {
-				err = errors.New("You could not move toward stairs.")
-			}
-			if ex.Done() {
-				g.Targeting = InvalidPos
-			}
-		} else {
-			err = errors.New("You cannot go to any stairs.")
-		}
+		err = g.GoToStairs(g.Ev)
 	case KeyEquip:
 		err = g.Equip(g.Ev)
 		ui.MenuSelectedAnimation(MenuInteract, err == nil)
@@ -1242,13 +1206,10 @@ func (ui *gameui) CursorKeyAction(targ Targeter, rka runeKeyAction, data *examin
 	g := ui.g
 	pos := data.npos
 	again = true
-	if rka.r != 0 {
-		var ok bool
-		rka.k, ok = GameConfig.RuneTargetModeKeys[rka.r]
-		if !ok {
-			err = fmt.Errorf("Invalid targeting mode key '%c'. Type ? for help.", rka.r)
-			return err, again, quit, notarg
-		}
+	if rka.k == KeyNothing {
+		// rune without key action (see PollMapEvent)
+		err = fmt.Errorf("Invalid targeting mode key '%c'. Type ? for help.", rka.r)
+		return err, again, quit, notarg
 	}
 	if rka.k == KeyMenu {
 		rka.k, err = ui.SelectAction(menuActions, g.Ev)
@@ -1598,7 +1559,7 @@ func (ui *gameui) Death() {
 	g.Print("You die... [(x) to continue]")
 	ui.DrawDungeonView(NormalMode)
 	ui.WaitForContinue(-1)
-	err := g.WriteDump()
+	err := g.WriteMorgue()
 	ui.Dump(err)
 	ui.WaitForContinue(-1)
 }
@@ -1616,7 +1577,7 @@ func (ui *gameui) Win() {
 	}
 	ui.DrawDungeonView(NormalMode)
 	ui.WaitForContinue(-1)
-	err = g.WriteDump()
+	err = g.WriteMorgue()
 	ui.Dump(err)
 	ui.WaitForContinue(-1)
 }
@@ -1642,12 +1603,19 @@ func (ui *gameui) Quit() bool {
 	ui.DrawDungeonView(NormalMode)
 	quit := ui.PromptConfirmation()
 	if quit {
+		g.EndGame("quit")
 		err := g.RemoveSaveFile()
 		if err != nil {
 			g.PrintfStyled("Error removing save file: %v [press any key to quit]", logError, err)
 			ui.DrawDungeonView(NormalMode)
 			ui.PressAnyKey()
 		}
+		err = g.WriteMorgue()
+		if err != nil {
+			g.PrintfStyled("Error archiving game: %v [press any key to quit]", logError, err)
+			ui.DrawDungeonView(NormalMode)
+			ui.PressAnyKey()
+		}
 	} else {
 		g.Print(DoNothing)
 	}
@@ -1665,6 +1633,18 @@ func (ui *gameui) HandlePlayerTurn(ev event) bool {
 	g := ui.g
 getKey:
 	for {
+		if g.inputReplay != nil && g.inputReplay.done {
+			return true
+		}
+		if g.hangup {
+			g.Ev.Renew(g, 0)
+			g.Targeting = InvalidPos
+			err := g.Save()
+			if err != nil {
+				log.Printf("boohu: could not save game after input was closed: %v", err)
+			}
+			return true
+		}
 		var err error
 		var again, quit bool
 		if g.Targeting.valid() {
@@ -1684,6 +1664,17 @@ getKey:
 }
 
 func (ui *gameui) ExploreStep() bool {
+	g := ui.g
+	if g.controller != nil {
+		// player controllers cannot interrupt auto-exploration
+		ui.DrawDungeonView(NormalMode)
+		return false
+	}
+	if g.inputReplay != nil {
+		stop := g.inputReplay.NextExploreStep()
+		ui.DrawDungeonView(NormalMode)
+		return stop
+	}
 	next := make(chan bool)
 	var stop bool
 	go func() {
@@ -1696,6 +1687,7 @@ func (ui *gameui) ExploreStep() bool {
 		next <- !interrupted
 	}()
 	stop = <-next
+	g.RecordInput(recordedInput{Kind: InputExploreStep, Stop: stop})
 	ui.DrawDungeonView(NormalMode)
 	return stop
 }
@@ -1719,6 +1711,14 @@ func ApplyConfig() {
 	if GameConfig.RuneNormalModeKeys == nil || GameConfig.RuneTargetModeKeys == nil {
 		ApplyDefaultKeyBindings()
 	}
+	palette := GameConfig.Palette
+	if ForcedPalette != "" {
+		palette = ForcedPalette
+	} else if palette == "" {
+		palette = DefaultPalette
+	}
+	ApplyPalette(palette)
+	DisableAnimations = ForcedNoAnimations || GameConfig.NoAnimations
 	if GameConfig.DarkLOS {
 		ApplyDarkLOS()
 	} else {
@@ -18,12 +18,20 @@ func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
-func RandInt(n int) int {
+// RandInt returns a random number in [0, n) from the game's own random
+// source.
+func (g *game) RandInt(n int) int {
+	return g.Rand.Intn(n)
+}
+
+// RandIntUI returns a random number for purely cosmetic purposes, like
+// animations. It does not use the game's random source, so that the game
+// stays reproducible whether animations are enabled or not.
+func RandIntUI(n int) int {
 	if n <= 0 {
 		return 0
 	}
-	x := rand.Intn(n)
-	return x
+	return rand.Intn(n)
 }
 
 func Min(x, y int) int {
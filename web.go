@@ -0,0 +1,456 @@
+// +build web
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The web backend serves a page drawing the game in the browser. The server
+// sends the drawn cells as tile image numbers with foreground and
+// background colors, and the page colors the tiles of images.go. Tile
+// images and the palette are sent once per connection.
+
+var webAddr = flag.String("http", "localhost:8080", "address of the web server")
+
+type gameui struct {
+	g         *game
+	cursor    position
+	cache     map[UICell]int // tile numbers of cells
+	width     int
+	height    int
+	menuHover menu
+	itemHover int
+	ln        net.Listener
+
+	mu      sync.Mutex     // protects the fields below
+	tiles   map[string]int // numbers of tile images, by name
+	images  []string       // data URLs of tile images, by number
+	screen  [][3]int       // image numbers and colors of drawn cells
+	client  *webClient
+	pending *webClient // connection waiting for the player to accept it
+}
+
+type webClient struct {
+	ws     *wsConn
+	sent   map[int]bool // images already sent
+	accept chan bool    // answer of the player to the takeover request
+}
+
+// webFrame is a message sent to the browser: a screen update, a status
+// message or a request to accept a new connection. Draws are x, y, image
+// number, foreground and background colors.
+type webFrame struct {
+	W        int            `json:"w,omitempty"`
+	H        int            `json:"h,omitempty"`
+	Palette  []string       `json:"palette,omitempty"`
+	Images   map[int]string `json:"images,omitempty"`
+	Draws    [][5]int       `json:"draws,omitempty"`
+	Msg      string         `json:"msg,omitempty"`
+	Takeover bool           `json:"takeover,omitempty"`
+}
+
+// webInput is an input event sent by the browser, or the answer of the
+// player to a takeover request.
+type webInput struct {
+	Key    string `json:"key"`
+	Mouse  bool   `json:"mouse"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Button int    `json:"button"`
+	Answer bool   `json:"answer"`
+	Accept bool   `json:"accept"`
+}
+
+var ch chan uiInput
+var interrupt chan bool
+
+func init() {
+	ch = make(chan uiInput, 5)
+	interrupt = make(chan bool)
+}
+
+func (ui *gameui) Init() error {
+	ui.width = 16
+	ui.height = 24
+	ui.cache = make(map[UICell]int)
+	ui.tiles = make(map[string]int)
+	ui.menuHover = -1
+	ln, err := net.Listen("tcp", *webAddr)
+	if err != nil {
+		return err
+	}
+	ui.ln = ln
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, webPage)
+	})
+	mux.HandleFunc("/ws", ui.ServeWebSocket)
+	go http.Serve(ln, mux)
+	fmt.Fprintf(os.Stderr, "boohu: open http://%s/ in a browser to play\n", ln.Addr())
+	ForcedPalette = PaletteSolarized
+	SolarizedPalette()
+	ui.HideCursor()
+	settingsActions = append(settingsActions, toggleTiles)
+	return nil
+}
+
+func (ui *gameui) Close() {
+	ui.ln.Close()
+	ui.mu.Lock()
+	if ui.client != nil {
+		ui.client.ws.Close()
+	}
+	ui.mu.Unlock()
+}
+
+// CheckOrigin reports whether a WebSocket request comes from a page served
+// by the same host, so that other sites cannot play in the browser of the
+// player. Requests without origin do not come from browsers.
+func CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// ServeWebSocket handles a browser connection. A new connection replaces
+// the previous one, if any, once the player accepts it.
+func (ui *gameui) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !CheckOrigin(r) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+	ws, err := UpgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+	c := &webClient{ws: ws, sent: map[int]bool{}, accept: make(chan bool, 1)}
+	err = ui.Connect(c)
+	if err != nil {
+		c.Write(&webFrame{Msg: err.Error()})
+		return
+	}
+	defer func() {
+		ui.mu.Lock()
+		if ui.client == c {
+			ui.client = nil
+			if ui.pending != nil {
+				// nobody left to ask
+				ui.pending.accept <- true
+				ui.pending = nil
+			}
+		}
+		ui.mu.Unlock()
+	}()
+	for {
+		data, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		var in webInput
+		err = json.Unmarshal(data, &in)
+		if err != nil {
+			log.Printf("boohu: bad input from browser: %v", err)
+			continue
+		}
+		if in.Answer {
+			ui.mu.Lock()
+			if ui.client == c && ui.pending != nil {
+				ui.pending.accept <- in.Accept
+				ui.pending = nil
+			}
+			ui.mu.Unlock()
+			continue
+		}
+		if in.Mouse && in.Button == -1 && CenteredCamera {
+			continue
+		}
+		if len(ch) < cap(ch) {
+			ch <- uiInput{key: in.Key, mouse: in.Mouse, mouseX: in.X, mouseY: in.Y, button: in.Button}
+		}
+	}
+}
+
+// Connect makes c the connection of the player. If a player is already
+// connected, they are asked to accept the new connection first.
+func (ui *gameui) Connect(c *webClient) error {
+	ui.mu.Lock()
+	if ui.client != nil {
+		if ui.pending != nil {
+			ui.mu.Unlock()
+			return errors.New("Another connection is already waiting to be accepted.")
+		}
+		ui.pending = c
+		err := ui.client.Write(&webFrame{Takeover: true})
+		if err != nil {
+			ui.client.ws.Close()
+		}
+		ui.mu.Unlock()
+		c.Write(&webFrame{Msg: "Waiting for the connected player to accept this connection…"})
+		if !<-c.accept {
+			return errors.New("The connected player refused this connection.")
+		}
+		ui.mu.Lock()
+		if ui.client != nil {
+			ui.client.ws.Close()
+		}
+	}
+	ui.client = c
+	palette := []string{}
+	for i := uicolor(0); i < 16; i++ {
+		palette = append(palette, i.String())
+	}
+	draws := [][5]int{}
+	for i, d := range ui.screen {
+		draws = append(draws, [5]int{i % UIWidth, i / UIWidth, d[0], d[1], d[2]})
+	}
+	ui.Send(&webFrame{Palette: palette, Draws: draws})
+	ui.mu.Unlock()
+	return nil
+}
+
+// Write sends a message to the browser.
+func (c *webClient) Write(wf *webFrame) error {
+	data, err := json.Marshal(wf)
+	if err != nil {
+		return err
+	}
+	return c.ws.WriteMessage(data)
+}
+
+// Send sends a screen update to the connected player, with the images they
+// do not know yet. It should be called with ui.mu locked.
+func (ui *gameui) Send(wf *webFrame) {
+	c := ui.client
+	if c == nil {
+		return
+	}
+	wf.W, wf.H = UIWidth, UIHeight
+	wf.Images = map[int]string{}
+	for _, d := range wf.Draws {
+		if !c.sent[d[2]] {
+			wf.Images[d[2]] = ui.images[d[2]]
+			c.sent[d[2]] = true
+		}
+	}
+	err := c.Write(wf)
+	if err != nil {
+		c.ws.Close()
+		ui.client = nil
+	}
+}
+
+// TileNumber returns the number of the tile image of a cell, adding it to
+// the images to send if needed. It should be called with ui.mu locked.
+func (ui *gameui) TileNumber(cell UICell) int {
+	if id, ok := ui.cache[cell]; ok {
+		return id
+	}
+	name := TileName(cell, GameConfig.Tiles)
+	id, ok := ui.tiles[name]
+	if !ok {
+		data := strings.Replace(string(TileImgs[name]), "\n", "", -1)
+		ui.images = append(ui.images, "data:image/png;base64,"+data)
+		id = len(ui.images) - 1
+		ui.tiles[name] = id
+	}
+	ui.cache[cell] = id
+	return id
+}
+
+func (ui *gameui) Flush() {
+	ui.DrawLogFrame()
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	if len(ui.screen) != UIWidth*UIHeight {
+		ui.screen = make([][3]int, UIWidth*UIHeight)
+	}
+	draws := [][5]int{}
+	for _, cdraw := range ui.g.DrawLog[len(ui.g.DrawLog)-1].Draws {
+		c := cdraw.Cell
+		d := [3]int{ui.TileNumber(c), int(c.Fg), int(c.Bg)}
+		ui.screen[ui.GetIndex(cdraw.X, cdraw.Y)] = d
+		draws = append(draws, [5]int{cdraw.X, cdraw.Y, d[0], d[1], d[2]})
+	}
+	if len(draws) > 0 {
+		ui.Send(&webFrame{Draws: draws})
+	}
+}
+
+func (ui *gameui) ApplyToggleLayout() {
+	ui.ApplyToggleLayoutWithClear(true)
+}
+
+func (ui *gameui) ApplyToggleLayoutWithClear(clear bool) {
+	GameConfig.Small = !GameConfig.Small
+	if GameConfig.Small {
+		if clear {
+			ui.Clear()
+			ui.Flush()
+		}
+		UIHeight = 24
+		UIWidth = 80
+	} else {
+		UIHeight = 26
+		UIWidth = 100
+	}
+	ui.g.DrawBuffer = make([]UICell, UIWidth*UIHeight)
+	if clear {
+		ui.Clear()
+	}
+}
+
+func (ui *gameui) PollEvent() (in uiInput) {
+	select {
+	case in = <-ch:
+	case in.interrupt = <-interrupt:
+	}
+	in.key = BrowserKey(in.key)
+	return in
+}
+
+const webPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Boohu</title>
+<style>
+body {background-color: #002b36; color: #839496; text-align: center;}
+canvas {image-rendering: pixelated; margin-top: 1em;}
+</style>
+</head>
+<body>
+<canvas id="gamecanvas" width="1600" height="624" tabindex="1"></canvas>
+<p id="status"></p>
+<script>
+var canvas = document.getElementById("gamecanvas");
+var ctx = canvas.getContext("2d");
+var images = {};
+var palette = [];
+var cells = {};
+var queue = Promise.resolve();
+var mousepos = {x: -1, y: -1};
+var proto = location.protocol === "https:" ? "wss://" : "ws://";
+var ws = new WebSocket(proto + location.host + "/ws");
+function send(msg) {
+	if (ws.readyState === WebSocket.OPEN) {
+		ws.send(JSON.stringify(msg));
+	}
+}
+function rgb(color) {
+	return [1, 3, 5].map(function(i) { return parseInt(color.substr(i, 2), 16); });
+}
+// cellImage returns the image of a cell: the tile with white pixels in the
+// foreground color and black pixels in the background color.
+function cellImage(id, fg, bg) {
+	var key = id + "," + fg + "," + bg;
+	if (!(key in cells)) {
+		var c = document.createElement("canvas");
+		c.width = 16;
+		c.height = 24;
+		var cctx = c.getContext("2d");
+		cctx.drawImage(images[id], 0, 0);
+		var data = cctx.getImageData(0, 0, 16, 24);
+		var fgc = rgb(palette[fg]), bgc = rgb(palette[bg]);
+		for (var i = 0; i < data.data.length; i += 4) {
+			var col = data.data[i] === 0 ? bgc : fgc;
+			data.data[i] = col[0];
+			data.data[i + 1] = col[1];
+			data.data[i + 2] = col[2];
+			data.data[i + 3] = 255;
+		}
+		cctx.putImageData(data, 0, 0);
+		cells[key] = c;
+	}
+	return cells[key];
+}
+function draw(msg) {
+	if (msg.palette) {
+		palette = msg.palette;
+		cells = {};
+		document.getElementById("status").textContent = "";
+	}
+	if (canvas.width !== 16 * msg.w || canvas.height !== 24 * msg.h) {
+		canvas.width = 16 * msg.w;
+		canvas.height = 24 * msg.h;
+	}
+	ctx.imageSmoothingEnabled = false;
+	var loads = [];
+	for (var id in msg.images) {
+		var img = new Image();
+		img.src = msg.images[id];
+		images[id] = img;
+		loads.push(img.decode());
+	}
+	return Promise.all(loads).then(function() {
+		(msg.draws || []).forEach(function(d) {
+			ctx.drawImage(cellImage(d[2], d[3], d[4]), 16 * d[0], 24 * d[1]);
+		});
+	});
+}
+ws.onmessage = function(e) {
+	var msg = JSON.parse(e.data);
+	if (msg.takeover) {
+		var ok = confirm("Another browser wants to connect to the game. Let it take over?");
+		send({answer: true, accept: ok});
+	} else if (msg.msg) {
+		document.getElementById("status").textContent = msg.msg;
+	} else {
+		queue = queue.then(function() { return draw(msg); });
+	}
+};
+ws.onclose = function() {
+	document.getElementById("status").textContent = "Connection closed.";
+};
+document.addEventListener("keydown", function(e) {
+	if (e.ctrlKey || e.metaKey) {
+		return;
+	}
+	e.preventDefault();
+	var key = e.key;
+	if (key === "Unidentified") {
+		key = e.code;
+	}
+	send({key: key});
+});
+function cellPos(e) {
+	var rect = canvas.getBoundingClientRect();
+	var x = (e.clientX - rect.left) * canvas.width / rect.width;
+	var y = (e.clientY - rect.top) * canvas.height / rect.height;
+	return {x: Math.floor((x - 1) / 16), y: Math.floor((y - 1) / 24)};
+}
+canvas.addEventListener("contextmenu", function(e) { e.preventDefault(); });
+canvas.addEventListener("mousedown", function(e) {
+	var p = cellPos(e);
+	send({mouse: true, x: p.x, y: p.y, button: e.button});
+});
+canvas.addEventListener("mousemove", function(e) {
+	var p = cellPos(e);
+	if (p.x !== mousepos.x || p.y !== mousepos.y) {
+		mousepos = p;
+		send({mouse: true, x: p.x, y: p.y, button: -1});
+	}
+});
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,105 @@
+// +build web
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type webTestClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialWebTest(t *testing.T, addr, origin string) (*webTestClient, int) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: %s\r\nOrigin: %s\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", addr, origin)
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &webTestClient{conn: conn, r: r}, resp.StatusCode
+}
+
+func (c *webTestClient) Read(t *testing.T) webFrame {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var head [2]byte
+	if _, err := io.ReadFull(c.r, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	n := int(head[1] & 0x7f)
+	if n == 126 {
+		var ext [2]byte
+		io.ReadFull(c.r, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		t.Fatal(err)
+	}
+	var wf webFrame
+	if err := json.Unmarshal(data, &wf); err != nil {
+		t.Fatal(err)
+	}
+	return wf
+}
+
+func (c *webTestClient) Write(msg string) {
+	f := []byte{0x80 | wsOpText, 0x80 | byte(len(msg)), 0, 0, 0, 0}
+	c.conn.Write(append(f, msg...))
+}
+
+func TestWebTakeover(t *testing.T) {
+	ui := &gameui{cache: map[UICell]int{}, tiles: map[string]int{}}
+	srv := httptest.NewServer(http.HandlerFunc(ui.ServeWebSocket))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+	if _, code := dialWebTest(t, addr, "http://example.com"); code != http.StatusForbidden {
+		t.Errorf("foreign origin not rejected: %d", code)
+	}
+	c1, code := dialWebTest(t, addr, srv.URL)
+	if code != http.StatusSwitchingProtocols {
+		t.Fatalf("bad status: %d", code)
+	}
+	if wf := c1.Read(t); len(wf.Palette) != 16 {
+		t.Errorf("bad first frame: %+v", wf)
+	}
+	c2, _ := dialWebTest(t, addr, srv.URL)
+	if wf := c1.Read(t); !wf.Takeover {
+		t.Errorf("takeover not requested: %+v", wf)
+	}
+	if wf := c2.Read(t); !strings.HasPrefix(wf.Msg, "Waiting") {
+		t.Errorf("bad message: %+v", wf)
+	}
+	c1.Write(`{"answer":true,"accept":false}`)
+	if wf := c2.Read(t); !strings.Contains(wf.Msg, "refused") {
+		t.Errorf("bad message: %+v", wf)
+	}
+	c3, _ := dialWebTest(t, addr, "")
+	if wf := c1.Read(t); !wf.Takeover {
+		t.Errorf("takeover not requested: %+v", wf)
+	}
+	c3.Read(t)
+	c1.Write(`{"answer":true,"accept":true}`)
+	if wf := c3.Read(t); len(wf.Palette) != 16 {
+		t.Errorf("bad first frame: %+v", wf)
+	}
+	c1.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c1.r.ReadByte(); err == nil {
+		t.Errorf("replaced connection not closed")
+	}
+}
@@ -0,0 +1,195 @@
+// +build web
+
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Minimal server-side implementation of the WebSocket protocol (RFC 6455),
+// enough for exchanging text messages with the browser page.
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsOpContinuation = 0
+	wsOpText         = 1
+	wsOpBinary       = 2
+	wsOpClose        = 8
+	wsOpPing         = 9
+	wsOpPong         = 10
+)
+
+// wsMaxMessage is the maximum size of a message sent by the browser.
+const wsMaxMessage = 1 << 16
+
+const wsWriteTimeout = 10 * time.Second
+
+type wsConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	wmu  sync.Mutex
+}
+
+func WebSocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+func headerContains(h http.Header, name, value string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UpgradeWebSocket turns an HTTP request into a WebSocket connection.
+func UpgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") || key == "" {
+		http.Error(w, "WebSocket connection expected", http.StatusBadRequest)
+		return nil, errors.New("not a WebSocket request")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("connection cannot be hijacked")
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", WebSocketAccept(key))
+	err = brw.Flush()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, r: brw.Reader}, nil
+}
+
+func (ws *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	_, err = io.ReadFull(ws.r, head[:])
+	if err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0f
+	masked := head[1]&0x80 != 0
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		_, err = io.ReadFull(ws.r, ext[:])
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, err = io.ReadFull(ws.r, ext[:])
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if err != nil {
+		return
+	}
+	if !masked {
+		err = errors.New("unmasked client frame")
+		return
+	}
+	if n > wsMaxMessage {
+		err = errors.New("message too big")
+		return
+	}
+	var mask [4]byte
+	_, err = io.ReadFull(ws.r, mask[:])
+	if err != nil {
+		return
+	}
+	payload = make([]byte, n)
+	_, err = io.ReadFull(ws.r, payload)
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// ReadMessage returns the next text or binary message. It answers pings,
+// and returns io.EOF when the browser closes the connection.
+func (ws *wsConn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, payload, err := ws.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case wsOpClose:
+			ws.writeFrame(wsOpClose, nil)
+			return nil, io.EOF
+		case wsOpPing:
+			err = ws.writeFrame(wsOpPong, payload)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpText, wsOpBinary, wsOpContinuation:
+			msg = append(msg, payload...)
+			if len(msg) > wsMaxMessage {
+				return nil, errors.New("message too big")
+			}
+		default:
+			return nil, fmt.Errorf("unknown opcode %d", op)
+		}
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+func (ws *wsConn) writeFrame(op byte, payload []byte) error {
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	head := make([]byte, 2, 10)
+	head[0] = 0x80 | op
+	n := len(payload)
+	switch {
+	case n < 126:
+		head[1] = byte(n)
+	case n < 1<<16:
+		head[1] = 126
+		head = head[:4]
+		binary.BigEndian.PutUint16(head[2:], uint16(n))
+	default:
+		head[1] = 127
+		head = head[:10]
+		binary.BigEndian.PutUint64(head[2:], uint64(n))
+	}
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	_, err := ws.conn.Write(append(head, payload...))
+	return err
+}
+
+// WriteMessage sends a text message.
+func (ws *wsConn) WriteMessage(data []byte) error {
+	return ws.writeFrame(wsOpText, data)
+}
+
+func (ws *wsConn) Close() error {
+	return ws.conn.Close()
+}
@@ -0,0 +1,61 @@
+// +build web
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestWebSocketAccept(t *testing.T) {
+	// example from RFC 6455
+	if acc := WebSocketAccept("dGhlIHNhbXBsZSBub25jZQ=="); acc != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("bad accept value: %s", acc)
+	}
+}
+
+func TestWebSocketMessages(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	ws := &wsConn{conn: server, r: bufio.NewReader(server)}
+	mask := []byte{1, 2, 3, 4}
+	frame := func(fin byte, op byte, payload string) []byte {
+		f := []byte{fin<<7 | op, 0x80 | byte(len(payload))}
+		f = append(f, mask...)
+		for i := range payload {
+			f = append(f, payload[i]^mask[i%4])
+		}
+		return f
+	}
+	go func() {
+		client.Write(frame(0, wsOpText, `{"key":`))
+		client.Write(frame(1, wsOpPing, "hi"))
+		client.Write(frame(1, wsOpContinuation, `"p"}`))
+	}()
+	pong := make(chan []byte)
+	go func() {
+		buf := make([]byte, 4)
+		io.ReadFull(client, buf)
+		pong <- buf
+	}()
+	msg, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg) != `{"key":"p"}` {
+		t.Errorf("bad message: %q", msg)
+	}
+	if p := <-pong; p[0] != 0x80|wsOpPong || string(p[2:]) != "hi" {
+		t.Errorf("bad pong: %v", p)
+	}
+	go ws.WriteMessage([]byte("hello"))
+	buf := make([]byte, 7)
+	if _, err := io.ReadFull(client, buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf[0] != 0x80|wsOpText || buf[1] != 5 || string(buf[2:]) != "hello" {
+		t.Errorf("bad frame: %v", buf)
+	}
+}